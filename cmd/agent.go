@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fiftyk/claude-switcher/internal/agent"
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/vault"
+)
+
+// DefaultAgentTTL agent 默认保存密钥的时间
+const DefaultAgentTTL = time.Hour
+
+// newAgentCommand 启动保存主口令密钥的 agent
+func newAgentCommand() *Command {
+	c := newCommand("agent", "[--ttl 1h] [--socket 路径] | status | stop", "启动 agent，在内存中保存加密存储的密钥")
+	c.Long = `agent 解锁加密存储后在内存中保存密钥，通过权限为 0600 的 Unix socket 为其他命令加密和解密配置，
+密钥不会写入磁盘，也不会发送给其他进程。超过 --ttl 后清除密钥并退出（0 表示不过期）。
+
+设置了 ` + agent.SocketEnv + ` 时，命令行和菜单会自动使用 agent，无需再输入主口令:
+  claude-switcher agent                     # 在一个终端中运行
+  export ` + agent.SocketEnv + `=~/.claude-switcher/agent.sock
+  claude-switcher work
+
+  claude-switcher agent status              # 查看 agent 状态
+  claude-switcher agent stop                # 清除密钥并停止 agent`
+	ttl := c.Flags.Duration("ttl", DefaultAgentTTL, "保存密钥的时间，0 表示不过期")
+	socket := c.Flags.String("socket", "", "socket 路径（默认为 $"+agent.SocketEnv+" 或 ~/.claude-switcher/agent.sock）")
+	c.Run = func(args []string) error {
+		path := agentSocketPath(*socket)
+		if len(args) == 0 {
+			return RunAgent(config.GetProfilesDir(), path, *ttl)
+		}
+		if len(args) != 1 {
+			return c.usageError()
+		}
+
+		client := &agent.Client{Path: path}
+		switch args[0] {
+		case "status":
+			st, err := client.Status()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("agent 运行中: %s\n", path)
+			fmt.Printf("配置目录: %s\n", st.Dir)
+			if st.Expires.IsZero() {
+				fmt.Println("密钥不过期")
+			} else {
+				fmt.Printf("密钥过期时间: %s（剩余 %s）\n", st.Expires.Format("15:04:05"), time.Until(st.Expires).Round(time.Second))
+			}
+			return nil
+		case "stop":
+			if err := client.Stop(); err != nil {
+				return err
+			}
+			fmt.Println("✓ agent 已停止")
+			return nil
+		}
+		return c.usageError()
+	}
+	return c
+}
+
+// agentSocketPath 返回 agent socket 路径：命令行参数、环境变量、默认路径依次优先
+func agentSocketPath(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if path := os.Getenv(agent.SocketEnv); path != "" {
+		return path
+	}
+	return filepath.Join(config.GetConfigDir(), "agent.sock")
+}
+
+// RunAgent 输入主口令解锁后运行 agent，直到密钥过期、收到停止请求或中断信号
+func RunAgent(profilesDir, socketPath string, ttl time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("--ttl 不能为负数")
+	}
+	if _, err := vault.Load(profilesDir); err != nil {
+		return err
+	}
+	if _, err := (&agent.Client{Path: socketPath}).Status(); err == nil {
+		return fmt.Errorf("agent 已在运行: %s", socketPath)
+	}
+	key, err := unlockVaultKey(profilesDir)
+	if err != nil {
+		return err
+	}
+
+	dir, err := filepath.Abs(profilesDir)
+	if err != nil {
+		return err
+	}
+	srv := agent.NewServer(dir, key, ttl)
+	srv.Logf = func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, "[%s] %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
+	}
+	// 密钥已复制到 agent 中，清除本进程的缓存
+	vaultMu.Lock()
+	for i := range key {
+		key[i] = 0
+	}
+	delete(vaultKeys, profilesDir)
+	vaultMu.Unlock()
+
+	ln, err := agent.Listen(socketPath)
+	if err != nil {
+		srv.Close()
+		return fmt.Errorf("监听 %s 失败: %w", socketPath, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	fmt.Printf("agent 已启动: %s\n", socketPath)
+	if ttl > 0 {
+		fmt.Printf("密钥将在 %s 后清除\n", time.Now().Add(ttl).Format("15:04:05"))
+	}
+	fmt.Printf("使用方法: export %s=%s\n", agent.SocketEnv, socketPath)
+	fmt.Println("按 Ctrl+C 停止")
+
+	if err := srv.Serve(ln); err != nil {
+		return err
+	}
+	fmt.Println("agent 已停止")
+	return nil
+}
+
+// agentCipher 设置了 CLAUDE_SWITCHER_AGENT_SOCK 且 agent 保存着 profilesDir 的密钥时返回 agent 客户端
+// agent 不可用时给出提示，由调用方改为输入主口令
+func agentCipher(profilesDir string) (profile.Cipher, bool) {
+	path := os.Getenv(agent.SocketEnv)
+	if path == "" {
+		return nil, false
+	}
+
+	client := &agent.Client{Path: path}
+	st, err := client.Status()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠  %v，改为输入主口令\n", err)
+		return nil, false
+	}
+	if dir, _ := filepath.Abs(profilesDir); dir != st.Dir {
+		fmt.Fprintf(os.Stderr, "⚠  agent 保存的是 %s 的密钥，改为输入主口令\n", st.Dir)
+		return nil, false
+	}
+	return client, true
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fiftyk/claude-switcher/internal/agent"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+func TestRunAgentServesProfiles(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{Name: "work", AuthToken: "sk-work"})
+	stubPassphrase(t, "pw", "pw")
+	if err := InitVault(profilesDir); err != nil {
+		t.Fatal(err)
+	}
+
+	// agent 启动时输入一次口令
+	stubPassphrase(t, "pw")
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	done := make(chan error, 1)
+	go func() { done <- RunAgent(profilesDir, socket, time.Minute) }()
+	client := &agent.Client{Path: socket}
+	for i := 0; ; i++ {
+		if _, err := client.Status(); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatal("agent did not start")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Cleanup(func() { client.Stop() })
+
+	// 设置环境变量后读写加密配置不再提示输入口令
+	stubPassphrase(t)
+	t.Setenv(agent.SocketEnv, socket)
+	p, err := profile.LoadProfile(profilesDir, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AuthToken != "sk-work" {
+		t.Errorf("AuthToken = %q, want sk-work", p.AuthToken)
+	}
+	p.Model = "claude-opus-4-1"
+	if err := profile.SaveProfile(profilesDir, "work", p); err != nil {
+		t.Fatal(err)
+	}
+	if !profile.IsEncrypted(profilesDir, "work") {
+		t.Error("profile saved through the agent should stay encrypted")
+	}
+
+	if err := Execute([]string{"agent", "stop"}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunAgent() = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("agent did not stop")
+	}
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/probe"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+// probeMessagesFunc 用于发送 Messages API 探测请求，可被测试 mock
+var probeMessagesFunc = ProbeMessages
+
+// BenchOptions 基准测试选项
+type BenchOptions struct {
+	Runs        int  // 每个配置的测试次数
+	Concurrency int  // 同时进行的测试数量
+	Quick       bool // 只测试连通性，不发送 Messages API 请求
+	Stream      bool // Messages API 请求使用流式响应
+	JSON        bool
+}
+
+// BenchResult 单个配置的基准测试结果
+type BenchResult struct {
+	Profile      string  `json:"profile"`
+	Runs         int     `json:"runs"`
+	Errors       int     `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	LatencyP50Ms int64   `json:"latency_p50_ms"`
+	LatencyP95Ms int64   `json:"latency_p95_ms"`
+	TTFTP50Ms    int64   `json:"ttft_p50_ms,omitempty"`
+	TTFTP95Ms    int64   `json:"ttft_p95_ms,omitempty"`
+	LastError    string  `json:"last_error,omitempty"`
+
+	latencies []time.Duration
+	ttfts     []time.Duration
+}
+
+// newBenchCommand 并发测试所有配置的延迟
+func newBenchCommand() *Command {
+	c := newCommand("bench", "[-n 次数] [-c 并发数] [--quick] [--stream] [--json]", "并发测试所有配置的延迟与可用性")
+	c.Long = `每次测试包含一次连通性检查和一次 max_tokens 为 1 的 Messages API 请求（--quick 时跳过），
+结果按错误率、首 token 耗时和连接延迟排序。`
+	opts := BenchOptions{}
+	c.Flags.IntVar(&opts.Runs, "n", 3, "每个配置的测试次数")
+	c.Flags.IntVar(&opts.Concurrency, "c", 4, "同时进行的测试数量")
+	c.Flags.BoolVar(&opts.Quick, "quick", false, "只测试连通性")
+	c.Flags.BoolVar(&opts.Stream, "stream", false, "Messages API 请求使用流式响应")
+	c.Flags.BoolVar(&opts.JSON, "json", false, "以 JSON 格式输出")
+	c.Run = func(args []string) error {
+		if len(args) != 0 {
+			return c.usageError()
+		}
+		if opts.Runs < 1 || opts.Concurrency < 1 {
+			return fmt.Errorf("-n 和 -c 必须大于 0")
+		}
+
+		results, err := RunBench(config.GetProfilesDir(), opts)
+		if err != nil {
+			return err
+		}
+		if opts.JSON {
+			data, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		PrintBenchTable(os.Stdout, results, !opts.Quick)
+		return nil
+	}
+	return c
+}
+
+// benchJob 一次测试任务
+type benchJob struct {
+	result  *BenchResult
+	profile *profile.Profile
+}
+
+// RunBench 对所有配置执行基准测试，返回排序后的结果
+func RunBench(profilesDir string, opts BenchOptions) ([]*BenchResult, error) {
+	names, err := profile.ListProfiles(profilesDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("暂无配置")
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		jobs    = make(chan benchJob)
+		results = make([]*BenchResult, 0, len(names))
+	)
+
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				latency, ttft, errMsg := benchOnce(job.result.Profile, job.profile, opts)
+				mu.Lock()
+				job.result.Runs++
+				if latency > 0 {
+					job.result.latencies = append(job.result.latencies, latency)
+				}
+				if ttft > 0 {
+					job.result.ttfts = append(job.result.ttfts, ttft)
+				}
+				if errMsg != "" {
+					job.result.Errors++
+					job.result.LastError = errMsg
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, name := range names {
+		r := &BenchResult{Profile: name}
+		results = append(results, r)
+
+		p, err := loadResolvedProfile(profilesDir, name)
+		if err != nil {
+			r.Runs, r.Errors, r.LastError = opts.Runs, opts.Runs, err.Error()
+			continue
+		}
+		for i := 0; i < opts.Runs; i++ {
+			jobs <- benchJob{result: r, profile: p}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, r := range results {
+		r.summarize()
+	}
+	sortBenchResults(results)
+	return results, nil
+}
+
+// benchOnce 对配置执行一次测试，返回连接延迟、首 token 耗时与错误信息
+// 连接失败时延迟为 0；未进行或未成功的 API 测试首 token 耗时为 0
+func benchOnce(name string, p *profile.Profile, opts BenchOptions) (time.Duration, time.Duration, string) {
+	conn := checkConnectivityFunc(p)
+	var latency time.Duration
+	if conn.Error == nil {
+		latency = conn.Latency
+	}
+
+	if opts.Quick {
+		if !conn.Reachable {
+			return latency, 0, conn.Message
+		}
+		return latency, 0, ""
+	}
+	if conn.Error != nil {
+		return 0, 0, conn.Message
+	}
+
+	result, err := probeMessagesFunc(name, p, opts.Stream)
+	if err != nil {
+		return latency, 0, err.Error()
+	}
+	if result.Status != probe.StatusOK {
+		return latency, 0, fmt.Sprintf("%s: %s", result.Status.Label(), result.Message)
+	}
+	return latency, result.TTFT, ""
+}
+
+// summarize 计算错误率与百分位数
+func (r *BenchResult) summarize() {
+	if r.Runs > 0 {
+		r.ErrorRate = float64(r.Errors) / float64(r.Runs)
+	}
+	r.LatencyP50Ms = probe.Percentile(r.latencies, 50).Milliseconds()
+	r.LatencyP95Ms = probe.Percentile(r.latencies, 95).Milliseconds()
+	r.TTFTP50Ms = probe.Percentile(r.ttfts, 50).Milliseconds()
+	r.TTFTP95Ms = probe.Percentile(r.ttfts, 95).Milliseconds()
+}
+
+// sortBenchResults 按错误率、首 token 耗时、连接延迟排序，没有数据的指标排在后面
+func sortBenchResults(results []*BenchResult) {
+	less := func(a, b int64) (bool, bool) {
+		switch {
+		case a == b:
+			return false, false
+		case a == 0:
+			return false, true
+		case b == 0:
+			return true, true
+		default:
+			return a < b, true
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.ErrorRate != b.ErrorRate {
+			return a.ErrorRate < b.ErrorRate
+		}
+		if l, ok := less(a.TTFTP50Ms, b.TTFTP50Ms); ok {
+			return l
+		}
+		if l, ok := less(a.LatencyP50Ms, b.LatencyP50Ms); ok {
+			return l
+		}
+		return a.Profile < b.Profile
+	})
+}
+
+// PrintBenchTable 以表格形式输出基准测试结果
+func PrintBenchTable(w io.Writer, results []*BenchResult, withTTFT bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withTTFT {
+		fmt.Fprintln(tw, "配置\t错误率\t延迟 p50\t延迟 p95\t首 token p50\t首 token p95")
+	} else {
+		fmt.Fprintln(tw, "配置\t错误率\t延迟 p50\t延迟 p95")
+	}
+
+	ms := func(v int64) string {
+		if v == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%dms", v)
+	}
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%.0f%%\t%s\t%s", r.Profile, r.ErrorRate*100, ms(r.LatencyP50Ms), ms(r.LatencyP95Ms))
+		if withTTFT {
+			fmt.Fprintf(tw, "\t%s\t%s", ms(r.TTFTP50Ms), ms(r.TTFTP95Ms))
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+
+	for _, r := range results {
+		if r.LastError != "" {
+			fmt.Fprintf(w, "\n%s 最近一次错误: %s", r.Profile, r.LastError)
+		}
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fiftyk/claude-switcher/internal/probe"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+func mockBenchProbes(t *testing.T, ttft map[string]time.Duration) {
+	t.Helper()
+	originalConn, originalProbe := checkConnectivityFunc, probeMessagesFunc
+	t.Cleanup(func() {
+		checkConnectivityFunc = originalConn
+		probeMessagesFunc = originalProbe
+	})
+
+	checkConnectivityFunc = func(p *profile.Profile) *ConnectivityResult {
+		return &ConnectivityResult{Reachable: true, Latency: 20 * time.Millisecond}
+	}
+	probeMessagesFunc = func(name string, p *profile.Profile, stream bool) (*probe.MessagesResult, error) {
+		d, ok := ttft[name]
+		if !ok {
+			return &probe.MessagesResult{Status: probe.StatusAuth, Message: "invalid key"}, nil
+		}
+		return &probe.MessagesResult{Status: probe.StatusOK, TTFT: d}, nil
+	}
+}
+
+func TestRunBench(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	for _, name := range []string{"slow", "fast", "broken"} {
+		writeTestProfile(t, profilesDir, name, &profile.Profile{Name: name})
+	}
+	mockBenchProbes(t, map[string]time.Duration{
+		"slow": 900 * time.Millisecond,
+		"fast": 300 * time.Millisecond,
+	})
+
+	results, err := RunBench(profilesDir, BenchOptions{Runs: 4, Concurrency: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, r := range results {
+		order = append(order, r.Profile)
+		if r.Runs != 4 {
+			t.Errorf("%s runs = %d, want 4", r.Profile, r.Runs)
+		}
+	}
+	if got := strings.Join(order, ","); got != "fast,slow,broken" {
+		t.Errorf("order = %s, want fast,slow,broken", got)
+	}
+
+	fast := results[0]
+	if fast.TTFTP50Ms != 300 || fast.LatencyP95Ms != 20 || fast.ErrorRate != 0 {
+		t.Errorf("fast = %+v", fast)
+	}
+	broken := results[2]
+	if broken.ErrorRate != 1 || broken.LastError == "" {
+		t.Errorf("broken = %+v", broken)
+	}
+}
+
+func TestRunBenchQuick(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{Name: "work"})
+	mockBenchProbes(t, nil)
+	probeMessagesFunc = func(string, *profile.Profile, bool) (*probe.MessagesResult, error) {
+		return nil, fmt.Errorf("quick mode should not call the Messages API")
+	}
+
+	results, err := RunBench(profilesDir, BenchOptions{Runs: 2, Concurrency: 1, Quick: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Errors != 0 {
+		t.Errorf("unexpected errors: %s", results[0].LastError)
+	}
+}
+
+func TestPrintBenchTable(t *testing.T) {
+	var sb strings.Builder
+	PrintBenchTable(&sb, []*BenchResult{
+		{Profile: "work", ErrorRate: 0.25, LatencyP50Ms: 12, LatencyP95Ms: 40, TTFTP50Ms: 300, LastError: "timeout"},
+	}, true)
+
+	out := sb.String()
+	for _, want := range []string{"work", "25%", "12ms", "300ms", "首 token p95", "timeout"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestExecuteBenchJSON(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{Name: "work"})
+	mockBenchProbes(t, map[string]time.Duration{"work": 100 * time.Millisecond})
+
+	if err := Execute([]string{"bench", "-n", "1", "--json"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := Execute([]string{"bench", "-n", "0"}, BuildInfo{}); err == nil {
+		t.Error("expected error for -n 0")
+	}
+}
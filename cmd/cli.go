@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+// AppName 是程序名称
+const AppName = "Claude Switcher"
+
+// BuildInfo 表示版本信息 (由 Go Releaser 注入 main 包后传入)
+type BuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+// buildInfo 当前运行的版本信息，由 Execute 设置
+var buildInfo = BuildInfo{Version: "dev", Commit: "unknown", Date: "unknown"}
+
+// Command 表示一个子命令
+type Command struct {
+	Name  string
+	Usage string // 参数说明，如 "<配置1> <配置2>"
+	Short string // 一行说明，显示在命令列表中
+	Long  string // 详细说明，显示在命令帮助中
+
+	// Passthrough 为 true 时遇到第一个位置参数即停止解析 flag，
+	// 其后的参数原样交给 Run（用于向 claude 透传参数）
+	Passthrough bool
+
+	Flags *flag.FlagSet
+	Run   func(args []string) error
+}
+
+// newCommand 创建子命令并初始化其 FlagSet
+func newCommand(name, usage, short string) *Command {
+	c := &Command{
+		Name:  name,
+		Usage: usage,
+		Short: short,
+		Flags: flag.NewFlagSet(name, flag.ContinueOnError),
+	}
+	c.Flags.SetOutput(io.Discard)
+	return c
+}
+
+// usageError 返回命令用法错误
+func (c *Command) usageError() error {
+	return fmt.Errorf("用法: claude-switcher %s %s", c.Name, c.Usage)
+}
+
+// PrintUsage 打印命令帮助
+func (c *Command) PrintUsage(w io.Writer) {
+	fmt.Fprintf(w, "\n用法: claude-switcher %s %s\n\n", c.Name, c.Usage)
+	fmt.Fprintf(w, "%s\n", c.Short)
+	if c.Long != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimRight(c.Long, "\n"))
+	}
+
+	hasFlags := false
+	c.Flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintln(w, "\n选项:")
+		c.Flags.SetOutput(w)
+		c.Flags.PrintDefaults()
+		c.Flags.SetOutput(io.Discard)
+	}
+	fmt.Fprintln(w)
+}
+
+// execute 解析参数并运行命令
+func (c *Command) execute(args []string) error {
+	var positional []string
+	var err error
+	if c.Passthrough {
+		err = c.Flags.Parse(args)
+		positional = c.Flags.Args()
+	} else {
+		positional, err = parseInterspersed(c.Flags, args)
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		c.PrintUsage(os.Stdout)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%v\n使用 'claude-switcher help %s' 查看用法", err, c.Name)
+	}
+
+	return c.Run(positional)
+}
+
+// parseInterspersed 解析 flag，允许 flag 出现在位置参数之后
+// "--" 之后的参数一律视为位置参数
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var tail []string
+	if idx := indexOf(args, "--"); idx >= 0 {
+		tail = args[idx+1:]
+		args = args[:idx]
+	}
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	return append(positional, tail...), nil
+}
+
+// indexOf 返回 target 在 args 中的位置，不存在时返回 -1
+func indexOf(args []string, target string) int {
+	for i, arg := range args {
+		if arg == target {
+			return i
+		}
+	}
+	return -1
+}
+
+// Commands 返回所有子命令
+func Commands() []*Command {
+	return []*Command{
+		newListCommand(),
+		newUseCommand(),
+		newDiffCommand(),
+		newValidateCommand(),
+		newBenchCommand(),
+		newImportCommand(),
+		newExportCommand(),
+		newTemplateCommand(),
+		newEnvCommand(),
+		newTokenCommand(),
+		newRotateTokenCommand(),
+		newGatewayCommand(),
+		newGroupCommand(),
+		newEditCommand(),
+		newRenameCommand(),
+		newCopyCommand(),
+		newConfigCommand(),
+		newVaultCommand(),
+		newAgentCommand(),
+		newUpdateCommand(),
+		newVersionCommand(),
+		newHelpCommand(),
+	}
+}
+
+// findCommand 根据名称查找子命令
+func findCommand(name string) *Command {
+	for _, c := range Commands() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// legacyFlags 旧版本参数到子命令的映射，其余参数原样保留
+var legacyFlags = map[string][]string{
+	"--list":            {"list"},
+	"--test":            {"validate"},
+	"--validate":        {"validate"},
+	"--diff":            {"diff"},
+	"--rename":          {"rename"},
+	"--copy":            {"copy"},
+	"--import":          {"import"},
+	"--import-settings": {"import", "--from-settings"},
+	"--export":          {"export"},
+	"--export-all":      {"export", "--all"},
+	"--env":             {"env"},
+	"--templates":       {"template"},
+	"--config":          {"use"},
+	"-c":                {"use"},
+	"--check-update":    {"update", "--check"},
+	"--self-update":     {"update"},
+	"--version":         {"version"},
+	"--help":            {"help"},
+	"-h":                {"help"},
+}
+
+// TranslateLegacyArgs 将旧版本的参数形式转换为子命令形式
+//
+//	--rename old new      -> rename old new
+//	--config work -- -p   -> use work -- -p
+//	work --env            -> env work
+//	work --sync           -> use --no-launch work
+func TranslateLegacyArgs(args []string) []string {
+	if len(args) == 0 {
+		return args
+	}
+
+	if mapped, ok := legacyFlags[args[0]]; ok {
+		return append(append([]string{}, mapped...), args[1:]...)
+	}
+
+	// <配置名> --env / <配置名> --sync（仅检查 -- 分隔符之前的部分）
+	if !strings.HasPrefix(args[0], "-") && findCommand(args[0]) == nil && len(args) >= 2 {
+		switch args[1] {
+		case "--env":
+			return append([]string{"env", args[0]}, args[2:]...)
+		case "--sync":
+			return append([]string{"use", "--no-launch", args[0]}, args[2:]...)
+		}
+	}
+
+	return args
+}
+
+// Execute 解析命令行参数并执行对应命令
+func Execute(args []string, info BuildInfo) error {
+	buildInfo = info
+	args = TranslateLegacyArgs(args)
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "version":
+			return findCommand(args[0]).execute(args[1:])
+		}
+	}
+
+	// 初始化配置目录
+	if err := config.EnsureConfigDir(); err != nil {
+		return err
+	}
+
+	// 读写加密的配置时使用 agent 或提示输入主口令
+	profile.CipherSource = vaultCipher
+
+	// 无参数时启动交互式菜单，"-- <参数...>" 表示进入菜单后透传参数
+	if len(args) == 0 {
+		return RunInteractiveMenu(config.GetProfilesDir(), nil)
+	}
+	if args[0] == "--" {
+		return RunInteractiveMenu(config.GetProfilesDir(), args[1:])
+	}
+
+	if c := findCommand(args[0]); c != nil {
+		return c.execute(args[1:])
+	}
+
+	if strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("未知参数: %s\n使用 'claude-switcher help' 查看用法", args[0])
+	}
+
+	// 其余情况视为配置名称: <配置名> [参数...]
+	return findCommand("use").execute(args)
+}
+
+// RunInteractiveMenu 运行交互式菜单并处理用户选择
+// claudeArgs 会在启动 claude 时透传
+func RunInteractiveMenu(profilesDir string, claudeArgs []string) error {
+	menuClaudeArgs = claudeArgs
+	defer func() { menuClaudeArgs = nil }()
+
+	handler := DefaultMenuHandler{}
+
+	for {
+		action, name, err := handler.ShowMenu(profilesDir)
+		if err != nil {
+			return err
+		}
+
+		if err := HandleMenuAction(profilesDir, action, name, handler); err != nil {
+			if err == ErrQuit {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+}
+
+// legacyUsage 旧版本参数形式，继续作为别名支持
+const legacyUsage = `  --list, --test <名称>, --validate <名称>, --diff <配置1> <配置2>
+  --rename <旧> <新>, --copy <源> <目标>, --config <名称>
+  --import <文件> [格式], --import-settings [配置名]
+  --export <配置名> [格式], --export-all, --templates
+  <配置名> --env, <配置名> --sync
+  --check-update, --self-update, --version, --help`
+
+// PrintHelp 打印帮助信息
+func PrintHelp(w io.Writer) error {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s %s - 使用帮助\n\n", AppName, buildInfo.Version)
+	sb.WriteString("用法:\n")
+	sb.WriteString("  claude-switcher                          启动交互式配置选择\n")
+	sb.WriteString("  claude-switcher -- <参数...>             进入交互式菜单，启动时透传参数\n")
+	sb.WriteString("  claude-switcher <配置名称> [-- <参数...>] 使用指定配置启动，可透传参数\n")
+	sb.WriteString("  claude-switcher <命令> [参数...]\n\n")
+
+	sb.WriteString("命令:\n")
+	for _, c := range Commands() {
+		fmt.Fprintf(&sb, "  %-10s %s\n", c.Name, c.Short)
+	}
+
+	sb.WriteString("\n兼容旧参数:\n")
+	sb.WriteString(legacyUsage + "\n")
+
+	sb.WriteString("\n说明:\n")
+	sb.WriteString("  • 配置文件位于: ~/.claude-switcher/profiles/\n")
+	sb.WriteString("  • 无参数运行时进入交互式菜单\n")
+	sb.WriteString("  • 默认切换配置时会同步到 ~/.claude/settings.json，使用 --isolated 只向 claude 注入环境变量\n")
+	sb.WriteString("  • 使用 'claude-switcher help <命令>' 查看命令的详细用法\n\n")
+
+	_, err := fmt.Fprint(w, sb.String())
+	return err
+}
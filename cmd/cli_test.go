@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+// setupTestHome 使用临时目录作为 HOME 和配置目录
+func setupTestHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	originalConfigDir := config.ConfigDir
+	config.ConfigDir = filepath.Join(home, ".claude-switcher")
+	t.Cleanup(func() { config.ConfigDir = originalConfigDir })
+
+	if err := config.EnsureConfigDir(); err != nil {
+		t.Fatal(err)
+	}
+	return config.GetProfilesDir()
+}
+
+func writeTestProfile(t *testing.T, profilesDir, name string, p *profile.Profile) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(profilesDir, name+".conf"), profile.Format(p), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTranslateLegacyArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{}, []string{}},
+		{[]string{"--list"}, []string{"list"}},
+		{[]string{"--rename", "old", "new"}, []string{"rename", "old", "new"}},
+		{[]string{"--copy", "a", "b"}, []string{"copy", "a", "b"}},
+		{[]string{"--test", "work"}, []string{"validate", "work"}},
+		{[]string{"--diff", "a", "b"}, []string{"diff", "a", "b"}},
+		{[]string{"--import-settings", "mine"}, []string{"import", "--from-settings", "mine"}},
+		{[]string{"--export-all"}, []string{"export", "--all"}},
+		{[]string{"--config", "work", "--", "-p"}, []string{"use", "work", "--", "-p"}},
+		{[]string{"--check-update"}, []string{"update", "--check"}},
+		{[]string{"--self-update"}, []string{"update"}},
+		{[]string{"work", "--env"}, []string{"env", "work"}},
+		{[]string{"work", "--sync"}, []string{"use", "--no-launch", "work"}},
+		{[]string{"work", "--", "--help"}, []string{"work", "--", "--help"}},
+		{[]string{"diff", "a", "b"}, []string{"diff", "a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			got := TranslateLegacyArgs(tt.args)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TranslateLegacyArgs(%v) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	format := fs.String("format", "", "")
+	all := fs.Bool("all", false, "")
+
+	args, err := parseInterspersed(fs, []string{"work", "--format", "yaml", "extra", "--all", "--", "--not-a-flag"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"work", "extra", "--not-a-flag"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+	if *format != "yaml" {
+		t.Errorf("format = %q, want yaml", *format)
+	}
+	if !*all {
+		t.Error("all should be true")
+	}
+}
+
+func TestExecuteRenameLegacyForm(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "old", &profile.Profile{Name: "old", AuthToken: "sk-test"})
+
+	if err := Execute([]string{"--rename", "old", "new"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(profilesDir, "new.conf")); err != nil {
+		t.Error("new.conf should exist after rename")
+	}
+	if _, err := os.Stat(filepath.Join(profilesDir, "old.conf")); !os.IsNotExist(err) {
+		t.Error("old.conf should be removed after rename")
+	}
+}
+
+func TestExecuteCopy(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "src", &profile.Profile{Name: "src", AuthToken: "sk-test"})
+
+	if err := Execute([]string{"copy", "src", "dst"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	p, err := profile.LoadProfile(profilesDir, "dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AuthToken != "sk-test" {
+		t.Errorf("AuthToken = %q, want sk-test", p.AuthToken)
+	}
+}
+
+func TestExecuteExportImportRoundTrip(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{
+		Name:      "Work",
+		AuthToken: "sk-work",
+		BaseURL:   "https://api.example.com",
+	})
+
+	exported := filepath.Join(t.TempDir(), "shared.yaml")
+	if err := Execute([]string{"export", "work", "yaml", "--output", exported}, BuildInfo{}); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+
+	if err := Execute([]string{"import", exported, "--name", "imported"}, BuildInfo{}); err != nil {
+		t.Fatalf("import error = %v", err)
+	}
+
+	p, err := profile.LoadProfile(profilesDir, "imported")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AuthToken != "sk-work" || p.BaseURL != "https://api.example.com" {
+		t.Errorf("imported profile = %+v", p)
+	}
+}
+
+func TestExecuteTemplateNew(t *testing.T) {
+	profilesDir := setupTestHome(t)
+
+	if err := Execute([]string{"template", "new", "proxy", "office"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	p, err := profile.LoadProfile(profilesDir, "office")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.HTTPProxy != "http://127.0.0.1:7890" {
+		t.Errorf("HTTPProxy = %q, want template proxy", p.HTTPProxy)
+	}
+}
+
+func TestExecuteUseNoLaunch(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{Name: "work", AuthToken: "sk-work"})
+
+	if err := Execute([]string{"work", "--sync"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	active, err := GetActiveProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active != "work" {
+		t.Errorf("active profile = %q, want work", active)
+	}
+
+	data, err := os.ReadFile(GetSettingsFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "sk-work") {
+		t.Errorf("settings.json should contain the profile token: %s", data)
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	setupTestHome(t)
+
+	tests := [][]string{
+		{"diff", "only-one"},
+		{"--unknown-flag"},
+		{"export", "work", "--bogus"},
+		{"use", "../escape"},
+	}
+
+	for _, args := range tests {
+		if err := Execute(args, BuildInfo{}); err == nil {
+			t.Errorf("Execute(%v) expected error", args)
+		}
+	}
+}
+
+func TestPrintHelpListsCommands(t *testing.T) {
+	var sb strings.Builder
+	if err := PrintHelp(&sb); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range Commands() {
+		if !strings.Contains(sb.String(), c.Name) {
+			t.Errorf("help should mention command %q", c.Name)
+		}
+	}
+}
@@ -0,0 +1,590 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/geoip"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+// newListCommand 列出所有配置
+func newListCommand() *Command {
+	c := newCommand("list", "[--expiring]", "列出所有可用配置")
+	c.Long = `设置了 _EXPIRES_AT 的配置会显示 token 剩余天数。--expiring 只列出
+expiry-warn-days（默认 7）天内过期及已过期的配置，可用 'claude-switcher config' 修改天数。`
+	expiring := c.Flags.Bool("expiring", false, "只列出即将过期或已过期的配置")
+	c.Run = func(args []string) error {
+		return PrintProfileList(config.GetProfilesDir(), *expiring)
+	}
+	return c
+}
+
+// newUseCommand 切换配置并启动 claude
+func newUseCommand() *Command {
+	c := newCommand("use", "[选项] <配置名> [-- <claude 参数...>]", "切换到指定配置并启动 claude")
+	c.Long = `配置名之后的参数会原样透传给 claude，例如:
+  claude-switcher use work -- --model claude-sonnet-4-5
+  claude-switcher work --resume
+
+启动模式:
+  settings  启动前将配置同步到 ~/.claude/settings.json（默认）
+  isolated  不修改 settings.json，只向 claude 进程注入环境变量，
+            可在多个终端中同时使用不同配置
+  home      使用配置独立的 Claude 配置目录 ~/.claude-switcher/homes/<配置名>，
+            通过 CLAUDE_CONFIG_DIR 传给 claude，会话、设置互不影响
+默认模式可通过 'claude-switcher config set launch-mode isolated' 修改。
+
+配置中设置 ALLOWED_COUNTRIES（如 "US,JP"）时，启动前会经过配置的代理查询出口 IP，
+不在列表中时询问是否继续。`
+	c.Passthrough = true
+	var opts UseOptions
+	c.Flags.BoolVar(&opts.NoLaunch, "no-launch", false, "只切换配置并同步到 settings.json，不启动 claude")
+	c.Flags.StringVar(&opts.Mode, "mode", "", "启动模式: settings、isolated 或 home")
+	isolated := c.Flags.Bool("isolated", false, "等同于 --mode isolated")
+	c.Flags.BoolVar(&opts.SkipIPCheck, "skip-ip-check", false, "跳过启动前的出口国家检查")
+	c.Run = func(args []string) error {
+		if len(args) == 0 {
+			return c.usageError()
+		}
+		if *isolated {
+			opts.Mode = config.LaunchModeIsolated
+		}
+		name, claudeArgs := args[0], args[1:]
+		if len(claudeArgs) > 0 && claudeArgs[0] == "--" {
+			claudeArgs = claudeArgs[1:]
+		}
+		opts.Args = claudeArgs
+		return UseProfile(config.GetProfilesDir(), name, opts)
+	}
+	return c
+}
+
+// newDiffCommand 比较两个配置
+func newDiffCommand() *Command {
+	c := newCommand("diff", "<配置1> <配置2>", "比较两个配置的差异")
+	c.Run = func(args []string) error {
+		if len(args) != 2 {
+			return c.usageError()
+		}
+		return PrintDiff(config.GetProfilesDir(), args[0], args[1])
+	}
+	return c
+}
+
+// newValidateCommand 验证配置
+func newValidateCommand() *Command {
+	c := newCommand("validate", "[--deep [--stream]] <配置名>", "验证配置格式并测试连通性")
+	c.Long = `--deep 使用配置的密钥和模型发送一个 max_tokens 为 1 的 Messages API 请求，
+区分认证失败、模型不可用、额度耗尽与地区受限，并报告首 token 耗时。`
+	deep := c.Flags.Bool("deep", false, "发送真实的 Messages API 请求")
+	stream := c.Flags.Bool("stream", false, "深度检查使用流式请求")
+	c.Run = func(args []string) error {
+		if len(args) != 1 {
+			return c.usageError()
+		}
+		if *stream && !*deep {
+			return fmt.Errorf("--stream 需要与 --deep 一起使用")
+		}
+		return PrintValidationReport(config.GetProfilesDir(), args[0], ValidateOptions{Deep: *deep, Stream: *stream})
+	}
+	return c
+}
+
+// newImportCommand 导入配置
+func newImportCommand() *Command {
+	c := newCommand("import", "<文件> [json|yaml] | --from-settings [配置名]", "从 JSON/YAML 文件或 settings.json 导入配置")
+	c.Long = `未指定格式时根据文件扩展名判断（.json、.yaml、.yml）。
+未指定 --name 时使用文件名（不含扩展名）作为配置名称。`
+	name := c.Flags.String("name", "", "保存的配置名称")
+	fromSettings := c.Flags.Bool("from-settings", false, "从 ~/.claude/settings.json 导入当前环境变量")
+	c.Run = func(args []string) error {
+		profilesDir := config.GetProfilesDir()
+
+		if *fromSettings {
+			if len(args) > 1 {
+				return c.usageError()
+			}
+			profileName := *name
+			if len(args) == 1 {
+				profileName = args[0]
+			}
+			if profileName == "" {
+				profileName = "imported"
+			}
+			if valid, _ := config.ValidateConfigName(profileName); !valid {
+				return fmt.Errorf("配置名称格式不正确: %s", profileName)
+			}
+
+			p, err := ImportFromSettings(GetSettingsFilePath(), profileName)
+			if err != nil {
+				return err
+			}
+			if err := SaveProfileToFile(profilesDir, p, profileName); err != nil {
+				return err
+			}
+			fmt.Printf("✓ 已从 settings.json 导入配置: %s\n", profileName)
+			return nil
+		}
+
+		if len(args) < 1 || len(args) > 2 {
+			return c.usageError()
+		}
+
+		format := ImportFormat("")
+		if len(args) == 2 {
+			format = ImportFormat(strings.ToLower(args[1]))
+		}
+		profileName, err := ImportFile(profilesDir, args[0], format, *name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ 已导入配置: %s\n", profileName)
+		return nil
+	}
+	return c
+}
+
+// newExportCommand 导出配置
+func newExportCommand() *Command {
+	c := newCommand("export", "<配置名> [json|yaml|shell] | --all", "导出配置")
+	c.Long = "默认输出到标准输出，使用 --output 写入文件。"
+	all := c.Flags.Bool("all", false, "导出所有配置（JSON 格式）")
+	var output string
+	c.Flags.StringVar(&output, "output", "", "输出文件路径")
+	c.Flags.StringVar(&output, "o", "", "--output 的简写")
+	c.Run = func(args []string) error {
+		profilesDir := config.GetProfilesDir()
+
+		var data []byte
+		var err error
+		if *all {
+			if len(args) != 0 {
+				return c.usageError()
+			}
+			data, err = ExportAllProfiles(profilesDir)
+		} else {
+			if len(args) < 1 || len(args) > 2 {
+				return c.usageError()
+			}
+			format := FormatJSON
+			if len(args) == 2 {
+				format = ExportFormat(strings.ToLower(args[1]))
+			}
+
+			var p *profile.Profile
+			p, err = profile.LoadProfile(profilesDir, args[0])
+			if err != nil {
+				return err
+			}
+			data, err = ExportProfile(p, format)
+		}
+		if err != nil {
+			return fmt.Errorf("导出失败: %w", err)
+		}
+
+		if output == "" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := ExportProfileToPath(data, output); err != nil {
+			return err
+		}
+		fmt.Printf("✓ 已导出到: %s\n", output)
+		return nil
+	}
+	return c
+}
+
+// newTemplateCommand 管理配置模板
+func newTemplateCommand() *Command {
+	c := newCommand("template", "[list] | new <模板> <配置名>", "列出模板或从模板创建配置")
+	c.Run = func(args []string) error {
+		if len(args) == 0 || (len(args) == 1 && args[0] == "list") {
+			PrintTemplates()
+			return nil
+		}
+
+		if args[0] != "new" || len(args) != 3 {
+			return c.usageError()
+		}
+
+		templateName, name := args[1], args[2]
+		if valid, _ := config.ValidateConfigName(name); !valid {
+			return fmt.Errorf("配置名称格式不正确: %s", name)
+		}
+		p := ApplyTemplate(templateName, name)
+		if p == nil {
+			return fmt.Errorf("模板不存在: %s", templateName)
+		}
+		if err := SaveProfileToFile(config.GetProfilesDir(), p, name); err != nil {
+			return err
+		}
+		fmt.Printf("✓ 已从模板 '%s' 创建配置: %s\n", templateName, name)
+		return nil
+	}
+	return c
+}
+
+// newEnvCommand 输出配置的环境变量
+func newEnvCommand() *Command {
+	c := newCommand("env", "<配置名>", "输出配置的环境变量")
+	c.Long = `默认输出 export 语句，可用于当前 shell:
+  eval "$(claude-switcher env work)"`
+	preview := c.Flags.Bool("preview", false, "预览环境变量（遮蔽敏感信息）")
+	c.Run = func(args []string) error {
+		if len(args) != 1 {
+			return c.usageError()
+		}
+		action := EnvActionExport
+		if *preview {
+			action = EnvActionPreview
+		}
+		return ProcessEnvAction(config.GetProfilesDir(), args[0], action)
+	}
+	return c
+}
+
+// newEditCommand 编辑配置
+func newEditCommand() *Command {
+	c := newCommand("edit", "[--raw] <配置名>", "编辑配置")
+	c.Long = `默认逐项提示修改配置。
+使用 --raw 时用 $VISUAL 或 $EDITOR 打开配置文件的副本，保存退出后验证配置，
+验证通过才会替换原文件，失败时可重新打开编辑器修改。`
+	raw := c.Flags.Bool("raw", false, "使用 $VISUAL/$EDITOR 直接编辑配置文件")
+	c.Run = func(args []string) error {
+		if len(args) != 1 {
+			return c.usageError()
+		}
+		name := args[0]
+		if valid, _ := config.ValidateConfigName(name); !valid {
+			return fmt.Errorf("配置名称格式不正确")
+		}
+
+		if *raw {
+			return EditProfileRaw(config.GetProfilesDir(), name)
+		}
+		return EditProfileInteractive(config.GetProfilesDir(), name)
+	}
+	return c
+}
+
+// newRenameCommand 重命名配置
+func newRenameCommand() *Command {
+	c := newCommand("rename", "<旧名称> <新名称>", "重命名配置")
+	c.Run = func(args []string) error {
+		if len(args) != 2 {
+			return c.usageError()
+		}
+		oldName, newName := args[0], args[1]
+		if valid, _ := config.ValidateConfigName(oldName); !valid {
+			return fmt.Errorf("旧配置名称格式不正确")
+		}
+		if valid, _ := config.ValidateConfigName(newName); !valid {
+			return fmt.Errorf("新配置名称格式不正确")
+		}
+
+		if err := profile.RenameProfile(config.GetProfilesDir(), oldName, newName); err != nil {
+			return err
+		}
+		fmt.Printf("✓ 已重命名: %s -> %s\n", oldName, newName)
+		return nil
+	}
+	return c
+}
+
+// newCopyCommand 复制配置
+func newCopyCommand() *Command {
+	c := newCommand("copy", "<源名称> <目标名称>", "复制配置")
+	c.Run = func(args []string) error {
+		if len(args) != 2 {
+			return c.usageError()
+		}
+		srcName, dstName := args[0], args[1]
+		if valid, _ := config.ValidateConfigName(srcName); !valid {
+			return fmt.Errorf("源配置名称格式不正确")
+		}
+		if valid, _ := config.ValidateConfigName(dstName); !valid {
+			return fmt.Errorf("目标配置名称格式不正确")
+		}
+
+		if err := profile.CopyProfile(config.GetProfilesDir(), srcName, dstName); err != nil {
+			return err
+		}
+		fmt.Printf("✓ 已复制: %s -> %s\n", srcName, dstName)
+		return nil
+	}
+	return c
+}
+
+// newUpdateCommand 检查并安装更新
+func newUpdateCommand() *Command {
+	c := newCommand("update", "[--check]", "检查并更新到最新版本")
+	check := c.Flags.Bool("check", false, "只检查是否有新版本，不安装")
+	c.Run = func(args []string) error {
+		if len(args) != 0 {
+			return c.usageError()
+		}
+		if *check {
+			return CheckForUpdates()
+		}
+		return SelfUpdate()
+	}
+	return c
+}
+
+// newTokenCommand 输出配置的 API 密钥，供 settings.json 中的 apiKeyHelper 调用
+func newTokenCommand() *Command {
+	c := newCommand("token", "<配置名>", "输出配置的 API 密钥（供 apiKeyHelper 使用）")
+	c.Long = `同步模式为 helper 时，settings.json 中只写入:
+  "apiKeyHelper": "'/path/to/claude-switcher' token <配置名>"
+claude 启动时调用该命令获取密钥，密钥只保存在 ~/.claude-switcher/profiles 中。`
+	c.Run = func(args []string) error {
+		if len(args) != 1 {
+			return c.usageError()
+		}
+		if valid, _ := config.ValidateConfigName(args[0]); !valid {
+			return fmt.Errorf("配置名称格式不正确: %s", args[0])
+		}
+
+		p, err := loadResolvedProfile(config.GetProfilesDir(), args[0])
+		if err != nil {
+			return err
+		}
+		token := ProfileToken(p)
+		if token == "" {
+			return fmt.Errorf("配置 '%s' 未设置 API 密钥", args[0])
+		}
+		fmt.Println(token)
+		return nil
+	}
+	return c
+}
+
+// newConfigCommand 查看或修改 claude-switcher 全局设置
+func newConfigCommand() *Command {
+	c := newCommand("config", "[set <键> <值>]", "查看或修改全局设置")
+	c.Long = `设置保存在 ~/.claude-switcher/config.json，可用的键:
+  launch-mode  默认启动模式: settings、isolated 或 home
+  sync-mode    密钥写入方式: env（写入 settings.json 的 env）或
+               helper（写入 apiKeyHelper，密钥不落入 settings.json）
+  ip-check-url 出口 IP 查询地址，支持 ip-api.com、ipinfo.io 与 ipapi.co 的 JSON 格式，
+               设为 default 恢复默认值
+  expiry-warn-days
+               token 过期前多少天开始在启动时提醒（默认 7），也是 list --expiring
+               的范围，设为 off 关闭启动提醒`
+	c.Run = func(args []string) error {
+		cfg, err := config.LoadSwitcherConfig()
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			fmt.Printf("launch-mode = %s\n", cfg.LaunchMode)
+			fmt.Printf("sync-mode = %s\n", cfg.SyncMode)
+			if cfg.IPCheckURL != "" {
+				fmt.Printf("ip-check-url = %s\n", cfg.IPCheckURL)
+			} else {
+				fmt.Printf("ip-check-url = %s (默认)\n", geoip.DefaultEndpoint)
+			}
+			if *cfg.ExpiryWarnDays < 0 {
+				fmt.Println("expiry-warn-days = off")
+			} else {
+				fmt.Printf("expiry-warn-days = %d\n", *cfg.ExpiryWarnDays)
+			}
+			return nil
+		}
+		if args[0] != "set" || len(args) != 3 {
+			return c.usageError()
+		}
+
+		key, value := args[1], args[2]
+		switch key {
+		case "launch-mode":
+			if !config.ValidateLaunchMode(value) {
+				return fmt.Errorf("未知的启动模式: %s（可选: %s）", value, strings.Join(config.LaunchModes(), ", "))
+			}
+			cfg.LaunchMode = value
+		case "sync-mode":
+			if !config.ValidateSyncMode(value) {
+				return fmt.Errorf("未知的同步模式: %s（可选: %s）", value, strings.Join(config.SyncModes(), ", "))
+			}
+			cfg.SyncMode = value
+		case "ip-check-url":
+			switch {
+			case value == "default":
+				cfg.IPCheckURL = ""
+			case config.ValidateURL(value):
+				cfg.IPCheckURL = value
+			default:
+				return fmt.Errorf("URL 格式无效: %s", value)
+			}
+		case "expiry-warn-days":
+			days := -1
+			if value != "off" {
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					return fmt.Errorf("天数无效: %s（应为非负整数或 off）", value)
+				}
+				days = n
+			}
+			cfg.ExpiryWarnDays = &days
+		default:
+			return fmt.Errorf("未知的设置项: %s", key)
+		}
+
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("✓ 已设置 %s = %s\n", key, value)
+		if key == "sync-mode" {
+			fmt.Println("下次切换配置时生效，可运行 'claude-switcher use --no-launch <配置名>' 立即同步")
+		}
+		return nil
+	}
+	return c
+}
+
+// newVersionCommand 显示版本信息
+func newVersionCommand() *Command {
+	c := newCommand("version", "", "显示版本信息")
+	c.Run = func(args []string) error {
+		fmt.Printf("%s version %s (commit: %s, date: %s)\n", AppName, buildInfo.Version, buildInfo.Commit, buildInfo.Date)
+		return nil
+	}
+	return c
+}
+
+// newHelpCommand 显示帮助
+func newHelpCommand() *Command {
+	c := newCommand("help", "[命令]", "显示帮助信息")
+	c.Run = func(args []string) error {
+		if len(args) == 0 {
+			return PrintHelp(os.Stdout)
+		}
+		sub := findCommand(args[0])
+		if sub == nil {
+			return fmt.Errorf("未知命令: %s", args[0])
+		}
+		sub.PrintUsage(os.Stdout)
+		return nil
+	}
+	return c
+}
+
+// PrintProfileList 打印所有配置，expiringOnly 为 true 时只列出 expiry-warn-days 天内过期的配置
+func PrintProfileList(profilesDir string, expiringOnly bool) error {
+	names, err := profile.ListProfiles(profilesDir)
+	if err != nil {
+		return err
+	}
+
+	now := nowFunc()
+	warnDays := expiryWarnDays()
+	if warnDays < 0 {
+		warnDays = 0
+	}
+
+	if expiringOnly {
+		fmt.Printf("%d 天内过期的配置:\n", warnDays)
+	} else {
+		fmt.Println("可用配置:")
+	}
+	count := 0
+	for _, name := range names {
+		p, err := profile.LoadProfile(profilesDir, name)
+		if err != nil {
+			continue
+		}
+		if expiringOnly && !isExpiring(p, now, warnDays) {
+			continue
+		}
+		displayName := name
+		if p.Name != "" {
+			displayName = p.Name
+		}
+		if label := expiryLabel(p, now); label != "" {
+			fmt.Printf("  %s - %s  [%s]\n", name, displayName, label)
+		} else {
+			fmt.Printf("  %s - %s\n", name, displayName)
+		}
+		count++
+	}
+	if expiringOnly && count == 0 {
+		fmt.Println("  （无）")
+	}
+	return nil
+}
+
+// UseOptions 切换配置的选项
+type UseOptions struct {
+	Mode        string   // 启动模式，为空时使用 config.json 中的设置
+	NoLaunch    bool     // 只切换配置，不启动 claude
+	SkipIPCheck bool     // 跳过 ALLOWED_COUNTRIES 出口检查
+	Args        []string // 透传给 claude 的参数
+}
+
+// UseProfile 切换到指定配置并按启动模式启动 claude
+func UseProfile(profilesDir, name string, opts UseOptions) error {
+	if valid, _ := config.ValidateConfigName(name); !valid {
+		return fmt.Errorf("配置名称格式不正确: %s", name)
+	}
+
+	// name 可以是配置组，此时 target 为组内选中的配置
+	target, p, err := resolveLaunchTarget(profilesDir, name)
+	if err != nil {
+		return fmt.Errorf("%w\n使用 'claude-switcher list' 查看可用配置", err)
+	}
+	if target != name {
+		fmt.Printf("配置组 %s: 使用 %s\n", name, target)
+	}
+	warnExpiry(os.Stderr, target, p)
+
+	if !opts.NoLaunch && !opts.SkipIPCheck && !checkExitCountry(p) {
+		fmt.Println("已取消启动")
+		return nil
+	}
+
+	// --no-launch 默认同步到 settings.json，显式指定模式时按该模式处理
+	if opts.NoLaunch {
+		if p.Protocol == profile.ProtocolOpenAI {
+			fmt.Fprintln(os.Stderr, "⚠  OpenAI 协议的配置需要通过 claude-switcher 启动 claude，或配合 'claude-switcher gateway' 使用")
+		}
+		mode := config.LaunchModeSettings
+		if opts.Mode != "" {
+			if mode, err = resolveLaunchMode(opts.Mode); err != nil {
+				return err
+			}
+		}
+		if _, err := prepareLaunch(target, p, mode); err != nil {
+			return err
+		}
+		if err := SetActiveProfile(name); err != nil {
+			return err
+		}
+		fmt.Printf("✓ 已切换到配置: %s\n", name)
+		return nil
+	}
+
+	mode, err := resolveLaunchMode(opts.Mode)
+	if err != nil {
+		return err
+	}
+	// OpenAI 协议的配置需要本地转换代理，由本进程在 claude 运行期间提供
+	env, stop, err := launchEnv(target, p, mode)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	// 设置活动配置
+	if err := SetActiveProfile(name); err != nil {
+		return err
+	}
+
+	fmt.Printf("使用配置: %s\n", name)
+	return runClaudeFunc(env, opts.Args...)
+}
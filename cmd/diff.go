@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/proxy"
 )
 
 // FieldDiff 表示单个字段的差异
@@ -51,16 +52,24 @@ func DiffProfiles(p1, p2 *profile.Profile) *ProfileDiff {
 	if p1.HTTPProxy != p2.HTTPProxy {
 		diff.Differences = append(diff.Differences, FieldDiff{
 			Field:  "HTTPProxy",
-			Value1: p1.HTTPProxy,
-			Value2: p2.HTTPProxy,
+			Value1: proxy.Mask(p1.HTTPProxy),
+			Value2: proxy.Mask(p2.HTTPProxy),
 		})
 	}
 
 	if p1.HTTPSProxy != p2.HTTPSProxy {
 		diff.Differences = append(diff.Differences, FieldDiff{
 			Field:  "HTTPSProxy",
-			Value1: p1.HTTPSProxy,
-			Value2: p2.HTTPSProxy,
+			Value1: proxy.Mask(p1.HTTPSProxy),
+			Value2: proxy.Mask(p2.HTTPSProxy),
+		})
+	}
+
+	if p1.NoProxy != p2.NoProxy {
+		diff.Differences = append(diff.Differences, FieldDiff{
+			Field:  "NoProxy",
+			Value1: p1.NoProxy,
+			Value2: p2.NoProxy,
 		})
 	}
 
@@ -72,6 +81,38 @@ func DiffProfiles(p1, p2 *profile.Profile) *ProfileDiff {
 		})
 	}
 
+	if p1.Protocol != p2.Protocol {
+		diff.Differences = append(diff.Differences, FieldDiff{
+			Field:  "Protocol",
+			Value1: p1.Protocol,
+			Value2: p2.Protocol,
+		})
+	}
+
+	if c1, c2 := strings.Join(p1.AllowedCountries, ","), strings.Join(p2.AllowedCountries, ","); c1 != c2 {
+		diff.Differences = append(diff.Differences, FieldDiff{
+			Field:  "AllowedCountries",
+			Value1: c1,
+			Value2: c2,
+		})
+	}
+
+	if e1, e2 := profile.FormatExpiry(p1.ExpiresAt), profile.FormatExpiry(p2.ExpiresAt); e1 != e2 {
+		diff.Differences = append(diff.Differences, FieldDiff{
+			Field:  "ExpiresAt",
+			Value1: e1,
+			Value2: e2,
+		})
+	}
+
+	if p1.Note != p2.Note {
+		diff.Differences = append(diff.Differences, FieldDiff{
+			Field:  "Note",
+			Value1: p1.Note,
+			Value2: p2.Note,
+		})
+	}
+
 	// 比较自定义环境变量
 	for k, v1 := range p1.EnvVars {
 		if v2, ok := p2.EnvVars[k]; !ok || v1 != v2 {
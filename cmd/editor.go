@@ -2,12 +2,15 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/secret"
 )
 
 // ReaderProvider 定义了读取用户输入的接口
@@ -15,11 +18,14 @@ type ReaderProvider interface {
 	ReadString(delim byte) (string, error)
 }
 
+// stdinReader 在多次读取间共享缓冲，避免管道输入被提前读入后丢失
+var stdinReader = bufio.NewReader(os.Stdin)
+
 // StdioReader 使用标准输入
 type StdioReader struct{}
 
 func (r StdioReader) ReadString(delim byte) (string, error) {
-	return bufio.NewReader(os.Stdin).ReadString(delim)
+	return stdinReader.ReadString(delim)
 }
 
 // EditProfileInteractive 交互式编辑配置
@@ -65,16 +71,20 @@ func EditProfileInteractiveWithReader(profilesDir, name string, reader ReaderPro
 	p.BaseURL = displayAndPrompt("ANTHROPIC_BASE_URL", p.BaseURL)
 
 	// HTTP Proxy
+	// https_proxy 与 http_proxy 相同时一并修改，单独设置的 https_proxy 保持不变
+	oldProxy := p.HTTPProxy
 	p.HTTPProxy = displayAndPrompt("HTTP Proxy", p.HTTPProxy)
-	p.HTTPSProxy = p.HTTPProxy
+	if p.HTTPSProxy == oldProxy {
+		p.HTTPSProxy = p.HTTPProxy
+	}
 
 	// Model
 	p.Model = displayAndPrompt("ANTHROPIC_MODEL", p.Model)
 
+	promptEnvVars(p, reader)
+
 	// 保存配置
-	filePath := filepath.Join(profilesDir, name+".conf")
-	content := formatProfile(p)
-	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+	if err := profile.SaveProfile(profilesDir, name, p); err != nil {
 		return err
 	}
 
@@ -82,8 +92,128 @@ func EditProfileInteractiveWithReader(profilesDir, name string, reader ReaderPro
 	return nil
 }
 
-// maskValue 遮蔽敏感值
+// promptEnvVars 询问是否编辑自定义环境变量
+func promptEnvVars(p *profile.Profile, reader ReaderProvider) {
+	fmt.Printf("编辑自定义环境变量（当前 %d 个）? (y/N): ", len(p.EnvVars))
+	input, _ := reader.ReadString('\n')
+	if strings.ToLower(strings.TrimSpace(input)) != "y" {
+		return
+	}
+	if p.EnvVars == nil {
+		p.EnvVars = make(map[string]string)
+	}
+	EditEnvVars(p.EnvVars, reader)
+}
+
+// runEditorFunc 用编辑器打开文件（可在测试中替换）
+var runEditorFunc = RunEditor
+
+// EditorCommand 返回用户的编辑器命令，依次读取 $VISUAL、$EDITOR
+// 都未设置时 Windows 使用 notepad，其他平台使用 vi
+func EditorCommand() []string {
+	for _, key := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(key)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if IsWindows() {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// RunEditor 用编辑器打开文件并等待编辑器退出
+func RunEditor(path string) error {
+	editor := EditorCommand()
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("编辑器 %s 运行失败: %w", editor[0], err)
+	}
+	return nil
+}
+
+// EditProfileRaw 用外部编辑器直接编辑配置文件
+func EditProfileRaw(profilesDir, name string) error {
+	return EditProfileRawWithReader(profilesDir, name, StdioReader{})
+}
+
+// EditProfileRawWithReader 用外部编辑器编辑配置文件的临时副本（可注入 Reader 进行测试）
+// 编辑器退出后解析并验证配置，验证失败时询问是否重新编辑，通过后原子替换原文件
+func EditProfileRawWithReader(profilesDir, name string, reader ReaderProvider) error {
+	original, err := profile.ReadFile(profilesDir, name)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("配置文件不存在: %s", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 加密的配置在编辑期间以明文保存，临时文件放在配置目录下权限为 0700 的子目录中，
+	// 而不是公共的临时目录；编辑器的交换文件也会写在这里，编辑结束后一并删除
+	tmpDir, err := os.MkdirTemp(profilesDir, ".edit-"+name+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpPath := filepath.Join(tmpDir, name+".conf")
+	if err := os.WriteFile(tmpPath, original, 0600); err != nil {
+		return err
+	}
+
+	for {
+		if err := runEditorFunc(tmpPath); err != nil {
+			return err
+		}
+
+		edited, err := os.ReadFile(tmpPath)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(edited, original) {
+			fmt.Println("配置未修改")
+			return nil
+		}
+
+		doc := profile.ParseDocument(edited)
+		invalid := doc.Invalid()
+		result := ValidateProfile(profile.FromDocument(doc))
+		if result.Valid && len(invalid) == 0 {
+			if len(result.Warnings) > 0 {
+				fmt.Print(FormatValidationResult(result))
+			}
+			if err := profile.SaveRaw(profilesDir, name, edited); err != nil {
+				return err
+			}
+			fmt.Printf("✓ 配置 '%s' 已保存\n", name)
+			return nil
+		}
+
+		if len(invalid) > 0 {
+			// 无法解析的行会被忽略，其中的键不会生效，保存前必须让用户看到
+			fmt.Println("\n✗ 以下行无法解析（可能是引号未闭合），其中的设置不会生效:")
+			for _, l := range invalid {
+				fmt.Printf("  第 %d 行: %s\n", l.Line, l.Text)
+			}
+		}
+		if !result.Valid || len(result.Warnings) > 0 {
+			fmt.Print(FormatValidationResult(result))
+		}
+		fmt.Print("重新打开编辑器修改? (Y/n): ")
+		input, _ := reader.ReadString('\n')
+		if answer := strings.ToLower(strings.TrimSpace(input)); answer == "n" || answer == "no" {
+			return fmt.Errorf("配置验证失败，未保存修改")
+		}
+	}
+}
+
+// maskValue 遮蔽敏感值，密钥引用原样显示
 func maskValue(value string) string {
+	if secret.IsRef(value) {
+		return value
+	}
 	if len(value) <= 4 {
 		return "****"
 	}
@@ -3,7 +3,7 @@ package cmd
 import (
 	"os"
 	"path/filepath"
-	"strings"
+	"reflect"
 	"testing"
 
 	"github.com/fiftyk/claude-switcher/internal/profile"
@@ -36,7 +36,7 @@ func TestEditProfileInteractiveWithReader(t *testing.T) {
 		HTTPSProxy: "http://127.0.0.1:8080",
 		Model:      "claude-2",
 	}
-	content := formatProfile(originalProfile)
+	content := string(profile.Format(originalProfile))
 	if err := os.WriteFile(filepath.Join(profilesDir, "test.conf"), []byte(content), 0600); err != nil {
 		t.Fatal(err)
 	}
@@ -69,7 +69,7 @@ func TestEditProfileInteractiveWithReader_UpdateToken(t *testing.T) {
 		Name:      "test",
 		AuthToken: "sk-original",
 	}
-	content := formatProfile(originalProfile)
+	content := string(profile.Format(originalProfile))
 	if err := os.WriteFile(filepath.Join(profilesDir, "test.conf"), []byte(content), 0600); err != nil {
 		t.Fatal(err)
 	}
@@ -94,6 +94,64 @@ func TestEditProfileInteractiveWithReader_UpdateToken(t *testing.T) {
 	}
 }
 
+func TestEditProfileInteractiveWithReader_PreservesLayout(t *testing.T) {
+	profilesDir := t.TempDir()
+
+	content := "# 公司账号\nNAME=\"test\"\nANTHROPIC_AUTH_TOKEN=sk-original # 旧 token\n\nhttps_proxy=\"http://127.0.0.1:9090\"\nZ_VAR=\"z\"\nA_VAR=\"a\"\n"
+	filePath := filepath.Join(profilesDir, "test.conf")
+	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 全部直接回车时文件不变
+	if err := EditProfileInteractiveWithReader(profilesDir, "test", &mockReader{}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filePath); string(data) != content {
+		t.Errorf("file changed without edits:\n%s", data)
+	}
+
+	// 只改写修改过的键，新的键追加到末尾
+	reader := &mockReader{inputs: []string{"", "sk-new", "", "", "claude-opus-4-1"}}
+	if err := EditProfileInteractiveWithReader(profilesDir, "test", reader); err != nil {
+		t.Fatal(err)
+	}
+	want := "# 公司账号\nNAME=\"test\"\nANTHROPIC_AUTH_TOKEN=\"sk-new\" # 旧 token\n\nhttps_proxy=\"http://127.0.0.1:9090\"\nZ_VAR=\"z\"\nA_VAR=\"a\"\nANTHROPIC_MODEL=\"claude-opus-4-1\"\n"
+	if data, _ := os.ReadFile(filePath); string(data) != want {
+		t.Errorf("file =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestEditProfileInteractiveWithReader_EnvVars(t *testing.T) {
+	profilesDir := t.TempDir()
+	writeTestProfile(t, profilesDir, "test", &profile.Profile{
+		Name:    "test",
+		EnvVars: map[string]string{"API_TIMEOUT_MS": "600000"},
+	})
+
+	reader := &mockReader{inputs: []string{
+		"", "", "", "", "",
+		"y",
+		"e", "API_TIMEOUT_MS", "300000",
+		"a", "CLAUDE_CODE_MAX", "32000",
+		"",
+	}}
+	if err := EditProfileInteractiveWithReader(profilesDir, "test", reader); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := profile.LoadProfile(profilesDir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.EnvVars["API_TIMEOUT_MS"] != "300000" {
+		t.Errorf("API_TIMEOUT_MS = %q, want 300000", p.EnvVars["API_TIMEOUT_MS"])
+	}
+	if p.EnvVars["CLAUDE_CODE_MAX_OUTPUT_TOKENS"] != "32000" {
+		t.Errorf("CLAUDE_CODE_MAX_OUTPUT_TOKENS = %q, want 32000", p.EnvVars["CLAUDE_CODE_MAX_OUTPUT_TOKENS"])
+	}
+}
+
 func TestEditProfileInteractive_ProfileNotFound(t *testing.T) {
 	profilesDir := t.TempDir()
 	reader := &mockReader{}
@@ -128,54 +186,149 @@ func TestMaskValue(t *testing.T) {
 	}
 }
 
-func TestFormatProfile_AllFields(t *testing.T) {
-	p := &profile.Profile{
-		Name:       "Test Profile",
-		AuthToken:  "sk-test-token",
-		BaseURL:    "https://api.example.com",
-		HTTPProxy:  "http://127.0.0.1:7890",
-		HTTPSProxy: "http://127.0.0.1:7890",
-		Model:      "claude-3-5-sonnet",
-		EnvVars:    map[string]string{"CUSTOM_VAR": "custom_value"},
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "nano -w")
+	if got := EditorCommand(); !reflect.DeepEqual(got, []string{"nano", "-w"}) {
+		t.Errorf("EditorCommand() = %v, want [nano -w]", got)
+	}
+
+	t.Setenv("VISUAL", "code --wait")
+	if got := EditorCommand(); !reflect.DeepEqual(got, []string{"code", "--wait"}) {
+		t.Errorf("EditorCommand() = %v, want $VISUAL", got)
+	}
+}
+
+// stubEditor 依次将 contents 写入被编辑的文件
+func stubEditor(t *testing.T, contents ...string) *int {
+	t.Helper()
+	calls := 0
+	orig := runEditorFunc
+	runEditorFunc = func(path string) error {
+		if calls >= len(contents) {
+			t.Fatalf("editor opened %d times, want %d", calls+1, len(contents))
+		}
+		calls++
+		return os.WriteFile(path, []byte(contents[calls-1]), 0600)
+	}
+	t.Cleanup(func() { runEditorFunc = orig })
+	return &calls
+}
+
+func TestEditProfileRawWithReader(t *testing.T) {
+	profilesDir := t.TempDir()
+	original := "# 工作配置\nNAME=\"work\"\n"
+	filePath := filepath.Join(profilesDir, "work.conf")
+	if err := os.WriteFile(filePath, []byte(original), 0600); err != nil {
+		t.Fatal(err)
 	}
 
-	result := formatProfile(p)
+	invalid := original + "http_proxy=\"no-port\"\n"
+	valid := original + "http_proxy=\"socks5://127.0.0.1:1080\"\n"
+	calls := stubEditor(t, invalid, valid)
 
-	// 验证所有字段都被格式化
-	if !strings.Contains(result, "NAME=\"Test Profile\"") {
-		t.Error("expected NAME in output")
+	// 第一次验证失败，直接回车重新打开编辑器
+	if err := EditProfileRawWithReader(profilesDir, "work", &mockReader{inputs: []string{""}}); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 2 {
+		t.Errorf("editor opened %d times, want 2", *calls)
 	}
-	if !strings.Contains(result, "ANTHROPIC_AUTH_TOKEN=\"sk-test-token\"") {
-		t.Error("expected AuthToken in output")
+	if data, _ := os.ReadFile(filePath); string(data) != valid {
+		t.Errorf("file = %q, want %q", data, valid)
+	}
+}
+
+func TestEditProfileRawRejectsUnparsedLines(t *testing.T) {
+	profilesDir := t.TempDir()
+	original := "NAME=\"work\"\n"
+	filePath := filepath.Join(profilesDir, "work.conf")
+	if err := os.WriteFile(filePath, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 引号未闭合的行会被忽略，验证本身能通过，但不能直接保存
+	unclosed := original + "ANTHROPIC_MODEL=\"claude-opus-4-1\n"
+	calls := stubEditor(t, unclosed)
+	if err := EditProfileRawWithReader(profilesDir, "work", &mockReader{inputs: []string{"n"}}); err == nil {
+		t.Error("expected error when unparsed lines remain")
+	}
+	if *calls != 1 {
+		t.Errorf("editor opened %d times, want 1", *calls)
+	}
+	if data, _ := os.ReadFile(filePath); string(data) != original {
+		t.Errorf("original file should be unchanged, got %q", data)
+	}
+}
+
+func TestEditProfileRawKeepsTempFileInProfilesDir(t *testing.T) {
+	profilesDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(profilesDir, "work.conf"), []byte("NAME=\"work\"\n"), 0600); err != nil {
+		t.Fatal(err)
 	}
-	if !strings.Contains(result, "ANTHROPIC_BASE_URL=\"https://api.example.com\"") {
-		t.Error("expected BaseURL in output")
+
+	var editedPath string
+	orig := runEditorFunc
+	runEditorFunc = func(path string) error {
+		editedPath = path
+		info, err := os.Stat(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if perm := info.Mode().Perm(); perm != 0700 {
+			t.Errorf("temp dir permissions = %o, want 700", perm)
+		}
+		return nil
 	}
-	if !strings.Contains(result, "http_proxy=\"http://127.0.0.1:7890\"") {
-		t.Error("expected http_proxy in output")
+	defer func() { runEditorFunc = orig }()
+
+	if err := EditProfileRawWithReader(profilesDir, "work", &mockReader{}); err != nil {
+		t.Fatal(err)
 	}
-	if !strings.Contains(result, "ANTHROPIC_MODEL=\"claude-3-5-sonnet\"") {
-		t.Error("expected Model in output")
+	// 解密后的内容不能写到公共临时目录
+	if filepath.Dir(filepath.Dir(editedPath)) != profilesDir {
+		t.Errorf("temp file %s should be inside %s", editedPath, profilesDir)
 	}
-	if !strings.Contains(result, "CUSTOM_VAR=\"custom_value\"") {
-		t.Error("expected custom env var in output")
+	if _, err := os.Stat(filepath.Dir(editedPath)); !os.IsNotExist(err) {
+		t.Error("temp dir should be removed after editing")
 	}
 }
 
-func TestFormatProfile_EmptyFields(t *testing.T) {
-	p := &profile.Profile{
-		Name: "Minimal",
+func TestEditProfileRawWithReader_Abort(t *testing.T) {
+	profilesDir := t.TempDir()
+	original := "NAME=\"work\"\n"
+	filePath := filepath.Join(profilesDir, "work.conf")
+	if err := os.WriteFile(filePath, []byte(original), 0600); err != nil {
+		t.Fatal(err)
 	}
 
-	result := formatProfile(p)
+	stubEditor(t, "ANTHROPIC_BASE_URL=not-a-url\n")
+	if err := EditProfileRawWithReader(profilesDir, "work", &mockReader{inputs: []string{"n"}}); err == nil {
+		t.Error("expected error when validation fails and user declines to re-edit")
+	}
+	if data, _ := os.ReadFile(filePath); string(data) != original {
+		t.Errorf("original file should be unchanged, got %q", data)
+	}
 
-	// 验证包含 NAME
-	if !strings.Contains(result, "NAME=\"Minimal\"") {
-		t.Error("expected NAME in output")
+	entries, _ := os.ReadDir(profilesDir)
+	if len(entries) != 1 {
+		t.Errorf("expected no leftover files, got %d entries", len(entries))
+	}
+}
+
+func TestExecuteEditRaw(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{Name: "work"})
+	stubEditor(t, "NAME=\"work\"\nANTHROPIC_MODEL=\"claude-opus-4-1\"\n")
+
+	if err := Execute([]string{"edit", "--raw", "work"}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := profile.LoadProfile(profilesDir, "work")
+	if err != nil {
+		t.Fatal(err)
 	}
-	// 验证只有2行（注释 + NAME）
-	lines := strings.Split(strings.TrimSpace(result), "\n")
-	if len(lines) != 2 {
-		t.Errorf("expected 2 lines (comment + NAME), got %d", len(lines))
+	if p.Model != "claude-opus-4-1" {
+		t.Errorf("Model = %q, want claude-opus-4-1", p.Model)
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/proxy"
 )
 
 // PreviewEnvVars 预览配置将设置的环境变量
@@ -17,9 +18,8 @@ func PreviewEnvVars(p *profile.Profile) map[string]string {
 	if p.BaseURL != "" {
 		envVars["ANTHROPIC_BASE_URL"] = p.BaseURL
 	}
-	if p.HTTPProxy != "" {
-		envVars["http_proxy"] = p.HTTPProxy
-		envVars["https_proxy"] = p.HTTPProxy
+	for k, v := range ProxyEnvVars(p) {
+		envVars[k] = v
 	}
 	if p.Model != "" {
 		envVars["ANTHROPIC_MODEL"] = p.Model
@@ -46,13 +46,17 @@ func FormatEnvVarsForDisplay(envVars map[string]string) string {
 	}
 
 	sb.WriteString(strings.Repeat("-", 50) + "\n")
-	sb.WriteString("\n使用 'eval \"$(claude-switcher <配置名> --env)\"' 设置环境变量\n")
+	sb.WriteString("\n使用 'eval \"$(claude-switcher env <配置名>)\"' 设置环境变量\n")
 
 	return sb.String()
 }
 
 // MaskEnvValue 遮蔽敏感的环境变量值
 func MaskEnvValue(key, value string) string {
+	// 代理地址只遮蔽其中的密码
+	if strings.HasSuffix(strings.ToLower(key), "_proxy") {
+		return proxy.Mask(value)
+	}
 	// 只遮蔽 Auth Token
 	if strings.Contains(strings.ToLower(key), "auth_token") ||
 	   strings.Contains(strings.ToLower(key), "api_key") ||
@@ -62,13 +66,13 @@ func MaskEnvValue(key, value string) string {
 	return value
 }
 
-// GenerateExportCommand 生成 export 命令
+// GenerateExportCommand 生成 export 命令，值使用单引号转义，可安全 eval
 func GenerateExportCommand(envVars map[string]string) string {
 	var sb strings.Builder
 
 	sb.WriteString("# 导出环境变量\n")
 	for k, v := range envVars {
-		sb.WriteString(fmt.Sprintf("export %s=\"%s\"\n", k, v))
+		sb.WriteString(fmt.Sprintf("export %s=%s\n", k, shellQuote(v)))
 	}
 
 	return sb.String()
@@ -98,15 +102,18 @@ func ProcessEnvAction(profilesDir, profileName string, action EnvActionType) err
 		return fmt.Errorf("无法加载配置: %w", err)
 	}
 
-	envVars := PreviewEnvVars(p)
-
 	switch action {
 	case EnvActionPreview:
 		PrintEnvPreview(p)
 	case EnvActionExport:
-		fmt.Print(GenerateExportCommand(envVars))
+		// 导出到 shell 时解析密钥引用，预览只显示引用
+		resolved, err := profile.Resolve(p)
+		if err != nil {
+			return err
+		}
+		fmt.Print(GenerateExportCommand(PreviewEnvVars(resolved)))
 	case EnvActionEval:
-		fmt.Printf("eval \"$(claude-switcher %s --env)\"  # 当前不支持\n", profileName)
+		fmt.Printf("eval \"$(claude-switcher env %s)\"\n", profileName)
 	}
 
 	return nil
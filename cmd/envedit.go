@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+// EnvVarInfo 描述一个 Claude Code 支持的环境变量
+type EnvVarInfo struct {
+	Name string
+	Desc string
+}
+
+// KnownEnvVars Claude Code 常用的环境变量，用于编辑时补全
+// 已作为独立字段编辑的 ANTHROPIC_AUTH_TOKEN、ANTHROPIC_BASE_URL、ANTHROPIC_MODEL 不在其中
+var KnownEnvVars = []EnvVarInfo{
+	{"ANTHROPIC_API_KEY", "以 X-Api-Key 请求头发送的 API Key"},
+	{"ANTHROPIC_CUSTOM_HEADERS", "附加的请求头，格式为 Name: Value，多个用换行分隔"},
+	{"ANTHROPIC_DEFAULT_HAIKU_MODEL", "Haiku 级别使用的模型"},
+	{"ANTHROPIC_DEFAULT_OPUS_MODEL", "Opus 级别使用的模型"},
+	{"ANTHROPIC_DEFAULT_SONNET_MODEL", "Sonnet 级别使用的模型"},
+	{"ANTHROPIC_SMALL_FAST_MODEL", "后台任务使用的小模型（已由 ANTHROPIC_DEFAULT_HAIKU_MODEL 取代）"},
+	{"API_TIMEOUT_MS", "API 请求超时时间（毫秒）"},
+	{"BASH_DEFAULT_TIMEOUT_MS", "Bash 命令默认超时时间（毫秒）"},
+	{"BASH_MAX_OUTPUT_LENGTH", "Bash 输出的最大字符数，超出部分截断"},
+	{"BASH_MAX_TIMEOUT_MS", "Bash 命令允许设置的最大超时时间（毫秒）"},
+	{"CLAUDE_CODE_API_KEY_HELPER_TTL_MS", "apiKeyHelper 结果的缓存时间（毫秒）"},
+	{"CLAUDE_CODE_DISABLE_NONESSENTIAL_TRAFFIC", "设为 1 时禁用自动更新、遥测等非必要请求"},
+	{"CLAUDE_CODE_MAX_OUTPUT_TOKENS", "单次请求的最大输出 token 数"},
+	{"CLAUDE_CODE_SUBAGENT_MODEL", "子代理使用的模型"},
+	{"CLAUDE_CODE_USE_BEDROCK", "设为 1 时使用 Amazon Bedrock"},
+	{"CLAUDE_CODE_USE_VERTEX", "设为 1 时使用 Google Vertex AI"},
+	{"DISABLE_AUTOUPDATER", "设为 1 时禁用自动更新"},
+	{"DISABLE_COST_WARNINGS", "设为 1 时不显示费用提示"},
+	{"DISABLE_ERROR_REPORTING", "设为 1 时不上报错误"},
+	{"DISABLE_PROMPT_CACHING", "设为 1 时禁用提示缓存"},
+	{"DISABLE_TELEMETRY", "设为 1 时禁用遥测"},
+	{"MAX_MCP_OUTPUT_TOKENS", "MCP 工具响应的最大 token 数"},
+	{"MAX_THINKING_TOKENS", "扩展思考的 token 预算"},
+	{"MCP_TIMEOUT", "MCP 服务器启动超时时间（毫秒）"},
+	{"MCP_TOOL_TIMEOUT", "MCP 工具执行超时时间（毫秒）"},
+}
+
+// envVarDesc 返回已知环境变量的说明
+func envVarDesc(name string) string {
+	for _, v := range KnownEnvVars {
+		if v.Name == name {
+			return v.Desc
+		}
+	}
+	return ""
+}
+
+// CompleteEnvName 在候选名称中补全 input（不区分大小写的前缀匹配）
+// 完全匹配或只有一个候选时返回补全后的名称，否则返回所有候选
+func CompleteEnvName(input string, candidates []string) (string, []string) {
+	upper := strings.ToUpper(input)
+	var matches []string
+	for _, c := range candidates {
+		if strings.EqualFold(c, input) {
+			return c, nil
+		}
+		if strings.HasPrefix(strings.ToUpper(c), upper) {
+			matches = append(matches, c)
+		}
+	}
+	sort.Strings(matches)
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	return "", matches
+}
+
+// isEnvName 判断是否为合法的环境变量名
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// EditEnvVars 交互式编辑自定义环境变量，直接修改 vars
+// 支持添加、修改、删除，输入变量名前缀可补全
+func EditEnvVars(vars map[string]string, reader ReaderProvider) {
+	readLine := func(prompt string) string {
+		fmt.Print(prompt)
+		input, _ := reader.ReadString('\n')
+		return strings.TrimSpace(input)
+	}
+
+	// readName 读取变量名，candidates 为空时不补全；allowNew 为 true 时接受不在候选中的名称
+	readName := func(candidates []string, allowNew bool) string {
+		for {
+			input := readLine("变量名（输入前缀补全，? 列出候选，直接回车取消）: ")
+			switch input {
+			case "":
+				return ""
+			case "?":
+				printEnvCandidates(candidates)
+				continue
+			}
+
+			name, matches := CompleteEnvName(input, candidates)
+			if name != "" {
+				if name != input {
+					fmt.Printf("  → %s\n", name)
+				}
+				return name
+			}
+			if len(matches) > 1 {
+				printEnvCandidates(matches)
+				continue
+			}
+			if !allowNew {
+				fmt.Printf("变量不存在: %s\n", input)
+				continue
+			}
+			if !isEnvName(input) {
+				fmt.Printf("变量名无效: %s（只能包含字母、数字和下划线，不能以数字开头）\n", input)
+				continue
+			}
+			return input
+		}
+	}
+
+	for {
+		fmt.Println()
+		printEnvVars(vars)
+		action := readLine("操作 [a 添加 / e 修改 / d 删除 / 直接回车完成]: ")
+
+		switch strings.ToLower(action) {
+		case "":
+			return
+
+		case "a", "add":
+			var names []string
+			for _, v := range KnownEnvVars {
+				if _, ok := vars[v.Name]; !ok {
+					names = append(names, v.Name)
+				}
+			}
+			name := readName(names, true)
+			if name == "" {
+				continue
+			}
+			if profile.IsFieldKey(name) {
+				fmt.Printf("变量名 %s 保留给配置字段或内部元数据，不能作为自定义环境变量\n", name)
+				continue
+			}
+			if _, ok := vars[name]; ok {
+				fmt.Printf("变量 %s 已存在，请使用 e 修改\n", name)
+				continue
+			}
+			if desc := envVarDesc(name); desc != "" {
+				fmt.Printf("  %s\n", desc)
+			}
+			value := readLine("值: ")
+			if value == "" {
+				fmt.Println("值为空，已取消")
+				continue
+			}
+			vars[name] = value
+
+		case "e", "edit":
+			name := readName(sortedKeys(vars), false)
+			if name == "" {
+				continue
+			}
+			if value := readLine(fmt.Sprintf("值 [%s]: ", MaskEnvValue(name, vars[name]))); value != "" {
+				vars[name] = value
+			}
+
+		case "d", "delete":
+			name := readName(sortedKeys(vars), false)
+			if name == "" {
+				continue
+			}
+			delete(vars, name)
+			fmt.Printf("已删除 %s\n", name)
+
+		default:
+			fmt.Printf("未知操作: %s\n", action)
+		}
+	}
+}
+
+// printEnvVars 按名称顺序显示环境变量，token 与代理密码被遮蔽
+func printEnvVars(vars map[string]string) {
+	if len(vars) == 0 {
+		fmt.Println("自定义环境变量: （无）")
+		return
+	}
+	fmt.Println("自定义环境变量:")
+	for _, k := range sortedKeys(vars) {
+		fmt.Printf("  %s=%s\n", k, MaskEnvValue(k, vars[k]))
+	}
+}
+
+// printEnvCandidates 显示候选变量及其说明
+func printEnvCandidates(names []string) {
+	if len(names) == 0 {
+		fmt.Println("  （没有候选）")
+		return
+	}
+	for _, name := range names {
+		if desc := envVarDesc(name); desc != "" {
+			fmt.Printf("  %-42s %s\n", name, desc)
+		} else {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+}
+
+// sortedKeys 返回按名称排序的键
+func sortedKeys(vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
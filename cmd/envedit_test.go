@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+func TestCompleteEnvName(t *testing.T) {
+	candidates := []string{"API_TIMEOUT_MS", "MCP_TIMEOUT", "MCP_TOOL_TIMEOUT", "my_var"}
+
+	tests := []struct {
+		input   string
+		want    string
+		matches []string
+	}{
+		{"api", "API_TIMEOUT_MS", nil},
+		{"MCP_TIMEOUT", "MCP_TIMEOUT", nil},
+		{"mcp", "", []string{"MCP_TIMEOUT", "MCP_TOOL_TIMEOUT"}},
+		{"MY_VAR", "my_var", nil},
+		{"UNKNOWN", "", nil},
+	}
+	for _, tt := range tests {
+		got, matches := CompleteEnvName(tt.input, candidates)
+		if got != tt.want || !reflect.DeepEqual(matches, tt.matches) {
+			t.Errorf("CompleteEnvName(%q) = %q, %v, want %q, %v", tt.input, got, matches, tt.want, tt.matches)
+		}
+	}
+}
+
+func TestEditEnvVars(t *testing.T) {
+	vars := map[string]string{"OLD": "1", "KEEP": "k"}
+	reader := &mockReader{inputs: []string{
+		"a", "api_time", "600000", // 前缀补全后添加
+		"a", "MCP", "MCP_TOOL", "5000", // 多个候选时重新输入
+		"a", "1BAD", "MY_VAR", "x", // 无效名称后重新输入
+		"e", "my", "y", // 修改
+		"d", "OLD", // 删除
+		"d", "MISSING", "", // 不存在的变量
+		"",
+	}}
+
+	EditEnvVars(vars, reader)
+
+	want := map[string]string{
+		"KEEP":             "k",
+		"API_TIMEOUT_MS":   "600000",
+		"MCP_TOOL_TIMEOUT": "5000",
+		"MY_VAR":           "y",
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("vars = %v, want %v", vars, want)
+	}
+}
+
+func TestEditEnvVarsRejectsFieldKeys(t *testing.T) {
+	vars := map[string]string{}
+	reader := &mockReader{inputs: []string{
+		"a", "ANTHROPIC_AUTH_TOKEN",
+		"a", "http_proxy",
+		"a", "NAME",
+		"a", "_NOTE",
+		"a", "CUSTOM", "1",
+		"",
+	}}
+
+	EditEnvVars(vars, reader)
+
+	if want := map[string]string{"CUSTOM": "1"}; !reflect.DeepEqual(vars, want) {
+		t.Errorf("vars = %v, want %v", vars, want)
+	}
+}
+
+func TestCreateProfileInteractiveWithReader(t *testing.T) {
+	profilesDir := t.TempDir()
+	reader := &mockReader{inputs: []string{
+		"Work", "sk-work", "", "", "claude-sonnet-4-5",
+		"y", "a", "ANTHROPIC_DEFAULT_HAIKU", "claude-haiku-4-5", "",
+	}}
+
+	if err := CreateProfileInteractiveWithReader(profilesDir, "work", reader); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := profile.LoadProfile(profilesDir, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Work" || p.AuthToken != "sk-work" || p.Model != "claude-sonnet-4-5" {
+		t.Errorf("unexpected profile: %+v", p)
+	}
+	if got := p.EnvVars["ANTHROPIC_DEFAULT_HAIKU_MODEL"]; got != "claude-haiku-4-5" {
+		t.Errorf("ANTHROPIC_DEFAULT_HAIKU_MODEL = %q", got)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+// nowFunc 返回当前时间，可被测试 mock
+var nowFunc = time.Now
+
+// expiryWarnDays 返回 config.json 中的提醒天数，读取失败时使用默认值
+func expiryWarnDays() int {
+	cfg, err := config.LoadSwitcherConfig()
+	if err != nil {
+		return config.DefaultExpiryWarnDays
+	}
+	return *cfg.ExpiryWarnDays
+}
+
+// expiryLabel 返回配置的剩余天数说明，未设置过期时间时返回空字符串
+func expiryLabel(p *profile.Profile, now time.Time) string {
+	days, ok := p.DaysUntilExpiry(now)
+	switch {
+	case !ok:
+		return ""
+	case days > 0:
+		return fmt.Sprintf("剩余 %d 天", days)
+	case days == 0:
+		return "今天过期"
+	default:
+		return fmt.Sprintf("已过期 %d 天", -days)
+	}
+}
+
+// isExpiring 判断配置是否在 warnDays 天内过期（包括已过期）
+func isExpiring(p *profile.Profile, now time.Time, warnDays int) bool {
+	days, ok := p.DaysUntilExpiry(now)
+	return ok && days <= warnDays
+}
+
+// warnExpiry 配置的 token 即将过期或已过期时输出提醒，提醒天数由 config.json 的 expiry-warn-days 决定
+func warnExpiry(w io.Writer, name string, p *profile.Profile) {
+	warnDays := expiryWarnDays()
+	now := nowFunc()
+	if warnDays < 0 || !isExpiring(p, now, warnDays) {
+		return
+	}
+
+	days, _ := p.DaysUntilExpiry(now)
+	date := profile.FormatExpiry(p.ExpiresAt)
+	switch {
+	case days > 0:
+		fmt.Fprintf(w, "⚠  配置 %s 的 token 将在 %d 天后过期（%s）\n", name, days, date)
+	case days == 0:
+		fmt.Fprintf(w, "⚠  配置 %s 的 token 今天过期（%s）\n", name, date)
+	default:
+		fmt.Fprintf(w, "⚠  配置 %s 的 token 已于 %s 过期\n", name, date)
+	}
+	if p.Note != "" {
+		fmt.Fprintf(w, "   备注: %s\n", p.Note)
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+// stubNow 固定当前时间
+func stubNow(t *testing.T, now time.Time) {
+	t.Helper()
+	original := nowFunc
+	nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { nowFunc = original })
+}
+
+func TestExpiryLabel(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		expires time.Time
+		want    string
+	}{
+		{time.Time{}, ""},
+		{time.Date(2026, 3, 6, 0, 0, 0, 0, time.Local), "剩余 5 天"},
+		{time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local), "今天过期"},
+		{time.Date(2026, 2, 27, 0, 0, 0, 0, time.Local), "已过期 2 天"},
+	}
+	for _, tt := range tests {
+		if got := expiryLabel(&profile.Profile{ExpiresAt: tt.expires}, now); got != tt.want {
+			t.Errorf("expiryLabel(%v) = %q, want %q", tt.expires, got, tt.want)
+		}
+	}
+}
+
+func TestWarnExpiry(t *testing.T) {
+	setupTestHome(t)
+	stubNow(t, time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local))
+
+	soon := &profile.Profile{ExpiresAt: time.Date(2026, 3, 4, 0, 0, 0, 0, time.Local), Note: "找管理员续费"}
+	later := &profile.Profile{ExpiresAt: time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local)}
+	expired := &profile.Profile{ExpiresAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)}
+
+	var buf bytes.Buffer
+	warnExpiry(&buf, "relay", soon)
+	if got := buf.String(); !strings.Contains(got, "3 天后过期（2026-03-04）") || !strings.Contains(got, "找管理员续费") {
+		t.Errorf("warning = %q", got)
+	}
+
+	buf.Reset()
+	warnExpiry(&buf, "relay", later)
+	warnExpiry(&buf, "plain", &profile.Profile{})
+	if buf.Len() != 0 {
+		t.Errorf("no warning expected, got %q", buf.String())
+	}
+
+	buf.Reset()
+	warnExpiry(&buf, "old", expired)
+	if !strings.Contains(buf.String(), "已于 2026-02-01 过期") {
+		t.Errorf("warning = %q", buf.String())
+	}
+
+	// 提醒天数来自 config.json，off 时不提醒
+	cfg, err := config.LoadSwitcherConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := -1
+	cfg.ExpiryWarnDays = &off
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	warnExpiry(&buf, "old", expired)
+	if buf.Len() != 0 {
+		t.Errorf("warnings should be disabled, got %q", buf.String())
+	}
+}
+
+func TestMenuShowsExpiry(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	stubNow(t, now)
+	writeTestProfile(t, profilesDir, "relay", &profile.Profile{
+		Name:      "relay",
+		ExpiresAt: time.Date(2026, 3, 4, 0, 0, 0, 0, time.Local),
+		Note:      "月付",
+	})
+
+	entries, err := loadMenuEntries(profilesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &menuModel{entries: entries, now: now}
+	if lines := m.render(60, 10); !strings.Contains(strings.Join(lines, "\n"), "relay  [剩余 3 天]") {
+		t.Errorf("menu should show days remaining:\n%s", strings.Join(lines, "\n"))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProfileDetails(&buf, profilesDir, "relay"); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.Contains(got, "过期时间: 2026-03-04（剩余 3 天）") || !strings.Contains(got, "备注: 月付") {
+		t.Errorf("details = %q", got)
+	}
+}
+
+func TestExecuteListExpiring(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	stubNow(t, time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local))
+	writeTestProfile(t, profilesDir, "soon", &profile.Profile{Name: "soon", ExpiresAt: time.Date(2026, 3, 4, 0, 0, 0, 0, time.Local)})
+	writeTestProfile(t, profilesDir, "plain", &profile.Profile{Name: "plain"})
+
+	if err := Execute([]string{"list", "--expiring"}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Execute([]string{"--list"}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Execute([]string{"config", "set", "expiry-warn-days", "soon"}, BuildInfo{}); err == nil {
+		t.Error("invalid expiry-warn-days should be rejected")
+	}
+	if err := Execute([]string{"config", "set", "expiry-warn-days", "30"}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := expiryWarnDays(); got != 30 {
+		t.Errorf("expiryWarnDays() = %d, want 30", got)
+	}
+}
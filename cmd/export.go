@@ -27,7 +27,10 @@ func ExportProfileToJSON(p *profile.Profile) ([]byte, error) {
 		"base_url":     p.BaseURL,
 		"http_proxy":   p.HTTPProxy,
 		"https_proxy":  p.HTTPSProxy,
+		"no_proxy":     p.NoProxy,
 		"model":        p.Model,
+		"protocol":     p.Protocol,
+		"allowed_countries": strings.Join(p.AllowedCountries, ","),
 		"custom_vars":  p.EnvVars,
 	}
 
@@ -51,9 +54,18 @@ func ExportProfileToYAML(p *profile.Profile) ([]byte, error) {
 	if p.HTTPSProxy != "" {
 		sb.WriteString("https_proxy: " + p.HTTPSProxy + "\n")
 	}
+	if p.NoProxy != "" {
+		sb.WriteString("no_proxy: " + p.NoProxy + "\n")
+	}
 	if p.Model != "" {
 		sb.WriteString("model: " + p.Model + "\n")
 	}
+	if p.Protocol != "" {
+		sb.WriteString("protocol: " + p.Protocol + "\n")
+	}
+	if len(p.AllowedCountries) > 0 {
+		sb.WriteString("allowed_countries: " + strings.Join(p.AllowedCountries, ",") + "\n")
+	}
 	if len(p.EnvVars) > 0 {
 		sb.WriteString("custom_vars:\n")
 		for k, v := range p.EnvVars {
@@ -65,28 +77,36 @@ func ExportProfileToYAML(p *profile.Profile) ([]byte, error) {
 }
 
 // ExportProfileToShell 将配置导出为 Shell 变量格式
+// 输出用于 source，因此密钥引用会先解析为实际值，所有值使用单引号转义
 func ExportProfileToShell(p *profile.Profile) ([]byte, error) {
+	p, err := profile.Resolve(p)
+	if err != nil {
+		return nil, err
+	}
+
 	var sb strings.Builder
 
 	sb.WriteString("# Claude Switcher Profile Export\n")
 	sb.WriteString("# Generated by claude-switcher\n\n")
 
-	sb.WriteString("export CLAUDE_SWITCHER_PROFILE=\"" + p.Name + "\"\n")
+	sb.WriteString("export CLAUDE_SWITCHER_PROFILE=" + shellQuote(p.Name) + "\n")
 	if p.AuthToken != "" {
-		sb.WriteString("export ANTHROPIC_AUTH_TOKEN=\"" + p.AuthToken + "\"\n")
+		sb.WriteString("export ANTHROPIC_AUTH_TOKEN=" + shellQuote(p.AuthToken) + "\n")
 	}
 	if p.BaseURL != "" {
-		sb.WriteString("export ANTHROPIC_BASE_URL=\"" + p.BaseURL + "\"\n")
+		sb.WriteString("export ANTHROPIC_BASE_URL=" + shellQuote(p.BaseURL) + "\n")
 	}
-	if p.HTTPProxy != "" {
-		sb.WriteString("export http_proxy=\"" + p.HTTPProxy + "\"\n")
-		sb.WriteString("export https_proxy=\"" + p.HTTPProxy + "\"\n")
+	proxyVars := ProxyEnvVars(p)
+	for _, k := range proxyEnvKeys {
+		if v, ok := proxyVars[k]; ok {
+			sb.WriteString("export " + k + "=" + shellQuote(v) + "\n")
+		}
 	}
 	if p.Model != "" {
-		sb.WriteString("export ANTHROPIC_MODEL=\"" + p.Model + "\"\n")
+		sb.WriteString("export ANTHROPIC_MODEL=" + shellQuote(p.Model) + "\n")
 	}
 	for k, v := range p.EnvVars {
-		sb.WriteString("export " + k + "=\"" + v + "\"\n")
+		sb.WriteString("export " + k + "=" + shellQuote(v) + "\n")
 	}
 
 	return []byte(sb.String()), nil
@@ -126,7 +146,10 @@ func ExportAllProfiles(profilesDir string) ([]byte, error) {
 			"base_url":    p.BaseURL,
 			"http_proxy":  p.HTTPProxy,
 			"https_proxy": p.HTTPSProxy,
+			"no_proxy":    p.NoProxy,
 			"model":       p.Model,
+			"protocol":    p.Protocol,
+			"allowed_countries": strings.Join(p.AllowedCountries, ","),
 			"custom_vars": p.EnvVars,
 		}
 		profiles = append(profiles, profileData)
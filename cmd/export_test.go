@@ -162,3 +162,27 @@ func TestExportAllProfiles(t *testing.T) {
 		t.Errorf("expected 3 profiles, got %d", len(profilesList))
 	}
 }
+
+func TestExportProfileToShellQuotesAndResolves(t *testing.T) {
+	t.Setenv("WORK_KEY", "sk-from-env")
+	p := &profile.Profile{
+		Name:      "work",
+		AuthToken: "${env:WORK_KEY}",
+		EnvVars:   map[string]string{"CUSTOM": `it's "$(rm -rf ~)"`},
+	}
+
+	data, err := ExportProfileToShell(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if !contains(got, "export ANTHROPIC_AUTH_TOKEN='sk-from-env'\n") {
+		t.Errorf("secret reference should be resolved:\n%s", got)
+	}
+	if !contains(got, `export CUSTOM='it'\''s "$(rm -rf ~)"'`+"\n") {
+		t.Errorf("value should be single-quoted:\n%s", got)
+	}
+	if p.AuthToken != "${env:WORK_KEY}" {
+		t.Error("ExportProfileToShell should not modify the profile")
+	}
+}
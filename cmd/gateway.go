@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/gateway"
+	"github.com/fiftyk/claude-switcher/internal/group"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/settings"
+	"github.com/fiftyk/claude-switcher/internal/vault"
+)
+
+// DefaultGatewayAddr 网关默认监听地址
+const DefaultGatewayAddr = "127.0.0.1:8787"
+
+// newGatewayCommand 启动本地切换网关
+func newGatewayCommand() *Command {
+	c := newCommand("gateway", "[--addr 127.0.0.1:8787]", "启动本地网关，转发请求到当前活动配置")
+	c.Long = `claude 通过 ANTHROPIC_BASE_URL 指向网关后，每个请求都会转发到当前活动配置的
+BaseURL，并使用该配置的 token 和代理。切换配置后下一个请求即生效，无需重启 claude:
+  claude-switcher gateway
+  ANTHROPIC_BASE_URL=http://127.0.0.1:8787 ANTHROPIC_AUTH_TOKEN=gateway claude
+  claude-switcher use --no-launch --isolated personal   # 在其他终端切换
+
+切换时使用 --isolated 可避免把配置的 ANTHROPIC_BASE_URL 同步到 settings.json
+而覆盖网关地址。`
+	addr := c.Flags.String("addr", DefaultGatewayAddr, "监听地址，只允许本机回环地址")
+	timeout := c.Flags.Duration("response-timeout", gateway.DefaultResponseTimeout, "配置组中等待上游响应头的时间，超时后改用下一个配置（0 表示不限制）")
+	c.Run = func(args []string) error {
+		if len(args) != 0 {
+			return c.usageError()
+		}
+		return RunGateway(*addr, *timeout)
+	}
+	return c
+}
+
+// RunGateway 在指定地址运行网关，直到收到中断信号
+// responseTimeout 为配置组中切换到下一个配置前等待响应头的时间
+func RunGateway(addr string, responseTimeout time.Duration) error {
+	if err := validateLoopbackAddr(addr); err != nil {
+		return err
+	}
+
+	// 加密存储需要在开始处理请求前解锁，避免多个请求同时提示输入口令
+	if err := unlockForGateway(config.GetProfilesDir()); err != nil {
+		return err
+	}
+
+	gw := gateway.New(activeUpstream)
+	gw.ResponseTimeout = responseTimeout
+	gw.Logf = func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, "[%s] %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %w", addr, err)
+	}
+
+	srv := &http.Server{Handler: gw, ReadHeaderTimeout: 30 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	baseURL := "http://" + ln.Addr().String()
+	fmt.Printf("网关已启动: %s\n", baseURL)
+	if name, _ := GetActiveProfile(); strings.TrimSpace(name) != "" {
+		fmt.Printf("当前活动配置: %s\n", strings.TrimSpace(name))
+	}
+	fmt.Printf("使用方法: ANTHROPIC_BASE_URL=%s ANTHROPIC_AUTH_TOKEN=gateway claude\n", baseURL)
+	warnSettingsBaseURL()
+	fmt.Println("按 Ctrl+C 停止")
+
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// unlockForGateway 加密存储已锁定时预先解锁，有 agent 时由 agent 提供密钥
+func unlockForGateway(profilesDir string) error {
+	v, err := vault.Load(profilesDir)
+	if errors.Is(err, vault.ErrNotInitialized) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !v.Locked {
+		return nil
+	}
+	_, err = vaultCipher(profilesDir)
+	return err
+}
+
+// activeUpstream 读取 active 文件并返回活动配置对应的上游
+// 每个请求都会重新读取，切换配置后立即生效；活动配置为配置组时按顺序返回所有成员
+func activeUpstream() ([]*gateway.Upstream, error) {
+	name, err := GetActiveProfile()
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("尚未选择配置，请先运行 'claude-switcher use --no-launch --isolated <配置名>'")
+	}
+
+	profilesDir := config.GetProfilesDir()
+	if !profileExists(profilesDir, name) && group.Exists(config.GetGroupsDir(), name) {
+		g, err := group.LoadGroup(config.GetGroupsDir(), name)
+		if err != nil {
+			return nil, err
+		}
+		var ups []*gateway.Upstream
+		for _, member := range g.Fallback {
+			p, err := loadResolvedProfile(profilesDir, member)
+			if err != nil {
+				continue
+			}
+			ups = append(ups, profileUpstream(member, p))
+		}
+		if len(ups) == 0 {
+			return nil, fmt.Errorf("配置组 '%s' 中没有可加载的配置", name)
+		}
+		return ups, nil
+	}
+
+	p, err := loadResolvedProfile(profilesDir, name)
+	if err != nil {
+		return nil, err
+	}
+	return []*gateway.Upstream{profileUpstream(name, p)}, nil
+}
+
+// profileAuthToken 返回以 Bearer 方式发送的 token，ANTHROPIC_API_KEY 需以 x-api-key 发送，不包括在内
+func profileAuthToken(p *profile.Profile) string {
+	if p.AuthToken != "" {
+		return p.AuthToken
+	}
+	return p.EnvVars["ANTHROPIC_AUTH_TOKEN"]
+}
+
+// profileUpstream 返回配置对应的上游
+func profileUpstream(name string, p *profile.Profile) *gateway.Upstream {
+	return &gateway.Upstream{
+		Profile:  name,
+		BaseURL:  p.BaseURL,
+		Token:    profileAuthToken(p),
+		APIKey:   p.EnvVars["ANTHROPIC_API_KEY"],
+		Proxy:    profileProxyConfig(p),
+		Protocol: p.Protocol,
+	}
+}
+
+// validateLoopbackAddr 检查监听地址是否为本机回环地址
+// 网关会替请求附加真实 token，不能暴露到网络上
+func validateLoopbackAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("监听地址格式不正确: %s", addr)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("网关只能监听本机回环地址: %s", addr)
+}
+
+// warnSettingsBaseURL 提示 settings.json 中的 ANTHROPIC_BASE_URL 会覆盖网关地址
+func warnSettingsBaseURL() {
+	s, err := settings.LoadSettings(GetSettingsFilePath())
+	if err != nil {
+		return
+	}
+	if v, ok := s.Env["ANTHROPIC_BASE_URL"]; ok {
+		fmt.Fprintf(os.Stderr, "⚠  settings.json 中设置了 ANTHROPIC_BASE_URL=%s，会覆盖网关地址\n", v)
+	}
+}
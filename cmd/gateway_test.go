@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+func TestActiveUpstream(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{
+		Name:      "work",
+		AuthToken: "sk-work",
+		BaseURL:   "https://api.example.com",
+		HTTPProxy: "http://127.0.0.1:7890",
+	})
+
+	if _, err := activeUpstream(); err == nil {
+		t.Error("expected error when no profile is active")
+	}
+
+	// 只切换活动配置，不写入 settings.json
+	if err := Execute([]string{"use", "--no-launch", "--isolated", "work"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if _, err := os.Stat(GetSettingsFilePath()); !os.IsNotExist(err) {
+		t.Errorf("settings.json should not be written, stat err = %v", err)
+	}
+
+	ups, err := activeUpstream()
+	if err != nil {
+		t.Fatalf("activeUpstream() error = %v", err)
+	}
+	if len(ups) != 1 {
+		t.Fatalf("activeUpstream() returned %d upstreams, want 1", len(ups))
+	}
+	up := ups[0]
+	if up.Profile != "work" || up.Token != "sk-work" || up.BaseURL != "https://api.example.com" || up.Proxy.HTTPProxy != "http://127.0.0.1:7890" {
+		t.Errorf("activeUpstream() = %+v", up)
+	}
+}
+
+func TestValidateLoopbackAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{"127.0.0.1:8787", false},
+		{"localhost:0", false},
+		{"[::1]:8787", false},
+		{"0.0.0.0:8787", true},
+		{":8787", true},
+		{"192.168.1.2:8787", true},
+		{"127.0.0.1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if err := validateLoopbackAddr(tt.addr); (err != nil) != tt.wantErr {
+				t.Errorf("validateLoopbackAddr(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProfileUpstreamCredentials(t *testing.T) {
+	up := profileUpstream("official", &profile.Profile{EnvVars: map[string]string{"ANTHROPIC_API_KEY": "sk-ant-api"}})
+	if up.Token != "" || up.APIKey != "sk-ant-api" {
+		t.Errorf("Token = %q, APIKey = %q, want the key sent as x-api-key", up.Token, up.APIKey)
+	}
+
+	up = profileUpstream("relay", &profile.Profile{AuthToken: "sk-relay"})
+	if up.Token != "sk-relay" || up.APIKey != "" {
+		t.Errorf("Token = %q, APIKey = %q", up.Token, up.APIKey)
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/group"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+// checkConnectivityFunc 用于检查配置是否可用，可被测试 mock
+var checkConnectivityFunc = CheckConnectivity
+
+// newGroupCommand 管理配置组
+func newGroupCommand() *Command {
+	c := newCommand("group", "[list] | show <组名> | set <组名> <配置...> | delete <组名>", "管理故障转移配置组")
+	c.Long = `配置组保存在 ~/.claude-switcher/groups/<组名>.conf:
+  NAME="主备"
+  FALLBACK="primary backup openrouter"
+
+使用配置组启动时按顺序检查连通性，选择第一个可用的配置:
+  claude-switcher use <组名>
+通过 gateway 使用时，上游返回 5xx/529 或连接失败、超时（见 gateway --response-timeout）
+会自动改用下一个配置。`
+	c.Run = func(args []string) error {
+		groupsDir := config.GetGroupsDir()
+
+		if len(args) == 0 || (len(args) == 1 && args[0] == "list") {
+			return PrintGroupList(groupsDir)
+		}
+
+		switch {
+		case args[0] == "show" && len(args) == 2:
+			g, err := group.LoadGroup(groupsDir, args[1])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("配置组: %s (%s)\n", args[1], g.Name)
+			for i, member := range g.Fallback {
+				fmt.Printf("  %d. %s\n", i+1, member)
+			}
+			return nil
+
+		case args[0] == "set" && len(args) >= 3:
+			name, members := args[1], args[2:]
+			if valid, _ := config.ValidateConfigName(name); !valid {
+				return fmt.Errorf("配置组名称格式不正确: %s", name)
+			}
+			profilesDir := config.GetProfilesDir()
+			if profileExists(profilesDir, name) {
+				return fmt.Errorf("已存在同名配置: %s", name)
+			}
+			for _, member := range members {
+				if !profileExists(profilesDir, member) {
+					return fmt.Errorf("配置不存在: %s", member)
+				}
+			}
+			if err := group.SaveGroup(groupsDir, name, &group.Group{Name: name, Fallback: members}); err != nil {
+				return err
+			}
+			fmt.Printf("✓ 已保存配置组: %s\n", name)
+			return nil
+
+		case args[0] == "delete" && len(args) == 2:
+			if err := group.DeleteGroup(groupsDir, args[1]); err != nil {
+				return err
+			}
+			fmt.Printf("✓ 已删除配置组: %s\n", args[1])
+			return nil
+		}
+
+		return c.usageError()
+	}
+	return c
+}
+
+// PrintGroupList 打印所有配置组
+func PrintGroupList(groupsDir string) error {
+	names, err := group.ListGroups(groupsDir)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Println("暂无配置组，使用 'claude-switcher group set <组名> <配置...>' 创建")
+		return nil
+	}
+
+	fmt.Println("配置组:")
+	for _, name := range names {
+		g, err := group.LoadGroup(groupsDir, name)
+		if err != nil {
+			continue
+		}
+		fmt.Printf("  %s - %v\n", name, g.Fallback)
+	}
+	return nil
+}
+
+// profileExists 判断配置文件是否存在
+func profileExists(profilesDir, name string) bool {
+	return profile.Exists(profilesDir, name)
+}
+
+// resolveLaunchTarget 解析要启动的配置
+// name 为配置组时选择其中第一个可用的配置，返回实际使用的配置名称
+func resolveLaunchTarget(profilesDir, name string) (string, *profile.Profile, error) {
+	groupsDir := config.GetGroupsDir()
+	if profileExists(profilesDir, name) || !group.Exists(groupsDir, name) {
+		p, err := loadResolvedProfile(profilesDir, name)
+		return name, p, err
+	}
+
+	g, err := group.LoadGroup(groupsDir, name)
+	if err != nil {
+		return "", nil, err
+	}
+	return SelectGroupMember(profilesDir, g)
+}
+
+// SelectGroupMember 按顺序检查配置组成员的连通性，返回第一个可用的配置
+func SelectGroupMember(profilesDir string, g *group.Group) (string, *profile.Profile, error) {
+	for _, member := range g.Fallback {
+		p, err := loadResolvedProfile(profilesDir, member)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", member, err)
+			continue
+		}
+
+		result := checkConnectivityFunc(p)
+		if !result.Reachable {
+			fmt.Fprintf(os.Stderr, "✗ %s: %s\n", member, result.Message)
+			continue
+		}
+		return member, p, nil
+	}
+	return "", nil, fmt.Errorf("配置组 '%s' 中没有可用的配置", g.Name)
+}
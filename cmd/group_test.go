@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/group"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+// mockConnectivity 使 down 中的配置不可达
+func mockConnectivity(t *testing.T, down ...string) {
+	t.Helper()
+	original := checkConnectivityFunc
+	checkConnectivityFunc = func(p *profile.Profile) *ConnectivityResult {
+		for _, name := range down {
+			if p.Name == name {
+				return &ConnectivityResult{Message: "连接失败"}
+			}
+		}
+		return &ConnectivityResult{Reachable: true}
+	}
+	t.Cleanup(func() { checkConnectivityFunc = original })
+}
+
+func TestExecuteUseGroupPicksFirstHealthy(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "primary", &profile.Profile{Name: "primary", AuthToken: "sk-primary"})
+	writeTestProfile(t, profilesDir, "backup", &profile.Profile{Name: "backup", AuthToken: "sk-backup"})
+	mockConnectivity(t, "primary")
+
+	if err := Execute([]string{"group", "set", "ha", "primary", "backup"}, BuildInfo{}); err != nil {
+		t.Fatalf("group set error = %v", err)
+	}
+
+	var gotEnv []string
+	originalRun := runClaudeFunc
+	runClaudeFunc = func(env []string, args ...string) error {
+		gotEnv = env
+		return nil
+	}
+	defer func() { runClaudeFunc = originalRun }()
+
+	if err := Execute([]string{"use", "--isolated", "ha"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	env := strings.Join(gotEnv, "\n")
+	if !strings.Contains(env, "ANTHROPIC_AUTH_TOKEN=sk-backup") {
+		t.Errorf("expected backup profile to be used")
+	}
+	if active, _ := GetActiveProfile(); active != "ha" {
+		t.Errorf("active = %q, want the group name", active)
+	}
+
+	// 活动配置为配置组时网关按顺序获得所有成员
+	ups, err := activeUpstream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ups) != 2 || ups[0].Profile != "primary" || ups[1].Profile != "backup" {
+		t.Errorf("activeUpstream() = %+v", ups)
+	}
+}
+
+func TestSelectGroupMemberAllDown(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "a", &profile.Profile{Name: "a"})
+	mockConnectivity(t, "a")
+
+	g := &group.Group{Name: "ha", Fallback: []string{"missing", "a"}}
+	if _, _, err := SelectGroupMember(profilesDir, g); err == nil {
+		t.Error("expected error when no member is healthy")
+	}
+}
+
+func TestExecuteGroupSetErrors(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{Name: "work"})
+
+	if err := Execute([]string{"group", "set", "ha", "work", "missing"}, BuildInfo{}); err == nil {
+		t.Error("expected error for unknown member")
+	}
+	if err := Execute([]string{"group", "set", "work", "work"}, BuildInfo{}); err == nil {
+		t.Error("expected error when group name clashes with a profile")
+	}
+	if group.Exists(config.GetGroupsDir(), "ha") {
+		t.Error("group should not be saved on error")
+	}
+}
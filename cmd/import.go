@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/fiftyk/claude-switcher/internal/config"
 	"github.com/fiftyk/claude-switcher/internal/profile"
 	"github.com/fiftyk/claude-switcher/internal/settings"
 	"gopkg.in/yaml.v3"
@@ -26,7 +28,10 @@ type ImportData struct {
 	BaseURL     string            `json:"base_url,omitempty" yaml:"base_url"`
 	HTTPProxy   string            `json:"http_proxy,omitempty" yaml:"http_proxy"`
 	HTTPSProxy  string            `json:"https_proxy,omitempty" yaml:"https_proxy"`
+	NoProxy     string            `json:"no_proxy,omitempty" yaml:"no_proxy"`
 	Model       string            `json:"model,omitempty" yaml:"model"`
+	Protocol    string            `json:"protocol,omitempty" yaml:"protocol"`
+	AllowedCountries string       `json:"allowed_countries,omitempty" yaml:"allowed_countries"`
 	CustomVars  map[string]string `json:"custom_vars,omitempty" yaml:"custom_vars"`
 }
 
@@ -43,7 +48,10 @@ func ImportProfileFromJSON(jsonData, profileName string) (*profile.Profile, erro
 		BaseURL:    data.BaseURL,
 		HTTPProxy:  data.HTTPProxy,
 		HTTPSProxy: data.HTTPSProxy,
+		NoProxy:    data.NoProxy,
 		Model:      data.Model,
+		Protocol:   data.Protocol,
+		AllowedCountries: profile.ParseCountryList(data.AllowedCountries),
 		EnvVars:    make(map[string]string),
 	}
 
@@ -73,7 +81,10 @@ func ImportProfileFromYAML(yamlData, profileName string) (*profile.Profile, erro
 		BaseURL:    data.BaseURL,
 		HTTPProxy:  data.HTTPProxy,
 		HTTPSProxy: data.HTTPSProxy,
+		NoProxy:    data.NoProxy,
 		Model:      data.Model,
+		Protocol:   data.Protocol,
+		AllowedCountries: profile.ParseCountryList(data.AllowedCountries),
 		EnvVars:    make(map[string]string),
 	}
 
@@ -97,21 +108,59 @@ func SaveProfileToFile(profilesDir string, p *profile.Profile, fileName string)
 		fileName = p.Name
 	}
 
-	filePath := filepath.Join(profilesDir, fileName+".conf")
-
 	// 检查是否已存在
-	if _, err := os.Stat(filePath); err == nil {
+	if profile.Exists(profilesDir, fileName) {
 		return fmt.Errorf("配置 '%s' 已存在", fileName)
 	}
 
-	content := formatProfile(p)
-	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+	if err := profile.WriteFile(profilesDir, fileName, profile.Format(p)); err != nil {
 		return fmt.Errorf("无法保存配置文件: %w", err)
 	}
 
 	return nil
 }
 
+// ImportFile 从 JSON/YAML 文件导入配置，返回保存的配置名称
+// format 为空时根据文件扩展名判断，profileName 为空时使用文件名（不含扩展名）
+func ImportFile(profilesDir, file string, format ImportFormat, profileName string) (string, error) {
+	if format == "" {
+		format = ImportFormat(strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), "."))
+	}
+	if format == "yml" {
+		format = ImportFormatYAML
+	}
+
+	if profileName == "" {
+		profileName = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	if valid, _ := config.ValidateConfigName(profileName); !valid {
+		return "", fmt.Errorf("配置名称格式不正确: %s（可使用 --name 指定）", profileName)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("无法读取文件: %w", err)
+	}
+
+	var p *profile.Profile
+	switch format {
+	case ImportFormatJSON:
+		p, err = ImportProfileFromJSON(string(data), profileName)
+	case ImportFormatYAML:
+		p, err = ImportProfileFromYAML(string(data), profileName)
+	default:
+		return "", fmt.Errorf("不支持的导入格式: %s", format)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := SaveProfileToFile(profilesDir, p, profileName); err != nil {
+		return "", err
+	}
+	return profileName, nil
+}
+
 // ImportFromSettings 从 settings.json 导入配置
 func ImportFromSettings(settingsPath, profileName string) (*profile.Profile, error) {
 	// 加载 settings.json
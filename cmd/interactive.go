@@ -1,13 +1,13 @@
 package cmd
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/fiftyk/claude-switcher/internal/config"
 	"github.com/fiftyk/claude-switcher/internal/profile"
 )
 
@@ -17,9 +17,15 @@ var ErrQuit = errors.New("user quit")
 // runClaudeFunc 用于运行 claude CLI，可被测试 mock
 var runClaudeFunc = defaultRunClaude
 
+// menuClaudeArgs 从菜单启动 claude 时透传的参数，由 RunInteractiveMenu 设置
+var menuClaudeArgs []string
+
+// menuReader 菜单操作中读取用户输入，可被测试替换
+var menuReader ReaderProvider = StdioReader{}
+
 // defaultRunClaude 默认的 RunClaude 实现
-func defaultRunClaude() error {
-	return RunClaude()
+func defaultRunClaude(env []string, args ...string) error {
+	return RunClaudeWithEnv(env, args...)
 }
 
 // syncToSettingsFunc 用于同步到 settings.json，可被测试 mock
@@ -55,23 +61,33 @@ func HandleMenuAction(profilesDir string, action MenuAction, name string, handle
 		return ErrQuit
 
 	case ActionRun:
-		// 运行配置（会自动同步到 settings.json）
-		p, err := profile.LoadProfile(profilesDir, name)
+		// 运行配置（按启动模式同步到 settings.json 或注入环境变量）
+		p, err := loadResolvedProfile(profilesDir, name)
 		if err != nil {
 			return fmt.Errorf("加载配置失败: %w", err)
 		}
 
-		// 同步到 settings.json
-		if err := syncToSettingsFunc(name, p); err != nil {
-			return fmt.Errorf("同步到 settings.json 失败: %w", err)
+		mode, err := resolveLaunchMode("")
+		if err != nil {
+			return err
+		}
+		warnExpiry(os.Stderr, name, p)
+		if !checkExitCountry(p) {
+			fmt.Println("已取消启动")
+			return nil
 		}
+		env, stop, err := launchEnv(name, p, mode)
+		if err != nil {
+			return err
+		}
+		defer stop()
 
 		if err := handler.SetActiveProfile(name); err != nil {
 			return fmt.Errorf("设置活动配置失败: %w", err)
 		}
 
 		fmt.Printf("使用配置: %s\n", p.Name)
-		return runClaudeFunc()
+		return runClaudeFunc(env, menuClaudeArgs...)
 
 	case ActionCreate:
 		// 创建新配置
@@ -90,14 +106,79 @@ func HandleMenuAction(profilesDir string, action MenuAction, name string, handle
 		if err := ShowProfileDetails(profilesDir, name); err != nil {
 			return err
 		}
-		fmt.Print("\n按回车键继续...")
-		_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+		waitForEnter()
 		return nil
 
 	case ActionExport:
 		// 导出配置
 		return exportProfile(profilesDir, name)
 
+	case ActionRename:
+		// 重命名配置
+		newName, err := promptNewProfileName(profilesDir, "新配置名称")
+		if err != nil {
+			return err
+		}
+		if err := profile.RenameProfile(profilesDir, name, newName); err != nil {
+			return err
+		}
+		fmt.Printf("✓ 已重命名: %s -> %s\n", name, newName)
+		return nil
+
+	case ActionCopy:
+		// 复制配置
+		newName, err := promptNewProfileName(profilesDir, "目标配置名称")
+		if err != nil {
+			return err
+		}
+		if err := profile.CopyProfile(profilesDir, name, newName); err != nil {
+			return err
+		}
+		fmt.Printf("✓ 已复制: %s -> %s\n", name, newName)
+		return nil
+
+	case ActionDiff:
+		// 与另一个配置比较
+		other, err := promptOtherProfile(profilesDir, name)
+		if err != nil {
+			return err
+		}
+		if err := PrintDiff(profilesDir, name, other); err != nil {
+			return err
+		}
+		waitForEnter()
+		return nil
+
+	case ActionValidate:
+		// 验证配置并测试连通性
+		if err := PrintValidationReport(profilesDir, name, ValidateOptions{}); err != nil {
+			return err
+		}
+		waitForEnter()
+		return nil
+
+	case ActionImport:
+		// 从 JSON/YAML 文件导入配置
+		return importProfileInteractive(profilesDir)
+
+	case ActionSync:
+		// 只同步到 settings.json，不启动 claude
+		p, err := loadResolvedProfile(profilesDir, name)
+		if err != nil {
+			return fmt.Errorf("加载配置失败: %w", err)
+		}
+		if p.Protocol == profile.ProtocolOpenAI {
+			fmt.Fprintln(os.Stderr, "⚠  OpenAI 协议的配置需要通过 claude-switcher 启动 claude，或配合 'claude-switcher gateway' 使用")
+		}
+		if _, err := prepareLaunch(name, p, config.LaunchModeSettings); err != nil {
+			return err
+		}
+		if err := handler.SetActiveProfile(name); err != nil {
+			return fmt.Errorf("设置活动配置失败: %w", err)
+		}
+		fmt.Printf("✓ 已切换到配置: %s\n", name)
+		return nil
+
 	case ActionNone:
 		// 无操作，继续
 		return nil
@@ -107,6 +188,88 @@ func HandleMenuAction(profilesDir string, action MenuAction, name string, handle
 	}
 }
 
+// readMenuLine 显示提示并读取一行输入
+func readMenuLine(prompt string) string {
+	fmt.Print(prompt)
+	input, _ := menuReader.ReadString('\n')
+	return strings.TrimSpace(input)
+}
+
+// waitForEnter 等待用户按回车键
+func waitForEnter() {
+	readMenuLine("\n按回车键继续...")
+}
+
+// promptNewProfileName 提示输入一个尚不存在的配置名称
+func promptNewProfileName(profilesDir, label string) (string, error) {
+	name := readMenuLine("\n请输入" + label + ": ")
+	if name == "" {
+		return "", fmt.Errorf("名称不能为空")
+	}
+	if valid, _ := config.ValidateConfigName(name); !valid {
+		return "", fmt.Errorf("配置名称格式不正确: %s", name)
+	}
+	if profile.Exists(profilesDir, name) {
+		return "", fmt.Errorf("配置 '%s' 已存在", name)
+	}
+	return name, nil
+}
+
+// promptOtherProfile 提示选择 name 以外的一个配置，可输入编号或名称
+func promptOtherProfile(profilesDir, name string) (string, error) {
+	names, err := profile.ListProfiles(profilesDir)
+	if err != nil {
+		return "", err
+	}
+	var others []string
+	for _, n := range names {
+		if n != name {
+			others = append(others, n)
+		}
+	}
+	if len(others) == 0 {
+		return "", fmt.Errorf("没有其他配置可比较")
+	}
+
+	fmt.Printf("\n选择与 %s 比较的配置:\n", name)
+	for i, n := range others {
+		fmt.Printf("  %d. %s\n", i+1, n)
+	}
+	input := readMenuLine("请输入编号或名称: ")
+
+	var idx int
+	if _, err := fmt.Sscanf(input, "%d", &idx); err == nil && idx >= 1 && idx <= len(others) {
+		return others[idx-1], nil
+	}
+	for _, n := range others {
+		if n == input {
+			return n, nil
+		}
+	}
+	return "", fmt.Errorf("无效选择")
+}
+
+// importProfileInteractive 交互式导入配置
+func importProfileInteractive(profilesDir string) error {
+	file := readMenuLine("\n请输入要导入的文件路径（.json/.yaml/.yml）: ")
+	if file == "" {
+		return fmt.Errorf("文件路径不能为空")
+	}
+	if strings.HasPrefix(file, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			file = filepath.Join(home, file[2:])
+		}
+	}
+	name := readMenuLine("配置名称（直接回车使用文件名）: ")
+
+	imported, err := ImportFile(profilesDir, file, "", name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ 已导入配置: %s\n", imported)
+	return nil
+}
+
 // exportProfile 导出配置（内部函数，便于测试）
 func exportProfile(profilesDir, name string) error {
 	p, err := profile.LoadProfile(profilesDir, name)
@@ -120,8 +283,7 @@ func exportProfile(profilesDir, name string) error {
 	fmt.Println("  3. Shell")
 
 	fmt.Print("请选择: ")
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
+	input, _ := stdinReader.ReadString('\n')
 	input = strings.TrimSpace(input)
 
 	var format ExportFormat
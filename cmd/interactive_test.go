@@ -35,7 +35,7 @@ func (m *mockMenuHandler) SetActiveProfile(name string) error {
 // TestHandleMenuAction_Quit 测试退出操作
 func TestHandleMenuAction_Quit(t *testing.T) {
 	// Mock RunClaude 和 SyncToSettings
-	runClaudeFunc = func() error { return nil }
+	runClaudeFunc = func(env []string, args ...string) error { return nil }
 	syncToSettingsFunc = func(profileName string, p *profile.Profile) error { return nil }
 	defer func() {
 		runClaudeFunc = defaultRunClaude
@@ -57,7 +57,7 @@ func TestHandleMenuAction_Quit(t *testing.T) {
 func TestHandleMenuAction_Run(t *testing.T) {
 	// Mock RunClaude 和 SyncToSettings
 	syncCalled := false
-	runClaudeFunc = func() error { return nil }
+	runClaudeFunc = func(env []string, args ...string) error { return nil }
 	syncToSettingsFunc = func(profileName string, p *profile.Profile) error { syncCalled = true; return nil }
 	defer func() {
 		runClaudeFunc = defaultRunClaude
@@ -74,7 +74,7 @@ func TestHandleMenuAction_Run(t *testing.T) {
 		HTTPProxy:  "http://127.0.0.1:7890",
 		HTTPSProxy: "http://127.0.0.1:7890",
 	}
-	content := formatProfile(p)
+	content := string(profile.Format(p))
 	if err := os.WriteFile(filepath.Join(profilesDir, "test.conf"), []byte(content), 0600); err != nil {
 		t.Fatal(err)
 	}
@@ -105,7 +105,7 @@ func TestHandleMenuAction_Edit(t *testing.T) {
 		Name:       "test",
 		AuthToken:  "sk-test",
 	}
-	content := formatProfile(p)
+	content := string(profile.Format(p))
 	if err := os.WriteFile(filepath.Join(profilesDir, "test.conf"), []byte(content), 0600); err != nil {
 		t.Fatal(err)
 	}
@@ -130,7 +130,7 @@ func TestHandleMenuAction_Delete(t *testing.T) {
 		Name:       "test",
 		AuthToken:  "sk-test",
 	}
-	content := formatProfile(p)
+	content := string(profile.Format(p))
 	if err := os.WriteFile(filepath.Join(profilesDir, "test.conf"), []byte(content), 0600); err != nil {
 		t.Fatal(err)
 	}
@@ -164,7 +164,7 @@ func TestHandleMenuAction_ShowDetails(t *testing.T) {
 		Model:      "claude-3-5-sonnet",
 		EnvVars:    map[string]string{"TEST": "value"},
 	}
-	content := formatProfile(p)
+	content := string(profile.Format(p))
 	if err := os.WriteFile(filepath.Join(profilesDir, "test.conf"), []byte(content), 0600); err != nil {
 		t.Fatal(err)
 	}
@@ -186,7 +186,7 @@ func TestHandleMenuAction_Export(t *testing.T) {
 		AuthToken:  "sk-test",
 		BaseURL:    "https://api.example.com",
 	}
-	content := formatProfile(p)
+	content := string(profile.Format(p))
 	if err := os.WriteFile(filepath.Join(profilesDir, "test.conf"), []byte(content), 0600); err != nil {
 		t.Fatal(err)
 	}
@@ -235,7 +235,7 @@ func TestHandleMenuAction_SetActiveProfileError(t *testing.T) {
 		Name:      "test",
 		AuthToken: "sk-test",
 	}
-	content := formatProfile(p)
+	content := string(profile.Format(p))
 	if err := os.WriteFile(filepath.Join(profilesDir, "test.conf"), []byte(content), 0600); err != nil {
 		t.Fatal(err)
 	}
@@ -251,3 +251,110 @@ func TestHandleMenuAction_SetActiveProfileError(t *testing.T) {
 		t.Error("expected error when SetActiveProfile fails")
 	}
 }
+
+// setMenuInput 替换菜单操作读取的输入
+func setMenuInput(t *testing.T, inputs ...string) {
+	t.Helper()
+	orig := menuReader
+	menuReader = &mockReader{inputs: inputs}
+	t.Cleanup(func() { menuReader = orig })
+}
+
+func TestHandleMenuAction_RenameAndCopy(t *testing.T) {
+	profilesDir := t.TempDir()
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{Name: "work", AuthToken: "sk-work"})
+	handler := &mockMenuHandler{}
+
+	setMenuInput(t, "office")
+	if err := HandleMenuAction(profilesDir, ActionRename, "work", handler); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := profile.LoadProfile(profilesDir, "office"); err != nil {
+		t.Errorf("renamed profile not found: %v", err)
+	}
+
+	setMenuInput(t, "office-copy")
+	if err := HandleMenuAction(profilesDir, ActionCopy, "office", handler); err != nil {
+		t.Fatal(err)
+	}
+	p, err := profile.LoadProfile(profilesDir, "office-copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AuthToken != "sk-work" {
+		t.Errorf("copied AuthToken = %q", p.AuthToken)
+	}
+
+	// 目标已存在或名称无效时报错
+	setMenuInput(t, "office")
+	if err := HandleMenuAction(profilesDir, ActionCopy, "office-copy", handler); err == nil {
+		t.Error("expected error when target exists")
+	}
+	setMenuInput(t, "bad/name")
+	if err := HandleMenuAction(profilesDir, ActionRename, "office", handler); err == nil {
+		t.Error("expected error for invalid name")
+	}
+}
+
+func TestHandleMenuAction_Diff(t *testing.T) {
+	profilesDir := t.TempDir()
+	writeTestProfile(t, profilesDir, "a", &profile.Profile{Name: "a", Model: "m1"})
+	writeTestProfile(t, profilesDir, "b", &profile.Profile{Name: "b", Model: "m2"})
+
+	setMenuInput(t, "1", "")
+	if err := HandleMenuAction(profilesDir, ActionDiff, "a", &mockMenuHandler{}); err != nil {
+		t.Fatal(err)
+	}
+
+	setMenuInput(t, "missing")
+	if err := HandleMenuAction(profilesDir, ActionDiff, "a", &mockMenuHandler{}); err == nil {
+		t.Error("expected error for invalid selection")
+	}
+}
+
+func TestHandleMenuAction_Import(t *testing.T) {
+	profilesDir := t.TempDir()
+	file := filepath.Join(t.TempDir(), "team.json")
+	if err := os.WriteFile(file, []byte(`{"name":"Team","auth_token":"sk-team"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	setMenuInput(t, file, "")
+	if err := HandleMenuAction(profilesDir, ActionImport, "", &mockMenuHandler{}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := profile.LoadProfile(profilesDir, "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AuthToken != "sk-team" {
+		t.Errorf("imported AuthToken = %q", p.AuthToken)
+	}
+}
+
+func TestHandleMenuAction_Sync(t *testing.T) {
+	var synced string
+	runClaudeFunc = func(env []string, args ...string) error {
+		t.Error("sync should not launch claude")
+		return nil
+	}
+	syncToSettingsFunc = func(profileName string, p *profile.Profile) error { synced = profileName; return nil }
+	defer func() {
+		runClaudeFunc = defaultRunClaude
+		syncToSettingsFunc = defaultSyncToSettings
+	}()
+
+	profilesDir := t.TempDir()
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{Name: "work"})
+	handler := &mockMenuHandler{}
+
+	if err := HandleMenuAction(profilesDir, ActionSync, "work", handler); err != nil {
+		t.Fatal(err)
+	}
+	if synced != "work" {
+		t.Errorf("synced %q, want work", synced)
+	}
+	if !handler.setActiveCalled {
+		t.Error("expected SetActiveProfile to be called")
+	}
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/gateway"
+	"github.com/fiftyk/claude-switcher/internal/geoip"
+	"github.com/fiftyk/claude-switcher/internal/home"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/settings"
+)
+
+// resolveLaunchMode 确定启动模式，命令行参数优先于 config.json
+func resolveLaunchMode(flagMode string) (string, error) {
+	if flagMode != "" {
+		if !config.ValidateLaunchMode(flagMode) {
+			return "", fmt.Errorf("未知的启动模式: %s（可选: %s）", flagMode, strings.Join(config.LaunchModes(), ", "))
+		}
+		return flagMode, nil
+	}
+
+	cfg, err := config.LoadSwitcherConfig()
+	if err != nil {
+		return "", err
+	}
+	if !config.ValidateLaunchMode(cfg.LaunchMode) {
+		return "", fmt.Errorf("config.json 中的启动模式无效: %s", cfg.LaunchMode)
+	}
+	return cfg.LaunchMode, nil
+}
+
+// loadResolvedProfile 加载配置并解析其中的密钥引用，用于启动、同步和连通性检查
+func loadResolvedProfile(profilesDir, name string) (*profile.Profile, error) {
+	p, err := profile.LoadProfile(profilesDir, name)
+	if err != nil {
+		return nil, err
+	}
+	return profile.Resolve(p)
+}
+
+// prepareLaunch 按启动模式准备配置，返回 claude 子进程使用的环境变量
+//
+//	settings: 同步到 ~/.claude/settings.json，子进程继承当前环境
+//	isolated: 不修改 settings.json，将配置的环境变量注入子进程
+//	home:     使用配置独立的 Claude 配置目录，通过 CLAUDE_CONFIG_DIR 传给子进程
+func prepareLaunch(name string, p *profile.Profile, mode string) ([]string, error) {
+	switch mode {
+	case config.LaunchModeIsolated:
+		warnSettingsOverride(name)
+		return BuildChildEnv(os.Environ(), BuildEnvVarsFromProfile(p)), nil
+	case config.LaunchModeHome:
+		dir, err := PrepareProfileHome(name, p)
+		if err != nil {
+			return nil, err
+		}
+		return BuildChildEnv(os.Environ(), map[string]string{"CLAUDE_CONFIG_DIR": dir}), nil
+	default:
+		if err := syncToSettingsFunc(name, p); err != nil {
+			return nil, fmt.Errorf("同步到 settings.json 失败: %w", err)
+		}
+		return os.Environ(), nil
+	}
+}
+
+// launchEnv 按启动模式准备启动 claude 所需的环境变量，stop 在 claude 退出后调用
+// OpenAI 协议的配置先在本机启动转换代理，指向代理的配置只注入 claude 子进程而不同步到
+// settings.json，否则 claude 退出、代理关闭后 settings.json 会指向失效的地址
+func launchEnv(name string, p *profile.Profile, mode string) ([]string, func(), error) {
+	if p.Protocol != profile.ProtocolOpenAI {
+		env, err := prepareLaunch(name, p, mode)
+		return env, func() {}, err
+	}
+
+	local, stop, err := startTranslator(name, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	vars := BuildEnvVarsFromProfile(local)
+	if mode == config.LaunchModeHome {
+		dir, _, err := prepareHomeDir(name)
+		if err != nil {
+			stop()
+			return nil, nil, err
+		}
+		vars["CLAUDE_CONFIG_DIR"] = dir
+	} else {
+		// settings.json 中任何已同步的配置都会覆盖注入的变量
+		warnSettingsOverride("")
+	}
+	return BuildChildEnv(os.Environ(), vars), stop, nil
+}
+
+// translatorToken 是 claude 发给本地转换代理的占位 token，真实 token 由代理附加
+const translatorToken = "claude-switcher"
+
+// startTranslator 为 OpenAI 协议的配置在本机启动协议转换代理
+// 返回指向代理的配置副本，claude 使用该副本启动；stop 用于关闭代理
+func startTranslator(name string, p *profile.Profile) (*profile.Profile, func(), error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, fmt.Errorf("启动协议转换代理失败: %w", err)
+	}
+
+	up := profileUpstream(name, p)
+	srv := &http.Server{Handler: gateway.New(gateway.Static(up))}
+	go srv.Serve(ln)
+
+	local := *p
+	local.BaseURL = "http://" + ln.Addr().String()
+	local.AuthToken = translatorToken
+	local.Protocol = ""
+	local.EnvVars = make(map[string]string, len(p.EnvVars))
+	for k, v := range p.EnvVars {
+		if k != "ANTHROPIC_API_KEY" {
+			local.EnvVars[k] = v
+		}
+	}
+
+	return &local, func() { srv.Close() }, nil
+}
+
+// PrepareProfileHome 准备配置独立的 Claude 配置目录并同步配置，返回目录路径
+// 首次使用时以全局 settings.json 为模板，plugins、agents 等共享资源链接自 ~/.claude
+func PrepareProfileHome(name string, p *profile.Profile) (string, error) {
+	dir, settingsPath, err := prepareHomeDir(name)
+	if err != nil {
+		return "", err
+	}
+	if err := SyncToSettingsFile(settingsPath, name, p); err != nil {
+		return "", fmt.Errorf("同步到 %s 失败: %w", settingsPath, err)
+	}
+	return dir, nil
+}
+
+// prepareHomeDir 创建配置独立的 Claude 配置目录，返回目录及其中 settings.json 的路径
+func prepareHomeDir(name string) (string, string, error) {
+	dir := config.GetProfileHomeDir(name)
+	globalSettings := GetSettingsFilePath()
+
+	if err := home.Prepare(dir, filepath.Dir(globalSettings)); err != nil {
+		return "", "", err
+	}
+
+	settingsPath := filepath.Join(dir, GetSettingsFileName())
+	if err := settings.SeedSettings(settingsPath, globalSettings); err != nil {
+		return "", "", err
+	}
+	return dir, settingsPath, nil
+}
+
+// BuildChildEnv 在 base 的基础上覆盖 overrides 中的环境变量
+// 返回的变量按 base 中的原顺序排列，新增变量按字母序追加
+func BuildChildEnv(base []string, overrides map[string]string) []string {
+	env := make([]string, 0, len(base)+len(overrides))
+	seen := make(map[string]bool)
+
+	for _, kv := range base {
+		key := kv
+		if idx := strings.Index(kv, "="); idx >= 0 {
+			key = kv[:idx]
+		}
+		if v, ok := overrides[key]; ok {
+			if !seen[key] {
+				env = append(env, key+"="+v)
+				seen[key] = true
+			}
+			continue
+		}
+		env = append(env, kv)
+	}
+
+	var added []string
+	for k := range overrides {
+		if !seen[k] {
+			added = append(added, k)
+		}
+	}
+	sort.Strings(added)
+	for _, k := range added {
+		env = append(env, k+"="+overrides[k])
+	}
+
+	return env
+}
+
+// warnSettingsOverride 提示 settings.json 中仍有其他配置同步的环境变量
+// claude 会优先使用 settings.json 中的 env，可能覆盖本次注入的变量；name 为空时任何已同步的配置都会提示
+func warnSettingsOverride(name string) {
+	s, err := settings.LoadSettings(GetSettingsFilePath())
+	if err != nil || s.ClaudeSwitcherProfile == "" || s.ClaudeSwitcherProfile == name {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "⚠  settings.json 中仍有配置 '%s' 同步的环境变量，可能覆盖本次注入的同名变量\n", s.ClaudeSwitcherProfile)
+}
+
+// exitIPFunc 用于查询出口 IP，可被测试 mock
+var exitIPFunc = GetExitIP
+
+// confirmFunc 用于向用户确认操作，可被测试 mock
+var confirmFunc = confirm
+
+// confirm 输出提示并读取一行输入，y 或 yes 表示确认
+// 与其他提示共用 stdinReader，管道输入中的后续行不会被之前的读取吞掉
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	input, _ := stdinReader.ReadString('\n')
+	input = strings.ToLower(strings.TrimSpace(input))
+	return input == "y" || input == "yes"
+}
+
+// checkExitCountry 启动前检查出口 IP 是否位于配置允许的国家
+// 未设置 ALLOWED_COUNTRIES 时不检查；查询失败或不在允许列表时询问是否继续，返回 false 表示取消启动
+func checkExitCountry(p *profile.Profile) bool {
+	if len(p.AllowedCountries) == 0 {
+		return true
+	}
+
+	info, err := exitIPFunc(p)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠  %v\n", err)
+		return confirmFunc("无法确认出口位置，是否仍要继续启动？[y/N]: ")
+	}
+
+	fmt.Printf("出口 IP: %s (%s)\n", info.IP, info.Location())
+	if geoip.Allowed(info.CountryCode, p.AllowedCountries) {
+		return true
+	}
+	fmt.Fprintf(os.Stderr, "⚠  出口位置 %s 不在允许列表 %s 中，可能无法访问 API\n", info.CountryCode, strings.Join(p.AllowedCountries, ","))
+	return confirmFunc("是否仍要继续启动？[y/N]: ")
+}
@@ -0,0 +1,393 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/geoip"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+func TestBuildChildEnv(t *testing.T) {
+	base := []string{"PATH=/usr/bin", "ANTHROPIC_AUTH_TOKEN=old", "HOME=/home/me"}
+	overrides := map[string]string{
+		"ANTHROPIC_AUTH_TOKEN": "sk-new",
+		"ANTHROPIC_BASE_URL":   "https://api.example.com",
+		"API_TIMEOUT_MS":       "600000",
+	}
+
+	got := BuildChildEnv(base, overrides)
+	want := []string{
+		"PATH=/usr/bin",
+		"ANTHROPIC_AUTH_TOKEN=sk-new",
+		"HOME=/home/me",
+		"ANTHROPIC_BASE_URL=https://api.example.com",
+		"API_TIMEOUT_MS=600000",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildChildEnv() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveLaunchMode(t *testing.T) {
+	setupTestHome(t)
+
+	mode, err := resolveLaunchMode("")
+	if err != nil || mode != config.LaunchModeSettings {
+		t.Errorf("resolveLaunchMode(\"\") = %q, %v, want settings", mode, err)
+	}
+
+	if err := (&config.SwitcherConfig{LaunchMode: config.LaunchModeIsolated}).Save(); err != nil {
+		t.Fatal(err)
+	}
+	if mode, _ := resolveLaunchMode(""); mode != config.LaunchModeIsolated {
+		t.Errorf("mode = %q, want isolated from config.json", mode)
+	}
+	if mode, _ := resolveLaunchMode(config.LaunchModeSettings); mode != config.LaunchModeSettings {
+		t.Errorf("mode = %q, flag should override config.json", mode)
+	}
+	if _, err := resolveLaunchMode("bogus"); err == nil {
+		t.Error("expected error for unknown launch mode")
+	}
+}
+
+func TestExecuteUseIsolated(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{
+		Name:      "work",
+		AuthToken: "sk-work",
+		BaseURL:   "https://api.example.com",
+	})
+
+	var gotEnv, gotArgs []string
+	originalRun := runClaudeFunc
+	runClaudeFunc = func(env []string, args ...string) error {
+		gotEnv, gotArgs = env, args
+		return nil
+	}
+	defer func() { runClaudeFunc = originalRun }()
+
+	if err := Execute([]string{"use", "--isolated", "work", "--", "--resume"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if _, err := os.Stat(GetSettingsFilePath()); !os.IsNotExist(err) {
+		t.Errorf("isolated mode should not create settings.json, stat err = %v", err)
+	}
+	if !reflect.DeepEqual(gotArgs, []string{"--resume"}) {
+		t.Errorf("claude args = %v, want [--resume]", gotArgs)
+	}
+
+	env := strings.Join(gotEnv, "\n")
+	for _, want := range []string{"ANTHROPIC_AUTH_TOKEN=sk-work", "ANTHROPIC_BASE_URL=https://api.example.com"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("child env missing %s", want)
+		}
+	}
+}
+
+func TestExecuteConfigSetLaunchMode(t *testing.T) {
+	setupTestHome(t)
+
+	if err := Execute([]string{"config", "set", "launch-mode", "isolated"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	cfg, err := config.LoadSwitcherConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LaunchMode != config.LaunchModeIsolated {
+		t.Errorf("LaunchMode = %q, want isolated", cfg.LaunchMode)
+	}
+
+	if err := Execute([]string{"config", "set", "launch-mode", "bogus"}, BuildInfo{}); err == nil {
+		t.Error("expected error for unknown launch mode")
+	}
+}
+
+func TestExecuteUseHome(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{Name: "work", AuthToken: "sk-work"})
+
+	var gotEnv []string
+	originalRun := runClaudeFunc
+	runClaudeFunc = func(env []string, args ...string) error {
+		gotEnv = env
+		return nil
+	}
+	defer func() { runClaudeFunc = originalRun }()
+
+	if err := Execute([]string{"use", "--mode", "home", "work"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	dir := config.GetProfileHomeDir("work")
+	if !strings.Contains(strings.Join(gotEnv, "\n"), "CLAUDE_CONFIG_DIR="+dir) {
+		t.Errorf("child env should set CLAUDE_CONFIG_DIR=%s", dir)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "settings.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "sk-work") {
+		t.Errorf("profile settings.json should contain the token: %s", data)
+	}
+	if _, err := os.Stat(GetSettingsFilePath()); !os.IsNotExist(err) {
+		t.Errorf("home mode should not create global settings.json, stat err = %v", err)
+	}
+}
+
+func TestSyncToSettingsHelperMode(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{
+		Name:      "work",
+		AuthToken: "sk-secret",
+		BaseURL:   "https://api.example.com",
+	})
+
+	if err := (&config.SwitcherConfig{SyncMode: config.SyncModeHelper}).Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Execute([]string{"use", "--no-launch", "work"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	data, err := os.ReadFile(GetSettingsFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-secret") {
+		t.Errorf("token should not be written to settings.json: %s", data)
+	}
+	if !strings.Contains(string(data), `"apiKeyHelper"`) || !strings.Contains(string(data), "token 'work'") {
+		t.Errorf("settings.json should contain apiKeyHelper: %s", data)
+	}
+	if !strings.Contains(string(data), "https://api.example.com") {
+		t.Errorf("non-secret env should still be synced: %s", data)
+	}
+}
+
+func TestProfileToken(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *profile.Profile
+		want string
+	}{
+		{"auth token", &profile.Profile{AuthToken: "sk-auth"}, "sk-auth"},
+		{"api key env", &profile.Profile{EnvVars: map[string]string{"ANTHROPIC_API_KEY": "sk-key"}}, "sk-key"},
+		{"none", &profile.Profile{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProfileToken(tt.p); got != tt.want {
+				t.Errorf("ProfileToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got := shellQuote("/opt/my app/it's"); got != `'/opt/my app/it'\''s'` {
+		t.Errorf("shellQuote() = %s", got)
+	}
+}
+
+func TestExecuteUseOpenAIStartsTranslator(t *testing.T) {
+	profilesDir := setupTestHome(t)
+
+	var gotPath, gotAuth string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"c1","choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+
+	writeTestProfile(t, profilesDir, "or", &profile.Profile{
+		Name:      "or",
+		AuthToken: "sk-or",
+		BaseURL:   upstream.URL + "/api/v1",
+		Model:     "openai/gpt-4o",
+		Protocol:  profile.ProtocolOpenAI,
+	})
+
+	var status int
+	var body []byte
+	originalRun := runClaudeFunc
+	runClaudeFunc = func(env []string, args ...string) error {
+		// 模拟 claude 使用注入的环境变量调用 Messages API
+		vars := map[string]string{}
+		for _, kv := range env {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				vars[k] = v
+			}
+		}
+		if vars["ANTHROPIC_AUTH_TOKEN"] == "sk-or" {
+			t.Error("the real token should not be passed to claude")
+		}
+		resp, err := http.Post(vars["ANTHROPIC_BASE_URL"]+"/v1/messages", "application/json",
+			strings.NewReader(`{"model":"openai/gpt-4o","messages":[{"role":"user","content":"hello"}]}`))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		status = resp.StatusCode
+		body, _ = io.ReadAll(resp.Body)
+		return nil
+	}
+	defer func() { runClaudeFunc = originalRun }()
+
+	if err := Execute([]string{"use", "--isolated", "or"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if status != http.StatusOK || !strings.Contains(string(body), `"type":"message"`) {
+		t.Errorf("translated response = %d %s", status, body)
+	}
+	if gotPath != "/api/v1/chat/completions" || gotAuth != "Bearer sk-or" {
+		t.Errorf("upstream path = %q, Authorization = %q", gotPath, gotAuth)
+	}
+}
+
+func TestExecuteUseOpenAIDoesNotSyncTranslator(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "or", &profile.Profile{
+		Name:      "or",
+		AuthToken: "sk-or",
+		BaseURL:   "https://openrouter.ai/api/v1",
+		Protocol:  profile.ProtocolOpenAI,
+	})
+
+	var baseURL string
+	originalRun := runClaudeFunc
+	runClaudeFunc = func(env []string, args ...string) error {
+		for _, kv := range env {
+			if v, ok := strings.CutPrefix(kv, "ANTHROPIC_BASE_URL="); ok {
+				baseURL = v
+			}
+		}
+		return nil
+	}
+	defer func() { runClaudeFunc = originalRun }()
+
+	// 默认的 settings 模式下，转换代理的地址只注入 claude 子进程
+	if err := Execute([]string{"use", "or"}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(baseURL, "http://127.0.0.1:") {
+		t.Errorf("child ANTHROPIC_BASE_URL = %q, want the local translator", baseURL)
+	}
+	data, _ := os.ReadFile(GetSettingsFilePath())
+	if strings.Contains(string(data), "127.0.0.1") || strings.Contains(string(data), translatorToken) {
+		t.Errorf("settings.json should not point to the temporary translator:\n%s", data)
+	}
+}
+
+func TestExecuteUseAllowedCountries(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{
+		Name:             "work",
+		AuthToken:        "sk-work",
+		AllowedCountries: []string{"US", "JP"},
+	})
+
+	launched := false
+	originalRun, originalExitIP, originalConfirm := runClaudeFunc, exitIPFunc, confirmFunc
+	runClaudeFunc = func(env []string, args ...string) error {
+		launched = true
+		return nil
+	}
+	defer func() {
+		runClaudeFunc, exitIPFunc, confirmFunc = originalRun, originalExitIP, originalConfirm
+	}()
+
+	country := "JP"
+	exitIPFunc = func(p *profile.Profile) (*geoip.Info, error) {
+		return &geoip.Info{IP: "5.6.7.8", CountryCode: country}, nil
+	}
+	confirmed := 0
+	confirmFunc = func(string) bool {
+		confirmed++
+		return false
+	}
+
+	if err := Execute([]string{"use", "--isolated", "work"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !launched || confirmed != 0 {
+		t.Errorf("allowed country should launch without asking, launched=%v confirmed=%d", launched, confirmed)
+	}
+
+	// 不在允许列表时询问，用户拒绝后不启动
+	launched, country = false, "CN"
+	if err := Execute([]string{"use", "--isolated", "work"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if launched || confirmed != 1 {
+		t.Errorf("disallowed country should ask and cancel, launched=%v confirmed=%d", launched, confirmed)
+	}
+
+	// --skip-ip-check 跳过检查
+	if err := Execute([]string{"use", "--isolated", "--skip-ip-check", "work"}, BuildInfo{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !launched || confirmed != 1 {
+		t.Errorf("--skip-ip-check should launch without asking, launched=%v confirmed=%d", launched, confirmed)
+	}
+}
+
+func TestExecuteUseResolvesSecretReferences(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	home, _ := os.UserHomeDir()
+	if err := os.WriteFile(filepath.Join(home, "key"), []byte("sk-from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_WORK_KEY", "sk-from-env")
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{
+		Name:      "work",
+		AuthToken: "${env:TEST_WORK_KEY}",
+		BaseURL:   "https://api.example.com",
+		EnvVars:   map[string]string{"ANTHROPIC_API_KEY": "file:~/key"},
+	})
+
+	var gotEnv []string
+	originalRun := runClaudeFunc
+	runClaudeFunc = func(env []string, args ...string) error {
+		gotEnv = env
+		return nil
+	}
+	defer func() { runClaudeFunc = originalRun }()
+
+	if err := Execute([]string{"use", "--isolated", "work"}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	env := strings.Join(gotEnv, "\n")
+	for _, want := range []string{"ANTHROPIC_AUTH_TOKEN=sk-from-env", "ANTHROPIC_API_KEY=sk-from-file"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("child env missing %s", want)
+		}
+	}
+
+	// 详情中显示引用而不是密钥
+	var buf strings.Builder
+	if err := WriteProfileDetails(&buf, profilesDir, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "${env:TEST_WORK_KEY}") || strings.Contains(buf.String(), "sk-from") {
+		t.Errorf("details should show the reference:\n%s", buf.String())
+	}
+
+	// 引用无法解析时不启动
+	t.Setenv("TEST_WORK_KEY", "")
+	if err := Execute([]string{"use", "--isolated", "work"}, BuildInfo{}); err == nil {
+		t.Error("expected error when the reference cannot be resolved")
+	}
+}
@@ -1,14 +1,15 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/fiftyk/claude-switcher/internal/config"
 	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/proxy"
+	"github.com/fiftyk/claude-switcher/internal/secret"
 )
 
 // MenuAction 表示菜单操作类型
@@ -23,6 +24,11 @@ const (
 	ActionImport
 	ActionExport
 	ActionShowDetails
+	ActionRename
+	ActionCopy
+	ActionDiff
+	ActionValidate
+	ActionSync // 只同步到 settings.json，不启动 claude
 	ActionQuit
 )
 
@@ -34,7 +40,16 @@ type MenuItem struct {
 }
 
 // ShowMenu 显示交互式菜单，返回选择的操作和配置名
+// 标准输入输出都是终端时使用全屏菜单，否则使用逐行输入的菜单
 func ShowMenu(profilesDir string) (MenuAction, string, error) {
+	if useTUI() {
+		return ShowMenuTUI(profilesDir)
+	}
+	return ShowMenuLine(profilesDir)
+}
+
+// ShowMenuLine 显示逐行输入的菜单，输入编号或字母后回车
+func ShowMenuLine(profilesDir string) (MenuAction, string, error) {
 	for {
 		profiles, err := loadProfiles(profilesDir)
 		if err != nil {
@@ -58,7 +73,11 @@ func ShowMenu(profilesDir string) (MenuAction, string, error) {
 				if p.Name == activeProfile {
 					marker = "✅"
 				}
-				fmt.Printf("  %s %d. %s\n", marker, i+1, p.Name)
+				if label := expiryLabel(p, nowFunc()); label != "" {
+					fmt.Printf("  %s %d. %s  [%s]\n", marker, i+1, p.Name, label)
+				} else {
+					fmt.Printf("  %s %d. %s\n", marker, i+1, p.Name)
+				}
 			}
 		}
 		fmt.Println()
@@ -68,23 +87,27 @@ func ShowMenu(profilesDir string) (MenuAction, string, error) {
 		fmt.Println("  n. 创建新配置")
 		fmt.Println("  e. 编辑配置")
 		fmt.Println("  d. 删除配置")
+		fmt.Println("  m. 重命名配置")
+		fmt.Println("  y. 复制配置")
+		fmt.Println("  o. 导入配置")
 		fmt.Println()
 
 		// 其他功能区
 		fmt.Println("📋 其他功能")
 		fmt.Println("  i. 配置详情")
-		fmt.Println("  s. 同步到 settings.json")
+		fmt.Println("  a. 验证配置")
+		fmt.Println("  f. 比较配置")
+		fmt.Println("  s. 同步到 settings.json（不启动）")
 		fmt.Println("  v. 查看环境变量")
 		fmt.Println("  t. 导出配置")
 		fmt.Println("  h. 帮助")
 		fmt.Println("  q. 退出")
 		fmt.Println()
 
-		fmt.Printf("请选择操作 [%d-%d/n/e/d/i/s/v/t/h/q]: ", 1, len(profiles))
+		fmt.Printf("请选择操作 [%d-%d/n/e/d/m/y/o/i/a/f/s/v/t/h/q]: ", 1, len(profiles))
 		fmt.Print("\033[?25h") // 显示光标
 
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
+		input, _ := stdinReader.ReadString('\n')
 		input = strings.TrimSpace(input)
 		input = strings.ToLower(input)
 
@@ -134,11 +157,28 @@ func ShowMenu(profilesDir string) (MenuAction, string, error) {
 			return ActionShowDetails, name, nil
 		}
 
-		if input == "s" || input == "sync" {
-			fmt.Println("\n提示: 切换配置时会自动同步到 settings.json，无需手动操作")
-			fmt.Print("按回车键继续...")
-			reader.ReadString('\n')
-			continue
+		if input == "o" || input == "import" {
+			return ActionImport, "", nil
+		}
+
+		// 需要选择一个配置的操作
+		selectActions := map[string]struct {
+			action  MenuAction
+			purpose string
+		}{
+			"m": {ActionRename, "重命名"}, "rename": {ActionRename, "重命名"},
+			"y": {ActionCopy, "复制"}, "copy": {ActionCopy, "复制"},
+			"a": {ActionValidate, "验证"}, "validate": {ActionValidate, "验证"},
+			"f": {ActionDiff, "比较"}, "diff": {ActionDiff, "比较"},
+			"s": {ActionSync, "同步"}, "sync": {ActionSync, "同步"},
+		}
+		if sel, ok := selectActions[input]; ok {
+			name, err := selectProfile(profiles, sel.purpose)
+			if err != nil {
+				fmt.Printf("错误: %v\n", err)
+				continue
+			}
+			return sel.action, name, nil
 		}
 
 		if input == "v" || input == "vars" || input == "env" {
@@ -200,8 +240,7 @@ func selectProfile(profiles []*profile.Profile, purpose string) (string, error)
 	}
 
 	fmt.Print("请输入编号: ")
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
+	input, _ := stdinReader.ReadString('\n')
 	input = strings.TrimSpace(input)
 
 	var idx int
@@ -220,8 +259,7 @@ func promptConfigName(profiles []*profile.Profile) (string, error) {
 	}
 
 	fmt.Print("\n请输入新配置名称: ")
-	reader := bufio.NewReader(os.Stdin)
-	name, _ := reader.ReadString('\n')
+	name, _ := stdinReader.ReadString('\n')
 	name = strings.TrimSpace(name)
 
 	if name == "" {
@@ -241,34 +279,51 @@ func promptConfigName(profiles []*profile.Profile) (string, error) {
 
 // ShowProfileDetails 显示配置详情
 func ShowProfileDetails(profilesDir, name string) error {
+	return WriteProfileDetails(os.Stdout, profilesDir, name)
+}
+
+// WriteProfileDetails 将配置详情写入 w
+func WriteProfileDetails(w io.Writer, profilesDir, name string) error {
 	p, err := profile.LoadProfile(profilesDir, name)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("\n=== 配置详情: %s ===\n", name)
-	fmt.Println()
-	fmt.Printf("  显示名称: %s\n", p.Name)
-	fmt.Printf("  Auth Token: %s\n", maskToken(p.AuthToken))
-	fmt.Printf("  Base URL: %s\n", p.BaseURL)
-	fmt.Printf("  HTTP Proxy: %s\n", p.HTTPProxy)
-	fmt.Printf("  HTTPS Proxy: %s\n", p.HTTPSProxy)
-	fmt.Printf("  Model: %s\n", p.Model)
+	fmt.Fprintf(w, "\n=== 配置详情: %s ===\n", name)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  显示名称: %s\n", p.Name)
+	fmt.Fprintf(w, "  Auth Token: %s\n", maskToken(p.AuthToken))
+	fmt.Fprintf(w, "  Base URL: %s\n", p.BaseURL)
+	fmt.Fprintf(w, "  HTTP Proxy: %s\n", proxy.Mask(p.HTTPProxy))
+	fmt.Fprintf(w, "  HTTPS Proxy: %s\n", proxy.Mask(p.HTTPSProxy))
+	if p.NoProxy != "" {
+		fmt.Fprintf(w, "  No Proxy: %s\n", p.NoProxy)
+	}
+	fmt.Fprintf(w, "  Model: %s\n", p.Model)
+	if !p.ExpiresAt.IsZero() {
+		fmt.Fprintf(w, "  过期时间: %s（%s）\n", profile.FormatExpiry(p.ExpiresAt), expiryLabel(p, nowFunc()))
+	}
+	if p.Note != "" {
+		fmt.Fprintf(w, "  备注: %s\n", p.Note)
+	}
 
 	if len(p.EnvVars) > 0 {
-		fmt.Println()
-		fmt.Println("  自定义环境变量:")
-		for k, v := range p.EnvVars {
-			fmt.Printf("    %s: %s\n", k, v)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  自定义环境变量:")
+		for _, k := range sortedKeys(p.EnvVars) {
+			fmt.Fprintf(w, "    %s: %s\n", k, MaskEnvValue(k, p.EnvVars[k]))
 		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(w)
 	return nil
 }
 
-// maskToken 遮蔽 token
+// maskToken 遮蔽 token，密钥引用原样显示
 func maskToken(token string) string {
+	if secret.IsRef(token) {
+		return token
+	}
 	if len(token) <= 8 {
 		return "****"
 	}
@@ -277,15 +332,19 @@ func maskToken(token string) string {
 
 // CreateProfileInteractive 交互式创建配置
 func CreateProfileInteractive(profilesDir, name string) error {
+	return CreateProfileInteractiveWithReader(profilesDir, name, StdioReader{})
+}
+
+// CreateProfileInteractiveWithReader 交互式创建配置（可注入 Reader 进行测试）
+func CreateProfileInteractiveWithReader(profilesDir, name string, reader ReaderProvider) error {
 	fmt.Printf("\n=== 创建配置: %s ===\n", name)
 	fmt.Println()
 
 	p := &profile.Profile{
-		Name: name,
+		Name:    name,
+		EnvVars: make(map[string]string),
 	}
 
-	reader := bufio.NewReader(os.Stdin)
-
 	// 输入显示名称
 	fmt.Print("显示名称 (直接回车使用配置名): ")
 	displayName, _ := reader.ReadString('\n')
@@ -307,7 +366,7 @@ func CreateProfileInteractive(profilesDir, name string) error {
 	p.BaseURL = baseURL
 
 	// 输入代理
-	fmt.Print("HTTP Proxy (直接回车不使用代理): ")
+	fmt.Print("Proxy (支持 http/https/socks5/socks5h，直接回车不使用代理): ")
 	proxy, _ := reader.ReadString('\n')
 	proxy = strings.TrimSpace(proxy)
 	p.HTTPProxy = proxy
@@ -319,10 +378,10 @@ func CreateProfileInteractive(profilesDir, name string) error {
 	model = strings.TrimSpace(model)
 	p.Model = model
 
+	promptEnvVars(p, reader)
+
 	// 保存配置
-	filePath := filepath.Join(profilesDir, name+".conf")
-	content := formatProfile(p)
-	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+	if err := profile.SaveProfile(profilesDir, name, p); err != nil {
 		return err
 	}
 
@@ -330,39 +389,10 @@ func CreateProfileInteractive(profilesDir, name string) error {
 	return nil
 }
 
-// formatProfile 将配置格式化为字符串
-func formatProfile(p *profile.Profile) string {
-	var sb strings.Builder
-	sb.WriteString("# Claude Switcher 配置文件\n")
-	sb.WriteString("NAME=\"" + p.Name + "\"\n")
-
-	if p.AuthToken != "" {
-		sb.WriteString("ANTHROPIC_AUTH_TOKEN=\"" + p.AuthToken + "\"\n")
-	}
-	if p.BaseURL != "" {
-		sb.WriteString("ANTHROPIC_BASE_URL=\"" + p.BaseURL + "\"\n")
-	}
-	if p.HTTPProxy != "" {
-		sb.WriteString("http_proxy=\"" + p.HTTPProxy + "\"\n")
-	}
-	if p.HTTPSProxy != "" {
-		sb.WriteString("https_proxy=\"" + p.HTTPSProxy + "\"\n")
-	}
-	if p.Model != "" {
-		sb.WriteString("ANTHROPIC_MODEL=\"" + p.Model + "\"\n")
-	}
-	for k, v := range p.EnvVars {
-		sb.WriteString(k + "=\"" + v + "\"\n")
-	}
-
-	return sb.String()
-}
-
 // DeleteProfileInteractive 交互式删除配置
 func DeleteProfileInteractive(profilesDir, name string) error {
 	fmt.Printf("\n⚠️  确认删除配置 '%s'？ (输入 y 确认): ", name)
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
+	input, _ := stdinReader.ReadString('\n')
 	input = strings.TrimSpace(input)
 
 	if input != "y" && input != "Y" {
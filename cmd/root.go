@@ -1,12 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/geoip"
 	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/proxy"
 	"github.com/fiftyk/claude-switcher/internal/settings"
 )
 
@@ -34,9 +39,8 @@ func BuildEnvVarsFromProfile(p *profile.Profile) map[string]string {
 	if p.BaseURL != "" {
 		envVars["ANTHROPIC_BASE_URL"] = p.BaseURL
 	}
-	if p.HTTPProxy != "" {
-		envVars["http_proxy"] = p.HTTPProxy
-		envVars["https_proxy"] = p.HTTPProxy
+	for k, v := range ProxyEnvVars(p) {
+		envVars[k] = v
 	}
 	if p.Model != "" {
 		envVars["ANTHROPIC_MODEL"] = p.Model
@@ -50,48 +54,65 @@ func BuildEnvVarsFromProfile(p *profile.Profile) map[string]string {
 	return envVars
 }
 
-// GetExitIP 获取出口 IP
-func GetExitIP() (string, error) {
-	cmd := exec.Command("curl", "-s", "http://ip-api.com/json/?fields=query")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("curl failed")
+// proxyEnvKeys 代理环境变量，按输出顺序排列
+var proxyEnvKeys = []string{"http_proxy", "https_proxy", "all_proxy", "no_proxy"}
+
+// ProxyEnvVars 返回配置的代理环境变量
+// https_proxy 优先使用 HTTPSProxy；SOCKS5 代理同时写入 all_proxy，供只识别 all_proxy 的程序使用
+func ProxyEnvVars(p *profile.Profile) map[string]string {
+	envVars := make(map[string]string)
+
+	httpsProxy := p.HTTPSProxy
+	if httpsProxy == "" {
+		httpsProxy = p.HTTPProxy
+	}
+	if p.HTTPProxy != "" {
+		envVars["http_proxy"] = p.HTTPProxy
+	}
+	if httpsProxy != "" {
+		envVars["https_proxy"] = httpsProxy
+	}
+	for _, v := range []string{p.HTTPProxy, httpsProxy} {
+		if proxy.IsSOCKS(v) {
+			envVars["all_proxy"] = v
+			break
+		}
+	}
+	if p.NoProxy != "" {
+		envVars["no_proxy"] = p.NoProxy
 	}
 
-	// 简单解析 JSON 获取 IP
-	ip := string(output)
-	// ip 格式可能是 {"query":"1.2.3.4"}
-	return ip, nil
+	return envVars
 }
 
-// PrintHelp 打印帮助信息
-func PrintHelp(w *os.File) error {
-	helpText := `Claude Switcher - 使用帮助
-
-用法:
-  claude-switcher                    启动交互式配置选择
-  claude-switcher <配置名称> [-- <参数...>]  使用指定配置启动，可透传参数
-  claude-switcher <配置名称> --sync         切换配置并同步到 settings.json
-  claude-switcher --config <名称> [-- <参数...>] 使用指定配置启动，可透传参数
-  claude-switcher --list             列出所有可用配置
-  claude-switcher --test <名称>      测试配置有效性
-  claude-switcher --rename <旧> <新> 重命名配置
-  claude-switcher --copy <源> <目标>  复制配置
-  claude-switcher --help             显示此帮助信息
+// GetExitIP 经过配置的代理查询出口 IP 及所在国家
+// 查询地址可通过 'claude-switcher config set ip-check-url <URL>' 修改
+func GetExitIP(p *profile.Profile) (*geoip.Info, error) {
+	cfg, err := config.LoadSwitcherConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport, err := proxy.NewTransport(profileProxyConfig(p))
+	if err != nil {
+		return nil, err
+	}
+	defer transport.CloseIdleConnections()
 
-说明:
-  • 配置文件位于: ~/.claude-switcher/profiles/
-  • 无参数运行时进入交互式菜单
-  • 使用 --sync 参数可将配置同步到 ~/.claude/settings.json
-`
-	_, err := fmt.Fprint(w, helpText)
-	return err
+	client := &http.Client{
+		Timeout:   connectivityTimeout,
+		Transport: transport,
+	}
+	return geoip.Lookup(context.Background(), client, cfg.IPCheckURL)
 }
 
 // SyncToSettings 将 profile 同步到 settings.json
 func SyncToSettings(profileName string, p *profile.Profile) error {
-	settingsPath := GetSettingsFilePath()
+	return SyncToSettingsFile(GetSettingsFilePath(), profileName, p)
+}
 
+// SyncToSettingsFile 将 profile 同步到指定的 settings.json
+// 同步模式为 helper 时不写入密钥，改为写入指向 'claude-switcher token' 的 apiKeyHelper
+func SyncToSettingsFile(settingsPath, profileName string, p *profile.Profile) error {
 	// 构建环境变量
 	envVars := make(map[string]string)
 	if p.AuthToken != "" {
@@ -100,15 +121,61 @@ func SyncToSettings(profileName string, p *profile.Profile) error {
 	if p.BaseURL != "" {
 		envVars["ANTHROPIC_BASE_URL"] = p.BaseURL
 	}
-	if p.HTTPProxy != "" {
-		envVars["http_proxy"] = p.HTTPProxy
-		envVars["https_proxy"] = p.HTTPProxy
+	for k, v := range ProxyEnvVars(p) {
+		envVars[k] = v
 	}
 	for k, v := range p.EnvVars {
 		envVars[k] = v
 	}
 
-	return settings.SyncProfileToSettings(settingsPath, profileName, envVars)
+	cfg, err := config.LoadSwitcherConfig()
+	if err != nil {
+		return err
+	}
+
+	var opts settings.SyncOptions
+	if cfg.SyncMode == config.SyncModeHelper {
+		for _, key := range tokenEnvKeys {
+			delete(envVars, key)
+		}
+		helper, err := apiKeyHelperCommand(profileName)
+		if err != nil {
+			return err
+		}
+		opts.APIKeyHelper = helper
+	}
+
+	return settings.SyncProfileWithOptions(settingsPath, profileName, envVars, opts)
+}
+
+// tokenEnvKeys 保存 API 密钥的环境变量，helper 模式下不会写入 settings.json
+var tokenEnvKeys = []string{"ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_API_KEY"}
+
+// ProfileToken 返回配置的 API 密钥
+func ProfileToken(p *profile.Profile) string {
+	if p.AuthToken != "" {
+		return p.AuthToken
+	}
+	for _, key := range tokenEnvKeys {
+		if v := p.EnvVars[key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// apiKeyHelperCommand 返回输出指定配置密钥的 shell 命令
+func apiKeyHelperCommand(profileName string) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("无法获取程序路径: %w", err)
+	}
+	return shellQuote(exe) + " token " + shellQuote(profileName), nil
+}
+
+// shellQuote 使用单引号转义 shell 参数
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 // SetActiveProfile 设置活动配置
@@ -139,11 +206,20 @@ func GetActiveProfile() (string, error) {
 
 // RunClaude 运行 claude CLI
 func RunClaude(args ...string) error {
+	return RunClaudeWithEnv(os.Environ(), args...)
+}
+
+// RunClaudeWithEnv 使用指定的环境变量运行 claude CLI
+func RunClaudeWithEnv(env []string, args ...string) error {
+	if _, err := exec.LookPath("claude"); err != nil {
+		return fmt.Errorf("Claude CLI 未安装")
+	}
+
 	cmd := exec.Command("claude", args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	cmd.Env = os.Environ()
+	cmd.Env = env
 
 	return cmd.Run()
 }
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/secret"
+	"github.com/fiftyk/claude-switcher/internal/settings"
+)
+
+// fingerprintPrefix token 指纹的前缀
+const fingerprintPrefix = "sha256:"
+
+// newRotateTokenCommand 替换多个配置共用的 token
+func newRotateTokenCommand() *Command {
+	c := newCommand("rotate-token", "[--yes] <配置名|指纹> | --list", "替换所有使用同一 token 的配置中的 token")
+	c.Long = `以配置名或 token 指纹指定旧 token，找出所有使用该 token 的配置（ANTHROPIC_AUTH_TOKEN
+或 ANTHROPIC_API_KEY），预览后一并替换为新 token。新 token 在终端中不回显地输入，
+标准输入不是终端时读取一行，避免出现在命令行历史中:
+  claude-switcher rotate-token --list          # 查看各 token 的指纹及使用它的配置
+  claude-switcher rotate-token work
+  pass show relay/new | claude-switcher rotate-token --yes sha256:1a2b3c4d5e6f
+
+任何一个配置写入失败时，已修改的配置会恢复原样。settings.json 中同步的配置受影响时会重新同步。`
+	list := c.Flags.Bool("list", false, "列出 token 指纹及使用它的配置")
+	yes := c.Flags.Bool("yes", false, "不询问确认")
+	c.Run = func(args []string) error {
+		profilesDir := config.GetProfilesDir()
+		if *list {
+			if len(args) != 0 {
+				return c.usageError()
+			}
+			return PrintTokenFingerprints(profilesDir)
+		}
+		if len(args) != 1 {
+			return c.usageError()
+		}
+		return RotateToken(profilesDir, args[0], *yes)
+	}
+	return c
+}
+
+// TokenFingerprint 返回 token 的指纹（SHA-256 的前 12 位十六进制），用于识别 token 而不显示其内容
+func TokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fingerprintPrefix + hex.EncodeToString(sum[:])[:12]
+}
+
+// tokenUse 一个配置中保存 token 的键
+type tokenUse struct {
+	Profile string
+	Key     string
+	Token   string
+}
+
+// collectTokenUses 返回所有配置中的 token，密钥引用不计入
+func collectTokenUses(profilesDir string) ([]tokenUse, error) {
+	names, err := profile.ListProfiles(profilesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var uses []tokenUse
+	for _, name := range names {
+		p, err := profile.LoadProfile(profilesDir, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range tokenEnvKeys {
+			token := p.EnvVars[key]
+			if key == "ANTHROPIC_AUTH_TOKEN" {
+				token = p.AuthToken
+			}
+			if token != "" && !secret.IsRef(token) {
+				uses = append(uses, tokenUse{Profile: name, Key: key, Token: token})
+			}
+		}
+	}
+	return uses, nil
+}
+
+// PrintTokenFingerprints 按 token 指纹分组列出配置
+func PrintTokenFingerprints(profilesDir string) error {
+	uses, err := collectTokenUses(profilesDir)
+	if err != nil {
+		return err
+	}
+	if len(uses) == 0 {
+		fmt.Println("没有配置保存了 token")
+		return nil
+	}
+
+	groups := make(map[string][]string)
+	for _, u := range uses {
+		fp := TokenFingerprint(u.Token)
+		if names := groups[fp]; len(names) == 0 || names[len(names)-1] != u.Profile {
+			groups[fp] = append(names, u.Profile)
+		}
+	}
+	var fps []string
+	for fp := range groups {
+		fps = append(fps, fp)
+	}
+	sort.Strings(fps)
+
+	fmt.Println("token 指纹:")
+	for _, fp := range fps {
+		fmt.Printf("  %s  %s\n", fp, strings.Join(groups[fp], ", "))
+	}
+	return nil
+}
+
+// findOldToken 根据配置名或指纹（可省略 sha256: 前缀，至少 6 位）找到要替换的 token
+func findOldToken(profilesDir, target string, uses []tokenUse) (string, error) {
+	if profile.Exists(profilesDir, target) {
+		p, err := profile.LoadProfile(profilesDir, target)
+		if err != nil {
+			return "", err
+		}
+		token := ProfileToken(p)
+		if token == "" {
+			return "", fmt.Errorf("配置 '%s' 未设置 API 密钥", target)
+		}
+		if secret.IsRef(token) {
+			return "", fmt.Errorf("配置 '%s' 的 token 是密钥引用 %s，请直接更新引用的来源", target, token)
+		}
+		return token, nil
+	}
+
+	prefix := strings.ToLower(strings.TrimPrefix(target, fingerprintPrefix))
+	if len(prefix) < 6 {
+		return "", fmt.Errorf("配置不存在: %s（指纹至少需要 6 位）", target)
+	}
+	var found string
+	for _, u := range uses {
+		if !strings.HasPrefix(strings.TrimPrefix(TokenFingerprint(u.Token), fingerprintPrefix), prefix) {
+			continue
+		}
+		if found != "" && found != u.Token {
+			return "", fmt.Errorf("指纹 %s 匹配多个 token，请提供更长的指纹", target)
+		}
+		found = u.Token
+	}
+	if found == "" {
+		return "", fmt.Errorf("没有配置使用指纹为 %s 的 token", target)
+	}
+	return found, nil
+}
+
+// RotateToken 将所有使用 target 对应 token 的配置改为新 token
+func RotateToken(profilesDir, target string, yes bool) error {
+	uses, err := collectTokenUses(profilesDir)
+	if err != nil {
+		return err
+	}
+	oldToken, err := findOldToken(profilesDir, target, uses)
+	if err != nil {
+		return err
+	}
+
+	affected := make(map[string][]string)
+	var names []string
+	for _, u := range uses {
+		if u.Token != oldToken {
+			continue
+		}
+		if _, ok := affected[u.Profile]; !ok {
+			names = append(names, u.Profile)
+		}
+		affected[u.Profile] = append(affected[u.Profile], u.Key)
+	}
+
+	fmt.Printf("token %s 被以下 %d 个配置使用:\n", TokenFingerprint(oldToken), len(names))
+	for _, name := range names {
+		fmt.Printf("  %s (%s)\n", name, strings.Join(affected[name], ", "))
+	}
+
+	newToken, err := readPassphraseFunc("新 token: ")
+	if err != nil {
+		return err
+	}
+	newToken = strings.TrimSpace(newToken)
+	if newToken == "" {
+		return fmt.Errorf("token 不能为空")
+	}
+	if newToken == oldToken {
+		return fmt.Errorf("新 token 与旧 token 相同")
+	}
+	if !yes && !confirmFunc(fmt.Sprintf("将 %d 个配置的 token 替换为 %s? [y/N]: ", len(names), TokenFingerprint(newToken))) {
+		fmt.Println("已取消")
+		return nil
+	}
+
+	if err := rewriteTokens(profilesDir, names, affected, newToken); err != nil {
+		return err
+	}
+	fmt.Printf("✓ 已更新 %d 个配置\n", len(names))
+
+	return resyncRotated(names)
+}
+
+// rewriteTokens 先生成所有配置的新内容再逐个写入，任何一个写入失败时恢复已写入的配置
+func rewriteTokens(profilesDir string, names []string, keys map[string][]string, newToken string) error {
+	originals := make(map[string][]byte, len(names))
+	updated := make(map[string][]byte, len(names))
+	for _, name := range names {
+		data, err := profile.ReadFile(profilesDir, name)
+		if err != nil {
+			return err
+		}
+		doc := profile.ParseDocument(data)
+		for _, key := range keys[name] {
+			if err := doc.Set(key, newToken); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		originals[name], updated[name] = data, doc.Bytes()
+	}
+
+	for i, name := range names {
+		if err := profile.SaveRaw(profilesDir, name, updated[name]); err != nil {
+			for _, done := range names[:i] {
+				if rbErr := profile.SaveRaw(profilesDir, done, originals[done]); rbErr != nil {
+					fmt.Fprintf(os.Stderr, "✗ 无法恢复配置 %s: %v\n", done, rbErr)
+				}
+			}
+			return fmt.Errorf("写入配置 %s 失败，已恢复其他配置: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// resyncRotated 重新同步仍保存旧 token 的 settings.json
+// 包括全局 settings.json 中同步的配置，以及 home 模式下各配置独立的 settings.json
+func resyncRotated(names []string) error {
+	profilesDir := config.GetProfilesDir()
+	if s, err := settings.LoadSettings(GetSettingsFilePath()); err == nil && indexOf(names, s.ClaudeSwitcherProfile) >= 0 {
+		name := s.ClaudeSwitcherProfile
+		p, err := loadResolvedProfile(profilesDir, name)
+		if err != nil {
+			return err
+		}
+		if err := syncToSettingsFunc(name, p); err != nil {
+			return fmt.Errorf("同步到 settings.json 失败: %w", err)
+		}
+		fmt.Printf("✓ 已重新同步 settings.json（%s）\n", name)
+	}
+
+	for _, name := range names {
+		path := filepath.Join(config.GetProfileHomeDir(name), GetSettingsFileName())
+		if s, err := settings.LoadSettings(path); err != nil || s.ClaudeSwitcherProfile != name {
+			continue
+		}
+		p, err := loadResolvedProfile(profilesDir, name)
+		if err != nil {
+			return err
+		}
+		if err := SyncToSettingsFile(path, name, p); err != nil {
+			return fmt.Errorf("同步到 %s 失败: %w", path, err)
+		}
+		fmt.Printf("✓ 已重新同步 %s\n", path)
+	}
+	return nil
+}
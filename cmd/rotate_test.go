@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/settings"
+)
+
+func TestTokenFingerprint(t *testing.T) {
+	fp := TokenFingerprint("sk-old")
+	if !strings.HasPrefix(fp, "sha256:") || len(fp) != len("sha256:")+12 {
+		t.Errorf("TokenFingerprint() = %q", fp)
+	}
+	if strings.Contains(fp, "sk-old") || fp == TokenFingerprint("sk-new") {
+		t.Error("fingerprint should not reveal the token and should differ between tokens")
+	}
+}
+
+func TestRotateToken(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	shared := "# 中转站\nNAME=\"a\"\nANTHROPIC_AUTH_TOKEN=\"sk-old\" # 每月更新\n"
+	if err := os.WriteFile(filepath.Join(profilesDir, "a.conf"), []byte(shared), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeTestProfile(t, profilesDir, "b", &profile.Profile{Name: "b", EnvVars: map[string]string{"ANTHROPIC_API_KEY": "sk-old"}})
+	writeTestProfile(t, profilesDir, "c", &profile.Profile{Name: "c", AuthToken: "sk-other"})
+
+	p, _ := profile.LoadProfile(profilesDir, "a")
+	if err := SyncToSettings("a", p); err != nil {
+		t.Fatal(err)
+	}
+
+	originalConfirm := confirmFunc
+	var prompt string
+	confirmFunc = func(s string) bool { prompt = s; return true }
+	defer func() { confirmFunc = originalConfirm }()
+
+	// 以指纹指定旧 token
+	stubPassphrase(t, "sk-new")
+	target := strings.TrimPrefix(TokenFingerprint("sk-old"), "sha256:")[:8]
+	if err := Execute([]string{"rotate-token", target}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "2 个配置") {
+		t.Errorf("confirm prompt = %q", prompt)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(profilesDir, "a.conf"))
+	if want := strings.Replace(shared, "sk-old", "sk-new", 1); string(data) != want {
+		t.Errorf("a.conf =\n%s\nwant\n%s", data, want)
+	}
+	if b, _ := profile.LoadProfile(profilesDir, "b"); b.EnvVars["ANTHROPIC_API_KEY"] != "sk-new" {
+		t.Errorf("b ANTHROPIC_API_KEY = %q", b.EnvVars["ANTHROPIC_API_KEY"])
+	}
+	if c, _ := profile.LoadProfile(profilesDir, "c"); c.AuthToken != "sk-other" {
+		t.Errorf("c should be unchanged, got %q", c.AuthToken)
+	}
+
+	s, err := settings.LoadSettings(GetSettingsFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Env["ANTHROPIC_AUTH_TOKEN"] != "sk-new" {
+		t.Errorf("settings.json token = %q, want sk-new", s.Env["ANTHROPIC_AUTH_TOKEN"])
+	}
+}
+
+func TestRotateTokenPipedInput(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "a", &profile.Profile{Name: "a", AuthToken: "sk-old"})
+
+	// 新 token 与确认都从同一个管道读取
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("sk-new\ny\n")
+	w.Close()
+	originalStdin, originalReader := os.Stdin, stdinReader
+	os.Stdin, stdinReader = r, bufio.NewReader(r)
+	defer func() { os.Stdin, stdinReader = originalStdin, originalReader; r.Close() }()
+
+	originalRead, originalConfirm := readPassphraseFunc, confirmFunc
+	readPassphraseFunc, confirmFunc = readPassphrase, confirm
+	defer func() { readPassphraseFunc, confirmFunc = originalRead, originalConfirm }()
+
+	if err := RotateToken(profilesDir, "a", false); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := profile.LoadProfile(profilesDir, "a"); a.AuthToken != "sk-new" {
+		t.Errorf("a token = %q, want sk-new", a.AuthToken)
+	}
+}
+
+func TestRotateTokenErrors(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "a", &profile.Profile{Name: "a", AuthToken: "sk-old"})
+	writeTestProfile(t, profilesDir, "ref", &profile.Profile{Name: "ref", AuthToken: "${env:WORK_KEY}"})
+
+	originalConfirm := confirmFunc
+	confirmFunc = func(string) bool { return false }
+	defer func() { confirmFunc = originalConfirm }()
+
+	stubPassphrase(t, "sk-old")
+	if err := RotateToken(profilesDir, "a", false); err == nil {
+		t.Error("expected error when the new token equals the old one")
+	}
+	if err := RotateToken(profilesDir, "ref", false); err == nil {
+		t.Error("expected error for a secret reference")
+	}
+	if err := RotateToken(profilesDir, "abc", false); err == nil {
+		t.Error("expected error for a short fingerprint")
+	}
+
+	// 取消确认时不修改
+	stubPassphrase(t, "sk-new")
+	if err := RotateToken(profilesDir, "a", false); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := profile.LoadProfile(profilesDir, "a"); p.AuthToken != "sk-old" {
+		t.Errorf("token changed after cancel: %q", p.AuthToken)
+	}
+}
@@ -1,9 +1,7 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/fiftyk/claude-switcher/internal/profile"
@@ -42,6 +40,7 @@ func GetTemplateList() []Template {
 				HTTPProxy:  "",
 				HTTPSProxy: "",
 				Model:      "",
+				Protocol:   profile.ProtocolOpenAI,
 				EnvVars:    map[string]string{},
 			},
 		},
@@ -134,8 +133,7 @@ func SelectTemplateInteractive() (*Template, error) {
 	PrintTemplates()
 
 	fmt.Print("请选择模板编号: ")
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
+	input, _ := stdinReader.ReadString('\n')
 	input = strings.TrimSpace(input)
 
 	var idx int
@@ -0,0 +1,420 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/tty"
+)
+
+// tuiPollInterval 等待按键的间隔，超时后检查窗口大小是否变化
+const tuiPollInterval = 100 * time.Millisecond
+
+// useTUI 判断是否使用全屏菜单
+func useTUI() bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return tty.IsTerminal(os.Stdin) && tty.IsTerminal(os.Stdout)
+}
+
+// menuEntry 菜单中的一个配置
+type menuEntry struct {
+	Name    string // 配置文件名（不含 .conf）
+	Profile *profile.Profile
+}
+
+// loadMenuEntries 加载所有配置
+func loadMenuEntries(profilesDir string) ([]menuEntry, error) {
+	names, err := profile.ListProfiles(profilesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []menuEntry
+	for _, name := range names {
+		p, err := profile.LoadProfile(profilesDir, name)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, menuEntry{Name: name, Profile: p})
+	}
+	return entries, nil
+}
+
+// menuModel 全屏菜单的状态，与终端读写分离以便测试
+type menuModel struct {
+	entries []menuEntry
+	active  string
+	details func(name string) []string // 返回详情区显示的内容
+	now     time.Time                  // 计算 token 剩余天数的时间
+
+	filter    string
+	filtering bool // 正在输入过滤条件
+	cursor    int  // 在 visible() 中的位置
+	offset    int  // 列表滚动位置
+	message   string
+}
+
+// visible 返回匹配过滤条件的配置（按配置名或显示名称，不区分大小写）
+func (m *menuModel) visible() []menuEntry {
+	if m.filter == "" {
+		return m.entries
+	}
+	filter := strings.ToLower(m.filter)
+	var out []menuEntry
+	for _, e := range m.entries {
+		if strings.Contains(strings.ToLower(e.Name), filter) ||
+			strings.Contains(strings.ToLower(e.Profile.Name), filter) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// selected 返回光标所在的配置
+func (m *menuModel) selected() (menuEntry, bool) {
+	items := m.visible()
+	if m.cursor < 0 || m.cursor >= len(items) {
+		return menuEntry{}, false
+	}
+	return items[m.cursor], true
+}
+
+// move 移动光标，超出范围时停在两端
+func (m *menuModel) move(delta int) {
+	m.cursor += delta
+	if n := len(m.visible()); m.cursor >= n {
+		m.cursor = n - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// handleKey 处理按键，done 为 true 时返回选择的操作
+func (m *menuModel) handleKey(k tty.Key) (action MenuAction, name string, done bool) {
+	m.message = ""
+
+	switch k.Code {
+	case tty.KeyCtrlC:
+		return ActionQuit, "", true
+	case tty.KeyUp:
+		m.move(-1)
+		return
+	case tty.KeyDown:
+		m.move(1)
+		return
+	case tty.KeyPageUp:
+		m.move(-10)
+		return
+	case tty.KeyPageDown:
+		m.move(10)
+		return
+	case tty.KeyHome:
+		m.cursor = 0
+		return
+	case tty.KeyEnd:
+		m.cursor = len(m.visible()) - 1
+		m.move(0)
+		return
+	}
+
+	if m.filtering {
+		switch k.Code {
+		case tty.KeyRune:
+			m.filter += string(k.Rune)
+			m.cursor = 0
+		case tty.KeyBackspace:
+			if m.filter == "" {
+				m.filtering = false
+			} else {
+				r := []rune(m.filter)
+				m.filter = string(r[:len(r)-1])
+				m.cursor = 0
+			}
+		case tty.KeyEnter:
+			m.filtering = false
+		case tty.KeyEsc:
+			m.filter, m.filtering, m.cursor = "", false, 0
+		}
+		return
+	}
+
+	switch k.Code {
+	case tty.KeyEnter:
+		return m.choose(ActionRun)
+	case tty.KeyEsc:
+		if m.filter != "" {
+			m.filter, m.cursor = "", 0
+			return
+		}
+		return ActionQuit, "", true
+	case tty.KeyRune:
+	default:
+		return
+	}
+
+	switch k.Rune {
+	case 'k', 'K':
+		m.move(-1)
+	case 'j', 'J':
+		m.move(1)
+	case 'g':
+		m.cursor = 0
+	case 'G':
+		m.cursor = len(m.visible()) - 1
+		m.move(0)
+	case '/':
+		m.filtering = true
+	case 'r', 'R':
+		return m.choose(ActionRun)
+	case 'c', 'C', 'n', 'N':
+		return ActionCreate, "", true
+	case 'e', 'E':
+		return m.choose(ActionEdit)
+	case 'd', 'D':
+		return m.choose(ActionDelete)
+	case 'v', 'V', 'i', 'I':
+		return m.choose(ActionShowDetails)
+	case 'x', 'X', 't', 'T':
+		return m.choose(ActionExport)
+	case 'm', 'M':
+		return m.choose(ActionRename)
+	case 'y', 'Y':
+		return m.choose(ActionCopy)
+	case 'f', 'F':
+		return m.choose(ActionDiff)
+	case 'a', 'A':
+		return m.choose(ActionValidate)
+	case 's', 'S':
+		return m.choose(ActionSync)
+	case 'o', 'O':
+		return ActionImport, "", true
+	case 'q', 'Q':
+		return ActionQuit, "", true
+	}
+	return
+}
+
+// choose 对光标所在的配置执行操作
+func (m *menuModel) choose(action MenuAction) (MenuAction, string, bool) {
+	e, ok := m.selected()
+	if !ok {
+		m.message = "没有可用配置"
+		return ActionNone, "", false
+	}
+	return action, e.Name, true
+}
+
+// menuKeyHelp 底部的按键说明
+var menuKeyHelp = []string{
+	"↑/↓ j/k 移动  回车/r 运行  s 同步  / 过滤  q 退出",
+	"c 创建  e 编辑  d 删除  m 重命名  y 复制  v 详情  a 验证  f 比较  o 导入  x 导出",
+}
+
+// render 生成 width x height 的屏幕内容
+// 上方为配置列表，下方为光标所在配置的详情，列表随光标滚动
+func (m *menuModel) render(width, height int) []string {
+	items := m.visible()
+	if m.cursor >= len(items) {
+		m.cursor = len(items) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+
+	var lines []string
+	add := func(s string) { lines = append(lines, tty.Truncate(s, width)) }
+	// 制表符在部分中文终端中占两列，分隔线使用 ASCII 字符
+	separator := strings.Repeat("-", width)
+
+	add(" Claude Switcher - 配置管理")
+	switch {
+	case m.filtering:
+		add(" 过滤: " + m.filter + "_")
+	case m.filter != "":
+		add(fmt.Sprintf(" 过滤: %s（%d/%d，Esc 清除）", m.filter, len(items), len(m.entries)))
+	default:
+		add(fmt.Sprintf(" 共 %d 个配置，按 / 过滤", len(m.entries)))
+	}
+	lines = append(lines, separator)
+
+	// 除标题与底部说明外，列表和详情平分剩余空间，列表至少显示 3 行
+	header, footer := 3, 1+len(menuKeyHelp)
+	rest := height - header - footer
+	listHeight := rest
+	var details []string
+	if e, ok := m.selected(); ok && m.details != nil && rest >= 8 {
+		details = m.details(e.Name)
+		listHeight = rest / 2
+		if len(items) < listHeight {
+			listHeight = len(items)
+		}
+		if listHeight < 3 {
+			listHeight = 3
+		}
+	}
+	if listHeight < 1 {
+		listHeight = 1
+	}
+
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+listHeight {
+		m.offset = m.cursor - listHeight + 1
+	}
+	if last := len(items) - listHeight; m.offset > last {
+		m.offset = last
+	}
+	if m.offset < 0 {
+		m.offset = 0
+	}
+
+	if len(items) == 0 {
+		if len(m.entries) == 0 {
+			add("  暂无配置，按 c 创建")
+		} else {
+			add("  没有匹配的配置")
+		}
+	}
+	for i := m.offset; i < len(items) && i < m.offset+listHeight; i++ {
+		e := items[i]
+		marker := "  "
+		if e.Name == m.active {
+			marker = "* "
+		}
+		label := marker + e.Name
+		if e.Profile.Name != "" && e.Profile.Name != e.Name {
+			label += "  (" + e.Profile.Name + ")"
+		}
+		if expiry := expiryLabel(e.Profile, m.now); expiry != "" {
+			label += "  [" + expiry + "]"
+		}
+		if i == m.cursor {
+			lines = append(lines, "\x1b[7m"+tty.Pad("> "+label, width)+"\x1b[0m")
+		} else {
+			add("  " + label)
+		}
+	}
+
+	if details != nil {
+		for len(lines) < header+listHeight {
+			lines = append(lines, "")
+		}
+		lines = append(lines, separator)
+		for _, d := range details {
+			if len(lines) >= height-footer {
+				break
+			}
+			add(d)
+		}
+	}
+
+	for len(lines) < height-footer {
+		lines = append(lines, "")
+	}
+	if m.message != "" {
+		add(" " + m.message)
+	} else {
+		lines = append(lines, separator)
+	}
+	for _, help := range menuKeyHelp {
+		add(" " + help)
+	}
+
+	// 终端过小时截掉多余的行，避免屏幕滚动
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return lines
+}
+
+// profileDetailLines 返回详情区显示的内容，去掉首尾空行
+func profileDetailLines(profilesDir, name string) []string {
+	var buf bytes.Buffer
+	if err := WriteProfileDetails(&buf, profilesDir, name); err != nil {
+		return []string{" " + err.Error()}
+	}
+	return strings.Split(strings.Trim(buf.String(), "\n"), "\n")
+}
+
+// ShowMenuTUI 显示全屏菜单，使用方向键选择配置，单键执行操作
+// 无法进入原始模式时退回逐行输入的菜单
+func ShowMenuTUI(profilesDir string) (MenuAction, string, error) {
+	entries, err := loadMenuEntries(profilesDir)
+	if err != nil {
+		return ActionNone, "", err
+	}
+	active, _ := GetActiveProfile()
+
+	t, err := tty.Open(os.Stdin, os.Stdout)
+	if err != nil {
+		return ShowMenuLine(profilesDir)
+	}
+
+	m := &menuModel{entries: entries, active: active, now: nowFunc()}
+	cache := make(map[string][]string)
+	m.details = func(name string) []string {
+		if _, ok := cache[name]; !ok {
+			cache[name] = profileDetailLines(profilesDir, name)
+		}
+		return cache[name]
+	}
+
+	action, name, err := runMenuTUI(t, m)
+	if closeErr := t.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return ActionNone, "", err
+	}
+
+	// 创建配置需要输入名称，在恢复终端后以逐行方式读取
+	if action == ActionCreate {
+		profiles := make([]*profile.Profile, len(entries))
+		for i, e := range entries {
+			profiles[i] = e.Profile
+		}
+		name, err = promptConfigName(profiles)
+		if err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return ActionNone, "", nil
+		}
+	}
+	return action, name, nil
+}
+
+// runMenuTUI 重绘屏幕并处理按键，直到选择了操作
+// 没有按键时定期检查窗口大小，变化后重绘
+func runMenuTUI(t *tty.Terminal, m *menuModel) (MenuAction, string, error) {
+	lastWidth, lastHeight := 0, 0
+	dirty := true
+	for {
+		width, height := t.Size()
+		if width != lastWidth || height != lastHeight {
+			lastWidth, lastHeight = width, height
+			dirty = true
+		}
+		if dirty {
+			t.Draw(m.render(width, height))
+			dirty = false
+		}
+
+		keys, err := t.ReadKeys(tuiPollInterval)
+		if err != nil {
+			return ActionNone, "", err
+		}
+		for _, k := range keys {
+			dirty = true
+			if action, name, done := m.handleKey(k); done {
+				return action, name, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/tty"
+)
+
+func newTestMenuModel(names ...string) *menuModel {
+	m := &menuModel{}
+	for _, name := range names {
+		m.entries = append(m.entries, menuEntry{Name: name, Profile: &profile.Profile{Name: strings.ToUpper(name)}})
+	}
+	return m
+}
+
+func runeKeys(s string) []tty.Key {
+	var keys []tty.Key
+	for _, r := range s {
+		keys = append(keys, tty.Key{Code: tty.KeyRune, Rune: r})
+	}
+	return keys
+}
+
+func TestMenuModelNavigation(t *testing.T) {
+	m := newTestMenuModel("alpha", "beta", "gamma")
+
+	for _, k := range []tty.Key{{Code: tty.KeyDown}, {Code: tty.KeyDown}, {Code: tty.KeyDown}} {
+		m.handleKey(k)
+	}
+	if m.cursor != 2 {
+		t.Errorf("cursor = %d, want 2 (clamped at end)", m.cursor)
+	}
+	m.handleKey(tty.Key{Code: tty.KeyRune, Rune: 'k'})
+	if m.cursor != 1 {
+		t.Errorf("cursor = %d after k, want 1", m.cursor)
+	}
+
+	action, name, done := m.handleKey(tty.Key{Code: tty.KeyEnter})
+	if !done || action != ActionRun || name != "beta" {
+		t.Errorf("Enter = %v, %q, %v, want run beta", action, name, done)
+	}
+
+	tests := map[rune]MenuAction{
+		'e': ActionEdit,
+		'd': ActionDelete,
+		'v': ActionShowDetails,
+		'x': ActionExport,
+		'r': ActionRun,
+		'm': ActionRename,
+		'y': ActionCopy,
+		'f': ActionDiff,
+		'a': ActionValidate,
+		's': ActionSync,
+	}
+	for r, want := range tests {
+		if action, name, done := m.handleKey(tty.Key{Code: tty.KeyRune, Rune: r}); !done || action != want || name != "beta" {
+			t.Errorf("key %c = %v, %q, %v, want %v beta", r, action, name, done, want)
+		}
+	}
+
+	if action, _, done := m.handleKey(tty.Key{Code: tty.KeyRune, Rune: 'c'}); !done || action != ActionCreate {
+		t.Errorf("c = %v, want create", action)
+	}
+	if action, _, done := m.handleKey(tty.Key{Code: tty.KeyRune, Rune: 'o'}); !done || action != ActionImport {
+		t.Errorf("o = %v, want import", action)
+	}
+	if action, _, done := m.handleKey(tty.Key{Code: tty.KeyRune, Rune: 'q'}); !done || action != ActionQuit {
+		t.Errorf("q = %v, want quit", action)
+	}
+}
+
+func TestMenuModelFilter(t *testing.T) {
+	m := newTestMenuModel("work", "home", "work-backup")
+
+	m.handleKey(tty.Key{Code: tty.KeyRune, Rune: '/'})
+	for _, k := range runeKeys("wor") {
+		if _, _, done := m.handleKey(k); done {
+			t.Fatal("typing a filter should not select an action")
+		}
+	}
+	if got := len(m.visible()); got != 2 {
+		t.Fatalf("visible = %d, want 2", got)
+	}
+
+	// 过滤时方向键仍可移动，Enter 结束输入
+	m.handleKey(tty.Key{Code: tty.KeyDown})
+	m.handleKey(tty.Key{Code: tty.KeyEnter})
+	if m.filtering {
+		t.Error("Enter should finish filtering")
+	}
+	if _, name, _ := m.handleKey(tty.Key{Code: tty.KeyRune, Rune: 'e'}); name != "work-backup" {
+		t.Errorf("selected %q, want work-backup", name)
+	}
+
+	// 按显示名称匹配，不区分大小写
+	m.handleKey(tty.Key{Code: tty.KeyEsc})
+	m.handleKey(tty.Key{Code: tty.KeyRune, Rune: '/'})
+	for _, k := range runeKeys("HOM") {
+		m.handleKey(k)
+	}
+	if v := m.visible(); len(v) != 1 || v[0].Name != "home" {
+		t.Errorf("visible = %v, want [home]", v)
+	}
+
+	// 没有匹配时不能执行操作
+	m.handleKey(tty.Key{Code: tty.KeyRune, Rune: 'x'})
+	m.handleKey(tty.Key{Code: tty.KeyEnter})
+	if _, _, done := m.handleKey(tty.Key{Code: tty.KeyEnter}); done {
+		t.Error("Enter with no matches should not select")
+	}
+	if m.message == "" {
+		t.Error("expected a message when nothing is selected")
+	}
+
+	// Esc 先清除过滤条件，再次按下才退出
+	if _, _, done := m.handleKey(tty.Key{Code: tty.KeyEsc}); done || m.filter != "" {
+		t.Errorf("first Esc should clear filter, filter = %q", m.filter)
+	}
+	if action, _, done := m.handleKey(tty.Key{Code: tty.KeyEsc}); !done || action != ActionQuit {
+		t.Error("second Esc should quit")
+	}
+}
+
+func TestMenuModelRender(t *testing.T) {
+	var names []string
+	for _, c := range "abcdefghijklmnopqrst" {
+		names = append(names, "profile-"+string(c))
+	}
+	m := newTestMenuModel(names...)
+	m.active = "profile-b"
+	m.details = func(name string) []string { return []string{"=== 配置详情: " + name + " ==="} }
+
+	m.cursor = 15
+	lines := m.render(40, 20)
+	if len(lines) != 20 {
+		t.Fatalf("render returned %d lines, want 20", len(lines))
+	}
+	for i, line := range lines {
+		if w := tty.Width(strings.TrimSuffix(strings.TrimPrefix(line, "\x1b[7m"), "\x1b[0m")); w > 40 {
+			t.Errorf("line %d is %d columns wide: %q", i, w, line)
+		}
+	}
+
+	screen := strings.Join(lines, "\n")
+	if !strings.Contains(screen, "> ") || !strings.Contains(screen, "profile-p") {
+		t.Error("list should scroll to keep the cursor visible")
+	}
+	if strings.Contains(screen, "profile-a") {
+		t.Error("list should be scrolled past the first entry")
+	}
+	if !strings.Contains(screen, "配置详情: profile-p") {
+		t.Error("detail pane should show the highlighted profile")
+	}
+
+	// 窗口缩小后重新计算布局
+	m.cursor = 1
+	lines = m.render(30, 6)
+	if len(lines) != 6 {
+		t.Fatalf("render returned %d lines, want 6", len(lines))
+	}
+	if screen := strings.Join(lines, "\n"); !strings.Contains(screen, "* profile-b") {
+		t.Errorf("active profile should be marked:\n%s", screen)
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fiftyk/claude-switcher/internal/update"
+)
+
+// updateRepo 更新仓库配置
+const updateRepo = "fiftyk/claude-switcher"
+
+// CheckForUpdates 检查是否有新版本
+func CheckForUpdates() error {
+	currentVersion := update.ParseVersion(buildInfo.Version)
+
+	// 检查是否为开发版本
+	if !currentVersion.IsValid() {
+		fmt.Println("当前版本: 开发版")
+		fmt.Println("无法检查更新（开发版本无法比较）")
+		fmt.Println("请从 Release 下载正式版本以使用自动更新功能")
+		return nil
+	}
+
+	fmt.Printf("当前版本: %s\n", currentVersion.String())
+	fmt.Println("检查更新...")
+
+	result, err := update.CheckUpdate(updateRepo, currentVersion)
+	if err != nil {
+		return err
+	}
+
+	if result.HasUpdate {
+		fmt.Printf("\n发现新版本: %s\n", result.Latest.String())
+		fmt.Printf("更新日志: %s\n", result.ChangelogURL)
+		fmt.Println("\n运行 'claude-switcher update' 进行更新")
+	} else {
+		fmt.Println("当前已是最新版本")
+	}
+
+	// 更新检查时间
+	saveUpdateCheckTime()
+	return nil
+}
+
+// SelfUpdate 执行自更新
+func SelfUpdate() error {
+	currentVersion := update.ParseVersion(buildInfo.Version)
+
+	// 检查是否为开发版本
+	if !currentVersion.IsValid() {
+		fmt.Println("当前版本: 开发版")
+		fmt.Println("无法自动更新（开发版本无法比较）")
+		fmt.Println("请从 Release 下载正式版本以使用自动更新功能")
+		return nil
+	}
+
+	fmt.Printf("当前版本: %s\n", currentVersion.String())
+	fmt.Println("检查更新...")
+
+	result, err := update.CheckUpdate(updateRepo, currentVersion)
+	if err != nil {
+		return err
+	}
+
+	if !result.HasUpdate {
+		fmt.Println("当前已是最新版本，无需更新")
+		return nil
+	}
+
+	fmt.Printf("\n发现新版本: %s\n", result.Latest.String())
+	fmt.Printf("更新日志: %s\n", result.ChangelogURL)
+	fmt.Println("")
+
+	// 询问用户确认
+	fmt.Print("是否更新? (y/N): ")
+	input, _ := stdinReader.ReadString('\n')
+	input = strings.TrimSpace(input)
+
+	if input != "y" && input != "Y" {
+		fmt.Println("已取消更新")
+		return nil
+	}
+
+	// 执行更新
+	installPath := update.GetInstallPath()
+	fmt.Printf("\n下载并安装新版本...")
+
+	if err := update.DownloadAndInstall(result.DownloadURL, installPath); err != nil {
+		fmt.Println()
+		return fmt.Errorf("更新失败: %w", err)
+	}
+
+	fmt.Println("完成!")
+	fmt.Printf("\n已更新到 %s。运行 'claude-switcher version' 确认新版本。\n", result.Latest.String())
+
+	// 更新检查时间
+	saveUpdateCheckTime()
+	return nil
+}
+
+// saveUpdateCheckTime 保存更新检查时间
+func saveUpdateCheckTime() {
+	configPath := update.GetConfigPath()
+
+	cfg, err := update.LoadCheckConfig(configPath)
+	if err != nil {
+		// 如果配置文件不存在，创建新的
+		cfg = update.GetDefaultConfig(updateRepo)
+	}
+
+	cfg.LastCheck = update.Now()
+	if err := cfg.Save(configPath); err != nil {
+		// 静默失败，不影响主要功能
+		_ = err
+	}
+}
@@ -1,13 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/geoip"
+	"github.com/fiftyk/claude-switcher/internal/probe"
 	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/proxy"
+	"github.com/fiftyk/claude-switcher/internal/secret"
 )
 
 // ValidationResult 验证结果
@@ -31,8 +37,9 @@ func ValidateProfile(p *profile.Profile) *ValidationResult {
 		result.Errors = append(result.Errors, "配置名称不能为空")
 	}
 
+	// 密钥引用在启动时才解析，不检查其格式
 	// 验证 Base URL
-	if p.BaseURL != "" {
+	if p.BaseURL != "" && !secret.IsRef(p.BaseURL) {
 		if !config.ValidateURL(p.BaseURL) {
 			result.Valid = false
 			result.Errors = append(result.Errors, fmt.Sprintf("Base URL 格式无效: %s", p.BaseURL))
@@ -40,29 +47,65 @@ func ValidateProfile(p *profile.Profile) *ValidationResult {
 	}
 
 	// 验证代理
-	if p.HTTPProxy != "" {
+	if p.HTTPProxy != "" && !secret.IsRef(p.HTTPProxy) {
 		if !config.ValidateProxy(p.HTTPProxy) {
 			result.Valid = false
-			result.Errors = append(result.Errors, fmt.Sprintf("HTTP Proxy 格式无效: %s", p.HTTPProxy))
+			result.Errors = append(result.Errors, fmt.Sprintf("HTTP Proxy 格式无效: %s", proxy.Mask(p.HTTPProxy)))
+		}
+	}
+	if p.HTTPSProxy != "" && p.HTTPSProxy != p.HTTPProxy && !secret.IsRef(p.HTTPSProxy) {
+		if !config.ValidateProxy(p.HTTPSProxy) {
+			result.Valid = false
+			result.Errors = append(result.Errors, fmt.Sprintf("HTTPS Proxy 格式无效: %s", proxy.Mask(p.HTTPSProxy)))
 		}
 	}
 
 	// 验证 Auth Token 格式（如果是提供的）
-	if p.AuthToken != "" && !strings.HasPrefix(p.AuthToken, "sk-") {
+	if p.AuthToken != "" && !strings.HasPrefix(p.AuthToken, "sk-") && !secret.IsRef(p.AuthToken) {
 		result.Warnings = append(result.Warnings, "Auth Token 可能不是有效的 Anthropic API Token")
 	}
 
+	// 验证协议
+	switch p.Protocol {
+	case "", profile.ProtocolAnthropic:
+	case profile.ProtocolOpenAI:
+		if p.Model == "" {
+			result.Warnings = append(result.Warnings, "OpenAI 协议需要通过 ANTHROPIC_MODEL 指定上游模型名称")
+		}
+	default:
+		result.Valid = false
+		result.Errors = append(result.Errors, fmt.Sprintf("不支持的协议: %s（可选: anthropic、openai）", p.Protocol))
+	}
+
 	// 检查 Model 是否为空（如果是提供的）
-	if p.Model != "" {
+	if p.Model != "" && !secret.IsRef(p.Model) {
 		// 简单检查 model 名称格式
 		if len(p.Model) < 5 {
 			result.Warnings = append(result.Warnings, fmt.Sprintf("Model 名称可能无效: %s", p.Model))
 		}
 	}
 
+	// 检查 token 是否已过期
+	if days, ok := p.DaysUntilExpiry(nowFunc()); ok && days < 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("token 已于 %s 过期", profile.FormatExpiry(p.ExpiresAt)))
+	}
+
 	return result
 }
 
+// checkExpiryFormat 检查配置文件中的 _EXPIRES_AT 格式，无效的值在解析时会被忽略
+func checkExpiryFormat(profilesDir, name string, result *ValidationResult) {
+	data, err := profile.ReadFile(profilesDir, name)
+	if err != nil {
+		return
+	}
+	if value, ok := profile.ParseDocument(data).Get("_EXPIRES_AT"); ok && value != "" {
+		if _, err := profile.ParseExpiry(value); err != nil {
+			result.Warnings = append(result.Warnings, err.Error())
+		}
+	}
+}
+
 // FormatValidationResult 格式化验证结果
 func FormatValidationResult(result *ValidationResult) string {
 	var sb strings.Builder
@@ -90,11 +133,61 @@ func FormatValidationResult(result *ValidationResult) string {
 type ConnectivityResult struct {
 	Reachable  bool
 	Latency    time.Duration
+	Timing     probe.Timing
 	Message    string
 	Error      error
 }
 
+// connectivityTimeout 连通性检查的超时时间
+const connectivityTimeout = 10 * time.Second
+
+// profileProxyConfig 返回配置使用的代理设置
+// NO_PROXY 优先取配置中的 no_proxy，否则沿用当前环境，与启动 claude 时的行为一致
+func profileProxyConfig(p *profile.Profile) proxy.Config {
+	cfg := proxy.Config{
+		HTTPProxy:  p.HTTPProxy,
+		HTTPSProxy: p.HTTPSProxy,
+		NoProxy:    p.NoProxy,
+	}
+	if cfg.NoProxy != "" {
+		return cfg
+	}
+	for _, key := range []string{"NO_PROXY", "no_proxy"} {
+		if v := os.Getenv(key); v != "" {
+			cfg.NoProxy = v
+			break
+		}
+	}
+	return cfg
+}
+
+// timedGet 使用指定代理发起 GET 请求并记录各阶段耗时
+func timedGet(cfg proxy.Config, rawURL string) (int, probe.Timing, error) {
+	transport, err := proxy.NewTransport(cfg)
+	if err != nil {
+		return 0, probe.Timing{}, err
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Timeout:   connectivityTimeout,
+		Transport: transport,
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, probe.Timing{}, err
+	}
+	req, tracer := probe.Trace(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, tracer.Done(), err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, tracer.Done(), nil
+}
+
 // CheckConnectivity 检查 API 连通性
+// 请求经过配置的 http_proxy/https_proxy，命中 NO_PROXY 的地址直接连接
 func CheckConnectivity(p *profile.Profile) *ConnectivityResult {
 	result := &ConnectivityResult{
 		Reachable: false,
@@ -113,83 +206,101 @@ func CheckConnectivity(p *profile.Profile) *ConnectivityResult {
 	}
 	checkURL += "health"
 
-	// 创建 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	// 设置代理
-	if p.HTTPProxy != "" {
-		// 注意：这里需要代理支持 HTTPS CONNECT
-	}
-
-	// 尝试请求
-	start := time.Now()
-	resp, err := client.Get(checkURL)
-	latency := time.Since(start)
+	status, timing, err := timedGet(profileProxyConfig(p), checkURL)
+	result.Timing = timing
+	result.Latency = timing.Total
 
 	if err != nil {
 		result.Error = err
 		result.Message = fmt.Sprintf("连接失败: %v", err)
 		return result
 	}
-	defer resp.Body.Close()
 
-	result.Latency = latency
-	if resp.StatusCode == 200 || resp.StatusCode == 404 {
+	if status == 200 || status == 404 {
 		result.Reachable = true
-		result.Message = fmt.Sprintf("可达 (耗时 %v)", latency)
+		result.Message = fmt.Sprintf("可达 (耗时 %v)", result.Latency)
 	} else {
-		result.Message = fmt.Sprintf("返回状态码: %d", resp.StatusCode)
+		result.Message = fmt.Sprintf("返回状态码: %d", status)
 	}
 
 	return result
 }
 
 // CheckProxyConnectivity 检查代理连通性
+// 强制经过代理访问测试地址，不受 NO_PROXY 影响
 func CheckProxyConnectivity(p *profile.Profile) *ConnectivityResult {
 	result := &ConnectivityResult{
 		Reachable: false,
 		Message:   "未测试",
 	}
 
-	if p.HTTPProxy == "" {
+	if p.HTTPProxy == "" && p.HTTPSProxy == "" {
 		result.Message = "未配置代理"
 		return result
 	}
 
-	// 创建 HTTP 客户端，使用代理
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
 	// 测试代理连通性（尝试访问百度）
 	testURL := "https://www.baidu.com"
 
-	start := time.Now()
-	resp, err := client.Get(testURL)
-	latency := time.Since(start)
+	cfg := profileProxyConfig(p)
+	cfg.NoProxy = ""
+	status, timing, err := timedGet(cfg, testURL)
+	result.Timing = timing
+	result.Latency = timing.Total
 
 	if err != nil {
 		result.Error = err
 		result.Message = fmt.Sprintf("代理连接失败: %v", err)
 		return result
 	}
-	defer resp.Body.Close()
 
-	result.Latency = latency
-	if resp.StatusCode == 200 {
+	if status == 200 {
 		result.Reachable = true
-		result.Message = fmt.Sprintf("代理可达 (耗时 %v)", latency)
+		result.Message = fmt.Sprintf("代理可达 (耗时 %v)", result.Latency)
 	} else {
-		result.Message = fmt.Sprintf("返回状态码: %d", resp.StatusCode)
+		result.Message = fmt.Sprintf("返回状态码: %d", status)
 	}
 
 	return result
 }
 
+// ValidateOptions 控制验证报告包含的检查
+type ValidateOptions struct {
+	Deep   bool // 发送真实的 Messages API 请求，检查密钥、模型与额度
+	Stream bool // 深度检查使用流式请求
+}
+
+// ProbeMessages 使用配置的密钥与模型发送一个最小的 Messages API 请求
+// openai 协议的配置通过本地协议转换代理发送
+func ProbeMessages(name string, p *profile.Profile, stream bool) (*probe.MessagesResult, error) {
+	target := p
+	if p.Protocol == profile.ProtocolOpenAI {
+		local, stop, err := startTranslator(name, p)
+		if err != nil {
+			return nil, err
+		}
+		defer stop()
+		target = local
+	}
+
+	transport, err := proxy.NewTransport(profileProxyConfig(target))
+	if err != nil {
+		return nil, err
+	}
+	defer transport.CloseIdleConnections()
+
+	return probe.Messages(context.Background(), probe.MessagesOptions{
+		BaseURL:   target.BaseURL,
+		AuthToken: target.AuthToken,
+		APIKey:    target.EnvVars["ANTHROPIC_API_KEY"],
+		Model:     target.Model,
+		Stream:    stream,
+		Transport: transport,
+	}), nil
+}
+
 // PrintValidationReport 打印完整验证报告
-func PrintValidationReport(profilesDir, profileName string) error {
+func PrintValidationReport(profilesDir, profileName string, opts ValidateOptions) error {
 	p, err := profile.LoadProfile(profilesDir, profileName)
 	if err != nil {
 		return fmt.Errorf("无法加载配置: %w", err)
@@ -201,8 +312,16 @@ func PrintValidationReport(profilesDir, profileName string) error {
 	fmt.Println("格式验证:")
 	fmt.Println(strings.Repeat("-", 40))
 	validation := ValidateProfile(p)
+	checkExpiryFormat(profilesDir, profileName, validation)
 	fmt.Print(FormatValidationResult(validation))
 
+	// 以下检查使用解析了密钥引用的配置
+	p, err = profile.Resolve(p)
+	if err != nil {
+		fmt.Printf("\n✗ %v\n\n", err)
+		return nil
+	}
+
 	// 连通性检查
 	fmt.Println("\nAPI 连通性:")
 	fmt.Println(strings.Repeat("-", 40))
@@ -212,9 +331,39 @@ func PrintValidationReport(profilesDir, profileName string) error {
 	} else {
 		fmt.Printf("  ✗ API 不可达 %s\n", conn.Message)
 	}
+	printTiming(conn.Timing)
+
+	// 出口 IP，只在设置了 ALLOWED_COUNTRIES 时查询，避免每次验证都访问第三方服务
+	if len(p.AllowedCountries) > 0 {
+		fmt.Println("\n出口 IP:")
+		fmt.Println(strings.Repeat("-", 40))
+		if info, err := exitIPFunc(p); err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+		} else if !geoip.Allowed(info.CountryCode, p.AllowedCountries) {
+			fmt.Printf("  ✗ %s (%s)，不在允许列表 %s 中\n", info.IP, info.Location(), strings.Join(p.AllowedCountries, ","))
+		} else {
+			fmt.Printf("  ✓ %s (%s)\n", info.IP, info.Location())
+		}
+	}
+
+	// 深度检查
+	if opts.Deep {
+		fmt.Println("\nMessages API:")
+		fmt.Println(strings.Repeat("-", 40))
+		deep, err := ProbeMessages(profileName, p, opts.Stream)
+		if err != nil {
+			return err
+		}
+		if deep.Status == probe.StatusOK {
+			fmt.Printf("  ✓ %s\n", deep.Message)
+		} else {
+			fmt.Printf("  ✗ %s: %s\n", deep.Status.Label(), deep.Message)
+		}
+		printTiming(deep.Timing)
+	}
 
 	// 代理检查
-	if p.HTTPProxy != "" {
+	if p.HTTPProxy != "" || p.HTTPSProxy != "" {
 		fmt.Println("\n代理连通性:")
 		fmt.Println(strings.Repeat("-", 40))
 		proxyConn := CheckProxyConnectivity(p)
@@ -223,16 +372,26 @@ func PrintValidationReport(profilesDir, profileName string) error {
 		} else {
 			fmt.Printf("  ✗ 代理不可达 %s\n", proxyConn.Message)
 		}
+		printTiming(proxyConn.Timing)
 	}
 
 	fmt.Println()
 	return nil
 }
 
+// printTiming 打印请求各阶段耗时
+func printTiming(t probe.Timing) {
+	if s := t.String(); s != "" {
+		fmt.Printf("    %s\n", s)
+	}
+}
+
 // PrintValidateHelp 打印验证帮助信息
 func PrintValidateHelp() {
 	fmt.Println("\n配置验证用法:")
 	fmt.Println("  claude-switcher --validate <配置名>   验证配置格式")
 	fmt.Println("  claude-switcher --test <配置名>       验证配置并测试连通性")
+	fmt.Println("  claude-switcher validate --deep [--stream] <配置名>")
+	fmt.Println("                                        发送真实请求检查密钥、模型与额度")
 	fmt.Println()
 }
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/tty"
+	"github.com/fiftyk/claude-switcher/internal/vault"
+)
+
+// readPassphraseFunc 不回显地读取口令或 token，可被测试 mock
+var readPassphraseFunc = readPassphrase
+
+// vaultKeys 本进程中已解锁的数据密钥（按 profiles 目录），一次运行中只需输入一次口令
+// 网关会在多个请求的 goroutine 中读取配置，访问时需持有 vaultMu
+var (
+	vaultMu   sync.Mutex
+	vaultKeys = make(map[string][]byte)
+)
+
+// passphraseAttempts 解锁时允许输入口令的次数
+const passphraseAttempts = 3
+
+// newVaultCommand 管理加密存储
+func newVaultCommand() *Command {
+	c := newCommand("vault", "[status] | init | lock | decrypt | change-passphrase", "管理配置的加密存储")
+	c.Long = `加密存储使用主口令保护配置中的 token:
+  init               设置主口令，并加密现有的全部配置
+  lock               加密所有明文配置，之后新建和修改的配置也加密保存
+  decrypt            将加密的配置解密后以明文 .conf 文件写回磁盘，并关闭加密
+  change-passphrase  修改主口令（配置文件无需重新加密）
+  status             显示加密状态
+
+加密的配置保存为 <配置名>.conf.enc，口令经 scrypt 派生密钥，内容使用 AES-256-GCM 加密。
+读取加密配置时会提示输入主口令；主口令遗忘后无法恢复配置。`
+	c.Run = func(args []string) error {
+		profilesDir := config.GetProfilesDir()
+
+		if len(args) == 0 || (len(args) == 1 && args[0] == "status") {
+			return PrintVaultStatus(profilesDir)
+		}
+		if len(args) != 1 {
+			return c.usageError()
+		}
+
+		switch args[0] {
+		case "init":
+			return InitVault(profilesDir)
+		case "lock":
+			return LockVault(profilesDir)
+		case "decrypt":
+			return DecryptVault(profilesDir)
+		case "change-passphrase":
+			return ChangeVaultPassphrase(profilesDir)
+		}
+		return c.usageError()
+	}
+	return c
+}
+
+// InitVault 设置主口令并加密现有配置
+func InitVault(profilesDir string) error {
+	if vault.Exists(profilesDir) {
+		return fmt.Errorf("加密存储已初始化，修改口令请使用 'claude-switcher vault change-passphrase'")
+	}
+
+	passphrase, err := readNewPassphrase()
+	if err != nil {
+		return err
+	}
+	v, key, err := vault.Create(passphrase)
+	if err != nil {
+		return err
+	}
+
+	// 先保存元数据再加密文件，中途失败时已加密的配置仍可用口令读取
+	if err := v.Save(profilesDir); err != nil {
+		return err
+	}
+	vaultMu.Lock()
+	vaultKeys[profilesDir] = key
+	vaultMu.Unlock()
+
+	n, err := profile.EncryptAll(profilesDir, vault.Key(key))
+	if err != nil {
+		return fmt.Errorf("加密配置失败: %w", err)
+	}
+	v.Locked = true
+	if err := v.Save(profilesDir); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ 已初始化加密存储，加密了 %d 个配置\n", n)
+	fmt.Println("请牢记主口令，遗忘后无法恢复配置")
+	return nil
+}
+
+// LockVault 加密所有明文配置，之后保存的配置也加密
+func LockVault(profilesDir string) error {
+	v, err := vault.Load(profilesDir)
+	if err != nil {
+		return err
+	}
+	c, err := vaultCipher(profilesDir)
+	if err != nil {
+		return err
+	}
+
+	n, err := profile.EncryptAll(profilesDir, c)
+	if err != nil {
+		return fmt.Errorf("加密配置失败: %w", err)
+	}
+	v.Locked = true
+	if err := v.Save(profilesDir); err != nil {
+		return err
+	}
+	fmt.Printf("✓ 已锁定，加密了 %d 个配置\n", n)
+	return nil
+}
+
+// DecryptVault 将加密的配置解密为明文文件写回磁盘，之后保存的配置不再加密
+func DecryptVault(profilesDir string) error {
+	v, err := vault.Load(profilesDir)
+	if err != nil {
+		return err
+	}
+	c, err := vaultCipher(profilesDir)
+	if err != nil {
+		return err
+	}
+
+	n, err := profile.DecryptAll(profilesDir, c)
+	if err != nil {
+		return fmt.Errorf("解密配置失败: %w", err)
+	}
+	v.Locked = false
+	if err := v.Save(profilesDir); err != nil {
+		return err
+	}
+	fmt.Printf("✓ 已解密，%d 个配置已以明文保存到磁盘\n", n)
+	fmt.Println("使用 'claude-switcher vault lock' 重新加密")
+	return nil
+}
+
+// ChangeVaultPassphrase 修改主口令
+func ChangeVaultPassphrase(profilesDir string) error {
+	v, err := vault.Load(profilesDir)
+	if err != nil {
+		return err
+	}
+
+	old, err := readPassphraseFunc("当前主口令: ")
+	if err != nil {
+		return err
+	}
+	if _, err := v.Unlock(old); err != nil {
+		return err
+	}
+	passphrase, err := readNewPassphrase()
+	if err != nil {
+		return err
+	}
+	if err := v.ChangePassphrase(old, passphrase); err != nil {
+		return err
+	}
+	if err := v.Save(profilesDir); err != nil {
+		return err
+	}
+	fmt.Println("✓ 主口令已修改")
+	return nil
+}
+
+// PrintVaultStatus 显示加密存储状态
+func PrintVaultStatus(profilesDir string) error {
+	v, err := vault.Load(profilesDir)
+	if errors.Is(err, vault.ErrNotInitialized) {
+		fmt.Println("未启用加密存储，使用 'claude-switcher vault init' 启用")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	names, err := profile.ListProfiles(profilesDir)
+	if err != nil {
+		return err
+	}
+	encrypted := 0
+	for _, name := range names {
+		if profile.IsEncrypted(profilesDir, name) {
+			encrypted++
+		}
+	}
+
+	if v.Locked {
+		fmt.Println("加密存储: 已锁定（新建和修改的配置加密保存）")
+	} else {
+		fmt.Println("加密存储: 已解锁（配置以明文保存）")
+	}
+	fmt.Printf("配置: %d 个加密，%d 个明文\n", encrypted, len(names)-encrypted)
+	return nil
+}
+
+// vaultCipher 返回读写加密配置使用的 Cipher，作为 profile.CipherSource
+// 本进程已解锁时使用缓存的密钥，其次使用 agent，最后提示输入主口令
+func vaultCipher(profilesDir string) (profile.Cipher, error) {
+	vaultMu.Lock()
+	key, ok := vaultKeys[profilesDir]
+	vaultMu.Unlock()
+	if ok {
+		return vault.Key(key), nil
+	}
+	if c, ok := agentCipher(profilesDir); ok {
+		return c, nil
+	}
+	key, err := unlockVaultKey(profilesDir)
+	if err != nil {
+		return nil, err
+	}
+	return vault.Key(key), nil
+}
+
+// unlockVaultKey 提示输入主口令并解出数据密钥
+// 输入口令期间持有锁，并发读取配置时只提示一次
+func unlockVaultKey(profilesDir string) ([]byte, error) {
+	vaultMu.Lock()
+	defer vaultMu.Unlock()
+	if key, ok := vaultKeys[profilesDir]; ok {
+		return key, nil
+	}
+	v, err := vault.Load(profilesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		passphrase, err := readPassphraseFunc("请输入主口令: ")
+		if err != nil {
+			return nil, err
+		}
+		key, err := v.Unlock(passphrase)
+		if err == nil {
+			vaultKeys[profilesDir] = key
+			return key, nil
+		}
+		if !errors.Is(err, vault.ErrWrongPassphrase) || attempt >= passphraseAttempts {
+			return nil, err
+		}
+		fmt.Fprintln(os.Stderr, "口令错误，请重试")
+	}
+}
+
+// readNewPassphrase 读取两次新口令并确认一致
+func readNewPassphrase() (string, error) {
+	passphrase, err := readPassphraseFunc("设置主口令: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("口令不能为空")
+	}
+	again, err := readPassphraseFunc("再次输入主口令: ")
+	if err != nil {
+		return "", err
+	}
+	if again != passphrase {
+		return "", fmt.Errorf("两次输入的口令不一致")
+	}
+	return passphrase, nil
+}
+
+// readPassphrase 在终端中不回显地读取口令，标准输入不是终端时读取一行
+// 提示输出到标准错误，避免混入 env 等命令的输出
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if tty.IsTerminal(os.Stdin) {
+		passphrase, err := tty.ReadPassword(os.Stdin)
+		fmt.Fprintln(os.Stderr)
+		return passphrase, err
+	}
+
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("无法读取口令: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
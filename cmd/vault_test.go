@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/vault"
+)
+
+// stubPassphrase 依次返回 inputs 作为输入的口令，并清空进程内缓存的密钥
+// 与 Execute 相同，读写加密配置时使用 vaultCipher
+func stubPassphrase(t *testing.T, inputs ...string) {
+	t.Helper()
+	origParams, origRead := vault.DefaultParams, readPassphraseFunc
+	vault.DefaultParams = vault.Params{N: 1 << 10, R: 8, P: 1}
+	readPassphraseFunc = func(prompt string) (string, error) {
+		if len(inputs) == 0 {
+			t.Fatalf("unexpected passphrase prompt: %s", prompt)
+		}
+		s := inputs[0]
+		inputs = inputs[1:]
+		return s, nil
+	}
+	vaultKeys = make(map[string][]byte)
+	origSource := profile.CipherSource
+	profile.CipherSource = vaultCipher
+	t.Cleanup(func() {
+		vault.DefaultParams, readPassphraseFunc = origParams, origRead
+		vaultKeys = make(map[string][]byte)
+		profile.CipherSource = origSource
+	})
+}
+
+func TestVaultCommands(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{Name: "work", AuthToken: "sk-work"})
+	encrypted := filepath.Join(profilesDir, "work"+profile.EncryptedExt)
+
+	stubPassphrase(t, "pw", "mismatch")
+	if err := Execute([]string{"vault", "init"}, BuildInfo{}); err == nil {
+		t.Fatal("expected error when passphrases do not match")
+	}
+
+	stubPassphrase(t, "pw", "pw")
+	if err := Execute([]string{"vault", "init"}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(encrypted); err != nil {
+		t.Fatalf("profile should be encrypted after init: %v", err)
+	}
+
+	// 新进程读取加密配置时提示输入口令，输错后可以重试
+	stubPassphrase(t, "wrong", "pw")
+	p, err := profile.LoadProfile(profilesDir, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AuthToken != "sk-work" {
+		t.Errorf("AuthToken = %q, want sk-work", p.AuthToken)
+	}
+
+	stubPassphrase(t, "pw", "new", "new")
+	if err := Execute([]string{"vault", "change-passphrase"}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// unlock 已改名为 decrypt，避免误以为只是临时解锁
+	if err := Execute([]string{"vault", "unlock"}, BuildInfo{}); err == nil {
+		t.Error("vault unlock should no longer be accepted")
+	}
+
+	stubPassphrase(t, "new")
+	if err := Execute([]string{"vault", "decrypt"}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(encrypted); !os.IsNotExist(err) {
+		t.Error("encrypted file should be removed after decrypt")
+	}
+	if p, err := profile.LoadProfile(profilesDir, "work"); err != nil || p.AuthToken != "sk-work" {
+		t.Errorf("LoadProfile after decrypt = %+v, %v", p, err)
+	}
+
+	stubPassphrase(t, "new")
+	if err := Execute([]string{"vault", "lock"}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(encrypted); err != nil {
+		t.Errorf("profile should be encrypted after lock: %v", err)
+	}
+}
+
+func TestVaultCipherConcurrentPromptsOnce(t *testing.T) {
+	profilesDir := setupTestHome(t)
+	writeTestProfile(t, profilesDir, "work", &profile.Profile{Name: "work", AuthToken: "sk-work"})
+	stubPassphrase(t, "pw", "pw")
+	if err := Execute([]string{"vault", "init"}, BuildInfo{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟新进程：网关的多个请求同时读取加密配置
+	stubPassphrase(t)
+	var prompts atomic.Int32
+	readPassphraseFunc = func(string) (string, error) {
+		prompts.Add(1)
+		return "pw", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if p, err := profile.LoadProfile(profilesDir, "work"); err != nil || p.AuthToken != "sk-work" {
+				t.Errorf("LoadProfile() = %+v, %v", p, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := prompts.Load(); n != 1 {
+		t.Errorf("passphrase prompted %d times, want 1", n)
+	}
+
+	// 网关启动前解锁，之后处理请求时不再提示
+	vaultKeys = make(map[string][]byte)
+	prompts.Store(0)
+	if err := unlockForGateway(profilesDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadResolvedProfile(profilesDir, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if n := prompts.Load(); n != 1 {
+		t.Errorf("passphrase prompted %d times, want 1", n)
+	}
+}
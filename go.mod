@@ -2,4 +2,10 @@ module github.com/fiftyk/claude-switcher
 
 go 1.23.2
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	golang.org/x/sys v0.33.0
+	golang.org/x/term v0.32.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/crypto v0.39.0
@@ -0,0 +1,245 @@
+// Package agent 实现在内存中保存加密存储数据密钥的后台服务
+//
+// 服务通过权限为 0600 的 Unix socket 提供加密和解密，数据密钥不离开服务进程。
+// 每个连接发送一个 JSON 请求并读取一个 JSON 响应
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fiftyk/claude-switcher/internal/vault"
+)
+
+// SocketEnv 指定 agent socket 路径的环境变量
+const SocketEnv = "CLAUDE_SWITCHER_AGENT_SOCK"
+
+// 请求类型
+const (
+	opStatus = "status"
+	opSeal   = "seal"
+	opOpen   = "open"
+	opStop   = "stop"
+)
+
+// callTimeout 客户端一次请求的超时时间
+const callTimeout = 5 * time.Second
+
+type request struct {
+	Op   string `json:"op"`
+	Data []byte `json:"data,omitempty"`
+}
+
+type response struct {
+	Data    []byte    `json:"data,omitempty"`
+	Dir     string    `json:"dir,omitempty"`
+	Expires time.Time `json:"expires"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Status agent 的状态
+type Status struct {
+	Dir     string    // 密钥所属的 profiles 目录
+	Expires time.Time // 密钥过期时间，零值表示不过期
+}
+
+// Server 保存数据密钥并处理请求
+type Server struct {
+	dir     string
+	expires time.Time
+
+	mu  sync.Mutex
+	key []byte
+	ln  net.Listener
+
+	// Logf 记录请求，为空时不记录
+	Logf func(format string, args ...interface{})
+}
+
+// NewServer 创建服务，ttl 为 0 时密钥不过期
+func NewServer(dir string, key []byte, ttl time.Duration) *Server {
+	s := &Server{dir: dir, key: append([]byte(nil), key...)}
+	if ttl > 0 {
+		s.expires = time.Now().Add(ttl)
+	}
+	return s
+}
+
+// Listen 在 path 上创建 Unix socket 并设置权限为 0600
+// path 上残留的 socket 无法连接时会先删除；已有 agent 在运行时返回错误
+func Listen(path string) (net.Listener, error) {
+	if _, err := os.Lstat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("agent 已在运行: %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// Serve 处理 ln 上的连接，直到密钥过期或调用 Close
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	s.ln = ln
+	s.mu.Unlock()
+
+	if !s.expires.IsZero() {
+		timer := time.AfterFunc(time.Until(s.expires), func() {
+			s.logf("密钥已过期")
+			s.Close()
+		})
+		defer timer.Stop()
+	}
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.closed() {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close 清除内存中的密钥并停止服务
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.key {
+		s.key[i] = 0
+	}
+	s.key = nil
+	if s.ln != nil {
+		s.ln.Close()
+	}
+}
+
+func (s *Server) closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.key == nil
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// handle 处理一个连接上的请求
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(callTimeout))
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	resp := s.do(req)
+	json.NewEncoder(conn).Encode(resp)
+
+	if req.Op == opStop {
+		s.logf("收到停止请求")
+		s.Close()
+	}
+}
+
+// do 执行请求
+func (s *Server) do(req request) response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		return response{Error: "密钥已清除"}
+	}
+
+	var data []byte
+	var err error
+	switch req.Op {
+	case opStatus, opStop:
+		return response{Dir: s.dir, Expires: s.expires}
+	case opSeal:
+		data, err = vault.Seal(s.key, req.Data)
+	case opOpen:
+		data, err = vault.Open(s.key, req.Data)
+	default:
+		err = fmt.Errorf("未知请求: %s", req.Op)
+	}
+	s.logf("处理请求: %s", req.Op)
+	if err != nil {
+		return response{Error: err.Error()}
+	}
+	return response{Data: data}
+}
+
+// Client 连接 agent 的客户端，实现配置文件的加密和解密
+type Client struct {
+	Path string
+}
+
+// call 发送一个请求并读取响应
+func (c *Client) call(req request) (response, error) {
+	conn, err := net.DialTimeout("unix", c.Path, callTimeout)
+	if err != nil {
+		return response{}, fmt.Errorf("无法连接 agent: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(callTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return response{}, err
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return response{}, fmt.Errorf("agent 响应无效: %w", err)
+	}
+	if resp.Error != "" {
+		return response{}, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// Status 查询 agent 状态
+func (c *Client) Status() (Status, error) {
+	resp, err := c.call(request{Op: opStatus})
+	if err != nil {
+		return Status{}, err
+	}
+	return Status{Dir: resp.Dir, Expires: resp.Expires}, nil
+}
+
+// Seal 请求 agent 加密
+func (c *Client) Seal(plaintext []byte) ([]byte, error) {
+	resp, err := c.call(request{Op: opSeal, Data: plaintext})
+	return resp.Data, err
+}
+
+// Open 请求 agent 解密
+func (c *Client) Open(data []byte) ([]byte, error) {
+	resp, err := c.call(request{Op: opOpen, Data: data})
+	return resp.Data, err
+}
+
+// Stop 请求 agent 清除密钥并退出
+func (c *Client) Stop() error {
+	_, err := c.call(request{Op: opStop})
+	return err
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fiftyk/claude-switcher/internal/vault"
+)
+
+// startServer 在临时目录中启动服务，返回客户端和 Serve 的结果
+func startServer(t *testing.T, key []byte, ttl time.Duration) (*Client, <-chan error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer("/profiles", key, ttl)
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ln) }()
+	t.Cleanup(srv.Close)
+	return &Client{Path: path}, done
+}
+
+func TestClientServer(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, vault.KeySize)
+	client, done := startServer(t, key, 0)
+
+	st, err := client.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Dir != "/profiles" || !st.Expires.IsZero() {
+		t.Errorf("Status() = %+v", st)
+	}
+
+	// agent 加密的内容可以用同一密钥解密，反之亦然
+	sealed, err := client.Seal([]byte("TOKEN=sk-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := vault.Open(key, sealed); err != nil || string(plain) != "TOKEN=sk-secret" {
+		t.Errorf("vault.Open(agent sealed) = %q, %v", plain, err)
+	}
+	local, _ := vault.Seal(key, []byte("local"))
+	if plain, err := client.Open(local); err != nil || string(plain) != "local" {
+		t.Errorf("client.Open() = %q, %v", plain, err)
+	}
+	if _, err := client.Open([]byte("garbage")); err == nil {
+		t.Error("expected error for invalid data")
+	}
+
+	if _, err := Listen(client.Path); err == nil {
+		t.Error("Listen should fail while an agent is running")
+	}
+
+	if err := client.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve() = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not stop")
+	}
+	if _, err := client.Status(); err == nil {
+		t.Error("expected error after stop")
+	}
+}
+
+func TestServerExpires(t *testing.T) {
+	client, done := startServer(t, bytes.Repeat([]byte{1}, vault.KeySize), 50*time.Millisecond)
+
+	st, err := client.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Expires.IsZero() {
+		t.Error("Expires should be set when ttl > 0")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not stop after ttl")
+	}
+	if _, err := client.Seal([]byte("x")); err == nil {
+		t.Error("expected error after ttl")
+	}
+}
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 )
 
 // ConfigDir 是配置目录路径
@@ -23,14 +26,29 @@ func GetProfilesDir() string {
 	return filepath.Join(GetConfigDir(), "profiles")
 }
 
+// GetHomesDir 返回各配置独立的 Claude 配置目录所在的目录
+func GetHomesDir() string {
+	return filepath.Join(GetConfigDir(), "homes")
+}
+
+// GetProfileHomeDir 返回指定配置独立的 Claude 配置目录
+func GetProfileHomeDir(name string) string {
+	return filepath.Join(GetHomesDir(), name)
+}
+
+// GetGroupsDir 返回配置组目录路径
+func GetGroupsDir() string {
+	return filepath.Join(GetConfigDir(), "groups")
+}
+
 // GetActiveFile 返回活动配置记录文件路径
 func GetActiveFile() string {
 	return filepath.Join(GetConfigDir(), "active")
 }
 
-// EnsureConfigDir 确保配置目录存在
+// EnsureConfigDir 确保配置目录及 profiles 目录存在
 func EnsureConfigDir() error {
-	dir := GetConfigDir()
+	dir := GetProfilesDir()
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		return os.MkdirAll(dir, 0700)
 	}
@@ -84,11 +102,38 @@ func ValidateURL(url string) bool {
 	return matched
 }
 
+// ProxySchemes 支持的代理协议
+var ProxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
 // ValidateProxy 验证代理格式
+// 支持 http、https、socks5、socks5h 协议，可带 user:pass@ 认证信息，主机可以是 IPv6 地址（[::1]），必须指定端口
 func ValidateProxy(proxy string) bool {
 	if proxy == "" {
 		return true
 	}
-	matched, _ := regexp.MatchString(`^https?://[a-zA-Z0-9.-]+:[0-9]+$`, proxy)
-	return matched
+	u, err := url.Parse(proxy)
+	if err != nil || u.Opaque != "" || u.RawQuery != "" || u.Fragment != "" {
+		return false
+	}
+	if u.Path != "" && u.Path != "/" {
+		return false
+	}
+
+	supported := false
+	for _, s := range ProxySchemes {
+		if u.Scheme == s {
+			supported = true
+		}
+	}
+	if !supported {
+		return false
+	}
+
+	// IPv6 地址必须使用方括号
+	host, portStr, err := net.SplitHostPort(u.Host)
+	if err != nil || host == "" {
+		return false
+	}
+	port, err := strconv.Atoi(portStr)
+	return err == nil && port > 0 && port <= 65535
 }
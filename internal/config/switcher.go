@@ -0,0 +1,117 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// 启动模式
+const (
+	// LaunchModeSettings 启动前将配置同步到 ~/.claude/settings.json（默认）
+	LaunchModeSettings = "settings"
+	// LaunchModeIsolated 只向 claude 子进程注入环境变量，不修改 settings.json
+	LaunchModeIsolated = "isolated"
+	// LaunchModeHome 使用配置独立的 Claude 配置目录（CLAUDE_CONFIG_DIR）
+	LaunchModeHome = "home"
+)
+
+// LaunchModes 返回所有支持的启动模式
+func LaunchModes() []string {
+	return []string{LaunchModeSettings, LaunchModeIsolated, LaunchModeHome}
+}
+
+// ValidateLaunchMode 验证启动模式
+func ValidateLaunchMode(mode string) bool {
+	for _, m := range LaunchModes() {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// 同步模式，决定 API 密钥如何写入 settings.json
+const (
+	// SyncModeEnv 将密钥作为环境变量写入 settings.json（默认）
+	SyncModeEnv = "env"
+	// SyncModeHelper 写入 apiKeyHelper，由 claude-switcher token 命令输出密钥
+	SyncModeHelper = "helper"
+)
+
+// SyncModes 返回所有支持的同步模式
+func SyncModes() []string {
+	return []string{SyncModeEnv, SyncModeHelper}
+}
+
+// ValidateSyncMode 验证同步模式
+func ValidateSyncMode(mode string) bool {
+	for _, m := range SyncModes() {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// SwitcherConfig 表示 ~/.claude-switcher/config.json 中的全局设置
+type SwitcherConfig struct {
+	LaunchMode     string `json:"launchMode,omitempty"`
+	SyncMode       string `json:"syncMode,omitempty"`
+	IPCheckURL     string `json:"ipCheckUrl,omitempty"`     // 出口 IP 查询地址，为空时使用内置默认值
+	ExpiryWarnDays *int   `json:"expiryWarnDays,omitempty"` // token 过期前多少天开始提醒，负数表示启动时不提醒
+}
+
+// DefaultExpiryWarnDays 默认在 token 过期前 7 天开始提醒
+const DefaultExpiryWarnDays = 7
+
+// GetSwitcherConfigFile 返回全局设置文件路径
+func GetSwitcherConfigFile() string {
+	return filepath.Join(GetConfigDir(), "config.json")
+}
+
+// LoadSwitcherConfig 加载全局设置，文件不存在时返回默认值
+func LoadSwitcherConfig() (*SwitcherConfig, error) {
+	cfg := &SwitcherConfig{}
+
+	data, err := os.ReadFile(GetSwitcherConfigFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg.withDefaults(), nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("解析 config.json 失败: %w", err)
+	}
+	return cfg.withDefaults(), nil
+}
+
+// Save 保存全局设置
+func (c *SwitcherConfig) Save() error {
+	if err := EnsureConfigDir(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(GetSwitcherConfigFile(), data, 0600)
+}
+
+// withDefaults 填充未设置的字段
+func (c *SwitcherConfig) withDefaults() *SwitcherConfig {
+	if c.LaunchMode == "" {
+		c.LaunchMode = LaunchModeSettings
+	}
+	if c.SyncMode == "" {
+		c.SyncMode = SyncModeEnv
+	}
+	if c.ExpiryWarnDays == nil {
+		days := DefaultExpiryWarnDays
+		c.ExpiryWarnDays = &days
+	}
+	return c
+}
@@ -0,0 +1,356 @@
+// Package gateway 实现本地切换网关
+// claude 通过 ANTHROPIC_BASE_URL 指向网关，网关将每个请求转发到当前活动配置的上游，
+// 切换配置后下一个请求即生效，无需重启 claude
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fiftyk/claude-switcher/internal/profile"
+	"github.com/fiftyk/claude-switcher/internal/proxy"
+)
+
+// DefaultBaseURL 配置未设置 BaseURL 时使用的上游地址
+const DefaultBaseURL = "https://api.anthropic.com"
+
+// DefaultResponseTimeout 还有下一个上游可用时，等待响应头的默认时间
+const DefaultResponseTimeout = 60 * time.Second
+
+// errResponseTimeout 上游在 ResponseTimeout 内没有返回响应头
+var errResponseTimeout = errors.New("等待响应头超时")
+
+// Upstream 表示一次请求要转发到的上游
+type Upstream struct {
+	Profile string // 配置名称，仅用于日志
+	BaseURL string
+	Token   string // 以 Authorization: Bearer 发送（ANTHROPIC_AUTH_TOKEN）
+	APIKey  string // 以 x-api-key 发送（ANTHROPIC_API_KEY），Token 为空时使用
+	Proxy   proxy.Config
+
+	// Protocol 为 profile.ProtocolOpenAI 时将 Messages 请求转换为 Chat Completions
+	Protocol string
+}
+
+// Resolver 在每个请求到达时返回当前的上游
+// 返回多个上游时按顺序尝试，前一个出错（5xx、529、连接失败或超时）时转发到下一个
+type Resolver func() ([]*Upstream, error)
+
+// Gateway 是转发请求到活动配置的 http.Handler
+type Gateway struct {
+	resolve Resolver
+
+	// Logf 不为空时输出每个请求的日志
+	Logf func(format string, args ...interface{})
+
+	// ResponseTimeout 还有下一个上游可用时，等待响应头的最长时间，超时后改用下一个上游；
+	// 最后一个上游不受限制，以免中断耗时较长的非流式请求。0 表示不限制
+	ResponseTimeout time.Duration
+
+	mu         sync.Mutex
+	transports map[proxy.Config]*http.Transport
+}
+
+// New 创建网关
+func New(resolve Resolver) *Gateway {
+	return &Gateway{
+		resolve:         resolve,
+		ResponseTimeout: DefaultResponseTimeout,
+		transports:      make(map[proxy.Config]*http.Transport),
+	}
+}
+
+// Static 返回固定上游的 Resolver
+func Static(ups ...*Upstream) Resolver {
+	return func() ([]*Upstream, error) { return ups, nil }
+}
+
+// maxRequestBody 允许的最大请求体，请求体需要缓存以便切换上游后重发，超过时返回 413
+const maxRequestBody = 64 << 20
+
+// hopHeaders 是逐跳头部，不能转发
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// ServeHTTP 将请求转发到当前活动配置的上游
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	ups, err := g.resolve()
+	if err == nil && len(ups) == 0 {
+		err = errors.New("没有可用的上游")
+	}
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("无法获取活动配置: %v", err))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeAPIError(w, http.StatusRequestEntityTooLarge, "request_too_large",
+				fmt.Sprintf("claude-switcher gateway: 请求体超过 %d MB", maxRequestBody>>20))
+			return
+		}
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("读取请求失败: %v", err))
+		return
+	}
+
+	for i, up := range ups {
+		var timeout time.Duration
+		if i < len(ups)-1 {
+			timeout = g.ResponseTimeout
+		}
+		resp, err := g.forward(r, up, body, timeout)
+
+		if i < len(ups)-1 && g.retryable(r, resp, err) {
+			reason := fmt.Sprint(err)
+			if resp != nil {
+				reason = resp.Status
+				resp.Body.Close()
+			}
+			g.logf("%s %s %s -> %s，切换到 %s", up.Profile, r.Method, r.URL.Path, reason, ups[i+1].Profile)
+			continue
+		}
+
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			writeAPIError(w, reqErr.status, reqErr.errType, reqErr.message)
+			return
+		}
+		if err != nil {
+			g.logf("%s %s %s -> 错误: %v", up.Profile, r.Method, r.URL.Path, err)
+			writeError(w, http.StatusBadGateway, fmt.Sprintf("请求上游失败: %v", err))
+			return
+		}
+
+		defer resp.Body.Close()
+		if err := g.writeResponse(w, r, up, resp, body); err != nil && !errors.Is(err, r.Context().Err()) {
+			g.logf("%s %s %s -> 转发响应中断: %v", up.Profile, r.Method, r.URL.Path, err)
+			return
+		}
+		g.logf("%s %s %s -> %d (%s)", up.Profile, r.Method, r.URL.Path, resp.StatusCode, time.Since(start).Round(time.Millisecond))
+		return
+	}
+}
+
+// forward 将请求发送到指定上游，timeout 大于 0 时限制等待响应头的时间
+func (g *Gateway) forward(r *http.Request, up *Upstream, body []byte, timeout time.Duration) (*http.Response, error) {
+	client, err := g.transport(up.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	var outReq *http.Request
+	if up.Protocol == profile.ProtocolOpenAI {
+		outReq, err = newOpenAIRequest(r, up, body)
+	} else {
+		outReq, err = newUpstreamRequest(r, up, body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		return client.RoundTrip(outReq)
+	}
+
+	// 收到响应头后停止计时，响应体读取完毕或关闭时才取消请求
+	ctx, cancel := context.WithCancelCause(r.Context())
+	timer := time.AfterFunc(timeout, func() { cancel(errResponseTimeout) })
+	resp, err := client.RoundTrip(outReq.WithContext(ctx))
+	if !timer.Stop() {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		cancel(nil)
+		return nil, fmt.Errorf("%w（%s）", errResponseTimeout, timeout)
+	}
+	if err != nil {
+		cancel(nil)
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: func() { cancel(nil) }}
+	return resp, nil
+}
+
+// cancelOnClose 关闭响应体时取消请求的 context
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// retryable 判断是否应改用下一个上游重试
+// 连接失败、等待响应头超时或返回 5xx 时重试，请求本身有误或客户端已断开时不重试
+func (g *Gateway) retryable(r *http.Request, resp *http.Response, err error) bool {
+	if r.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		var reqErr *requestError
+		return !errors.As(err, &reqErr)
+	}
+	return resp.StatusCode >= 500
+}
+
+// writeResponse 将上游响应写回客户端
+func (g *Gateway) writeResponse(w http.ResponseWriter, r *http.Request, up *Upstream, resp *http.Response, body []byte) error {
+	if up.Protocol == profile.ProtocolOpenAI {
+		return writeOpenAIResponse(w, resp, body)
+	}
+
+	copyHeader(w.Header(), resp.Header)
+	w.WriteHeader(resp.StatusCode)
+	return copyBody(w, resp.Body)
+}
+
+// requestError 表示请求本身无法转发（如无法转换为上游协议），换一个上游也不会成功
+type requestError struct {
+	status  int
+	errType string
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// newUpstreamRequest 构建发往上游的请求
+// 客户端的认证头会被替换为活动配置的 token
+func newUpstreamRequest(r *http.Request, up *Upstream, body []byte) (*http.Request, error) {
+	baseURL := up.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	target, err := joinURL(baseURL, r.URL.Path, up.Profile)
+	if err != nil {
+		return nil, err
+	}
+	target.RawQuery = r.URL.RawQuery
+
+	var reqBody io.Reader
+	if len(body) > 0 {
+		reqBody = bytes.NewReader(body)
+	}
+	outReq, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	copyHeader(outReq.Header, r.Header)
+	outReq.Header.Del("X-Api-Key")
+	outReq.Header.Del("Authorization")
+	if up.Token != "" {
+		outReq.Header.Set("Authorization", "Bearer "+up.Token)
+	} else if up.APIKey != "" {
+		outReq.Header.Set("X-Api-Key", up.APIKey)
+	}
+
+	return outReq, nil
+}
+
+// joinURL 将请求路径拼接到 BaseURL 的路径之后
+func joinURL(baseURL, path, profileName string) (*url.URL, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("配置 '%s' 的 BaseURL 无效: %s", profileName, baseURL)
+	}
+
+	target := *base
+	target.Path = strings.TrimRight(base.Path, "/") + path
+	target.RawPath = ""
+	target.RawQuery = ""
+	return &target, nil
+}
+
+// transport 返回使用指定代理的 Transport，相同代理设置复用连接
+func (g *Gateway) transport(cfg proxy.Config) (*http.Transport, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if t, ok := g.transports[cfg]; ok {
+		return t, nil
+	}
+
+	t, err := proxy.NewTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	g.transports[cfg] = t
+	return t, nil
+}
+
+func (g *Gateway) logf(format string, args ...interface{}) {
+	if g.Logf != nil {
+		g.Logf(format, args...)
+	}
+}
+
+// copyHeader 复制头部并去掉逐跳头部
+func copyHeader(dst, src http.Header) {
+	for k, vv := range src {
+		for _, v := range vv {
+			dst.Add(k, v)
+		}
+	}
+	// Connection 中列出的头部同样是逐跳的
+	for _, token := range strings.Split(src.Get("Connection"), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			dst.Del(token)
+		}
+	}
+	for _, h := range hopHeaders {
+		dst.Del(h)
+	}
+}
+
+// copyBody 复制响应体，每次写入后立即 flush，保证 SSE 事件实时送达
+func copyBody(w http.ResponseWriter, body io.Reader) error {
+	rc := http.NewResponseController(w)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			if ferr := rc.Flush(); ferr != nil && !errors.Is(ferr, http.ErrNotSupported) {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeError 以 Anthropic API 的错误格式返回网关自身的错误，claude 会直接显示其中的信息
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeAPIError(w, status, "api_error", "claude-switcher gateway: "+message)
+}
@@ -0,0 +1,420 @@
+package gateway
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGatewayForwardsToActiveUpstream(t *testing.T) {
+	var gotPath, gotAuth, gotAPIKey, gotBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.RequestURI()
+		gotAuth = r.Header.Get("Authorization")
+		gotAPIKey = r.Header.Get("X-Api-Key")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	active := &Upstream{Profile: "work", BaseURL: upstream.URL + "/api/", Token: "sk-work"}
+	gw := httptest.NewServer(New(func() ([]*Upstream, error) { return []*Upstream{active}, nil }))
+	defer gw.Close()
+
+	req, _ := http.NewRequest("POST", gw.URL+"/v1/messages?beta=true", strings.NewReader(`{"model":"x"}`))
+	req.Header.Set("X-Api-Key", "placeholder")
+	req.Header.Set("Authorization", "Bearer placeholder")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != `{"ok":true}` {
+		t.Errorf("response = %d %s", resp.StatusCode, body)
+	}
+	if gotPath != "/api/v1/messages?beta=true" {
+		t.Errorf("upstream path = %q, want /api/v1/messages?beta=true", gotPath)
+	}
+	if gotAuth != "Bearer sk-work" {
+		t.Errorf("Authorization = %q, want Bearer sk-work", gotAuth)
+	}
+	if gotAPIKey != "" {
+		t.Errorf("X-Api-Key should be stripped, got %q", gotAPIKey)
+	}
+	if gotBody != `{"model":"x"}` {
+		t.Errorf("body = %q", gotBody)
+	}
+
+	// 切换活动配置后下一个请求使用新 token
+	active = &Upstream{Profile: "personal", BaseURL: upstream.URL, Token: "sk-personal"}
+	resp, err = http.Post(gw.URL+"/v1/messages", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if gotAuth != "Bearer sk-personal" || gotPath != "/v1/messages" {
+		t.Errorf("after switch: Authorization = %q, path = %q", gotAuth, gotPath)
+	}
+}
+
+func TestGatewaySendsAPIKeyHeader(t *testing.T) {
+	var gotKey, gotAuth string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey, gotAuth = r.Header.Get("X-Api-Key"), r.Header.Get("Authorization")
+	}))
+	defer upstream.Close()
+
+	gw := httptest.NewServer(New(Static(&Upstream{BaseURL: upstream.URL, APIKey: "sk-ant-api"})))
+	defer gw.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, gw.URL+"/v1/messages", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer gateway")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// ANTHROPIC_API_KEY 以 x-api-key 发送，客户端的占位认证头被去掉
+	if gotKey != "sk-ant-api" || gotAuth != "" {
+		t.Errorf("x-api-key = %q, Authorization = %q", gotKey, gotAuth)
+	}
+}
+
+func TestGatewayStreamsSSE(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {}\n\n")
+		w.(http.Flusher).Flush()
+		// 第一个事件必须在上游结束前送达客户端
+		<-release
+		fmt.Fprint(w, "event: message_stop\ndata: {}\n\n")
+	}))
+	defer upstream.Close()
+
+	gw := httptest.NewServer(New(Static(&Upstream{BaseURL: upstream.URL, Token: "sk"})))
+	defer gw.Close()
+
+	resp, err := http.Post(gw.URL+"/v1/messages", "application/json", strings.NewReader(`{"stream":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil || line != "event: message_start\n" {
+		t.Fatalf("first line = %q, %v", line, err)
+	}
+	close(release)
+
+	rest, _ := io.ReadAll(reader)
+	if !strings.Contains(string(rest), "event: message_stop") {
+		t.Errorf("missing final event: %q", rest)
+	}
+}
+
+func TestGatewayResolveError(t *testing.T) {
+	gw := httptest.NewServer(New(func() ([]*Upstream, error) {
+		return nil, fmt.Errorf("没有活动配置")
+	}))
+	defer gw.Close()
+
+	resp, err := http.Post(gw.URL+"/v1/messages", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", resp.StatusCode)
+	}
+	if !strings.Contains(string(body), `"type":"error"`) || !strings.Contains(string(body), "没有活动配置") {
+		t.Errorf("body = %s", body)
+	}
+}
+
+func TestGatewayRejectsOversizedBody(t *testing.T) {
+	hits := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer upstream.Close()
+
+	gw := httptest.NewServer(New(Static(&Upstream{BaseURL: upstream.URL})))
+	defer gw.Close()
+
+	body := strings.NewReader(`{"pad":"` + strings.Repeat("x", maxRequestBody) + `"}`)
+	resp, err := http.Post(gw.URL+"/v1/messages", "application/json", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", resp.StatusCode)
+	}
+	if !strings.Contains(string(data), `"type":"request_too_large"`) {
+		t.Errorf("body = %s, want an Anthropic error", data)
+	}
+	if hits != 0 {
+		t.Errorf("truncated request should not be forwarded, upstream hits = %d", hits)
+	}
+}
+
+func TestCopyHeaderStripsHopHeaders(t *testing.T) {
+	src := http.Header{}
+	src.Set("Connection", "X-Custom-Hop")
+	src.Set("X-Custom-Hop", "1")
+	src.Set("Keep-Alive", "timeout=5")
+	src.Set("Anthropic-Version", "2023-06-01")
+
+	dst := http.Header{}
+	copyHeader(dst, src)
+
+	if dst.Get("Anthropic-Version") != "2023-06-01" {
+		t.Error("end-to-end headers should be copied")
+	}
+	for _, h := range []string{"Connection", "X-Custom-Hop", "Keep-Alive"} {
+		if dst.Get(h) != "" {
+			t.Errorf("%s should be stripped", h)
+		}
+	}
+}
+
+func TestGatewayTranslatesOpenAI(t *testing.T) {
+	var gotPath, gotAuth, gotBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+
+		if strings.Contains(gotBody, `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"id\":\"c1\",\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"id\":\"c1\",\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c1","model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1}}`)
+	}))
+	defer upstream.Close()
+
+	gw := httptest.NewServer(New(Static(&Upstream{BaseURL: upstream.URL + "/api/v1", Token: "sk-or", Protocol: "openai"})))
+	defer gw.Close()
+
+	resp, err := http.Post(gw.URL+"/v1/messages?beta=true", "application/json",
+		strings.NewReader(`{"model":"gpt-4o","max_tokens":10,"system":"be nice","messages":[{"role":"user","content":"hello"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if gotPath != "/api/v1/chat/completions" || gotAuth != "Bearer sk-or" {
+		t.Errorf("upstream path = %q, Authorization = %q", gotPath, gotAuth)
+	}
+	if !strings.Contains(gotBody, `"role":"system","content":"be nice"`) {
+		t.Errorf("upstream body = %s", gotBody)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"type":"message"`) || !strings.Contains(string(body), `"text":"hi"`) {
+		t.Errorf("response = %d %s", resp.StatusCode, body)
+	}
+
+	resp, err = http.Post(gw.URL+"/v1/messages", "application/json",
+		strings.NewReader(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hello"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	for _, ev := range []string{"event: message_start", "event: content_block_delta", "event: message_stop"} {
+		if !strings.Contains(string(body), ev) {
+			t.Errorf("stream missing %q: %s", ev, body)
+		}
+	}
+}
+
+func TestGatewayOpenAIErrors(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"message":"slow down"}}`)
+	}))
+	defer upstream.Close()
+
+	gw := httptest.NewServer(New(Static(&Upstream{BaseURL: upstream.URL, Protocol: "openai"})))
+	defer gw.Close()
+
+	resp, err := http.Post(gw.URL+"/v1/messages", "application/json", strings.NewReader(`{"model":"x","messages":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || !strings.Contains(string(body), "rate_limit_error") || !strings.Contains(string(body), "slow down") {
+		t.Errorf("response = %d %s", resp.StatusCode, body)
+	}
+
+	resp, err = http.Post(gw.URL+"/v1/messages/count_tokens", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("count_tokens status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestGatewayFailover(t *testing.T) {
+	var overloadedHits int
+	overloaded := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		overloadedHits++
+		w.WriteHeader(529)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"overloaded_error","message":"busy"}}`)
+	}))
+	defer overloaded.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close() // 连接被拒绝
+
+	var gotBody, gotAuth string
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody, gotAuth = string(body), r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer healthy.Close()
+
+	gw := httptest.NewServer(New(Static(
+		&Upstream{Profile: "primary", BaseURL: overloaded.URL, Token: "sk-1"},
+		&Upstream{Profile: "secondary", BaseURL: down.URL, Token: "sk-2"},
+		&Upstream{Profile: "backup", BaseURL: healthy.URL, Token: "sk-3"},
+	)))
+	defer gw.Close()
+
+	resp, err := http.Post(gw.URL+"/v1/messages", "application/json", strings.NewReader(`{"model":"x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != `{"ok":true}` {
+		t.Errorf("response = %d %s", resp.StatusCode, body)
+	}
+	if overloadedHits != 1 {
+		t.Errorf("primary hits = %d, want 1", overloadedHits)
+	}
+	if gotBody != `{"model":"x"}` || gotAuth != "Bearer sk-3" {
+		t.Errorf("backup got body %q, Authorization %q", gotBody, gotAuth)
+	}
+}
+
+func TestGatewayFailoverOnStalledUpstream(t *testing.T) {
+	release := make(chan struct{})
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer stalled.Close()
+	defer close(release)
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer healthy.Close()
+
+	g := New(Static(
+		&Upstream{Profile: "stalled", BaseURL: stalled.URL, Token: "sk-1"},
+		&Upstream{Profile: "backup", BaseURL: healthy.URL, Token: "sk-2"},
+	))
+	g.ResponseTimeout = 100 * time.Millisecond
+	gw := httptest.NewServer(g)
+	defer gw.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(gw.URL+"/v1/messages", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != "Bearer sk-2" {
+		t.Errorf("response = %d %s, want the backup upstream to serve the request", resp.StatusCode, body)
+	}
+}
+
+func TestGatewayFailoverLastResponseIsReturned(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer failing.Close()
+
+	gw := httptest.NewServer(New(Static(
+		&Upstream{Profile: "a", BaseURL: failing.URL, Token: "sk-a"},
+		&Upstream{Profile: "b", BaseURL: failing.URL, Token: "sk-b"},
+	)))
+	defer gw.Close()
+
+	resp, err := http.Post(gw.URL+"/v1/messages", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// 所有上游都失败时返回最后一个上游的响应
+	if resp.StatusCode != http.StatusInternalServerError || string(body) != "Bearer sk-b" {
+		t.Errorf("response = %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestGatewayDoesNotRetryClientErrors(t *testing.T) {
+	hits := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer upstream.Close()
+
+	gw := httptest.NewServer(New(Static(
+		&Upstream{Profile: "a", BaseURL: upstream.URL},
+		&Upstream{Profile: "b", BaseURL: upstream.URL},
+	)))
+	defer gw.Close()
+
+	resp, err := http.Post(gw.URL+"/v1/messages", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || hits != 1 {
+		t.Errorf("status = %d, hits = %d, want 400 and 1", resp.StatusCode, hits)
+	}
+}
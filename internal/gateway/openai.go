@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fiftyk/claude-switcher/internal/translate"
+)
+
+// newOpenAIRequest 将 Messages 请求转换为发往 chat/completions 的请求
+// 只携带必要的头部，anthropic-* 头部对上游没有意义
+func newOpenAIRequest(r *http.Request, up *Upstream, body []byte) (*http.Request, error) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/messages":
+	case r.URL.Path == "/v1/messages/count_tokens":
+		return nil, &requestError{http.StatusNotFound, "not_found_error", "OpenAI 协议的配置不支持 count_tokens"}
+	default:
+		return nil, &requestError{http.StatusNotFound, "not_found_error", fmt.Sprintf("OpenAI 协议的配置不支持 %s %s", r.Method, r.URL.Path)}
+	}
+
+	var req translate.MessagesRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, &requestError{http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("无效的请求: %v", err)}
+	}
+	chatReq, err := translate.ToChatRequest(&req)
+	if err != nil {
+		return nil, &requestError{http.StatusBadRequest, "invalid_request_error", err.Error()}
+	}
+	chatBody, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, err
+	}
+
+	path := "/chat/completions"
+	if strings.HasSuffix(strings.TrimRight(up.BaseURL, "/"), "/chat/completions") {
+		path = ""
+	}
+	target, err := joinURL(up.BaseURL, path, up.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	outReq, err := http.NewRequestWithContext(r.Context(), http.MethodPost, target.String(), bytes.NewReader(chatBody))
+	if err != nil {
+		return nil, err
+	}
+	outReq.Header.Set("Content-Type", "application/json")
+	// OpenAI 接口只接受 Bearer 认证
+	token := up.Token
+	if token == "" {
+		token = up.APIKey
+	}
+	if token != "" {
+		outReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	if ua := r.Header.Get("User-Agent"); ua != "" {
+		outReq.Header.Set("User-Agent", ua)
+	}
+	return outReq, nil
+}
+
+// writeOpenAIResponse 将 chat/completions 的响应转换为 Messages 响应写回客户端
+// body 为客户端的原始请求，用于确定是否流式输出和模型名称
+func writeOpenAIResponse(w http.ResponseWriter, resp *http.Response, body []byte) error {
+	var req struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	json.Unmarshal(body, &req)
+
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.StatusCode)
+		_, err := w.Write(translate.ErrorBody(resp.StatusCode, data))
+		return err
+	}
+
+	if req.Stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+
+		rc := http.NewResponseController(w)
+		return translate.NewStreamWriter(w, func() { rc.Flush() }, req.Model).Translate(resp.Body)
+	}
+
+	var chatResp translate.ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("解析上游响应失败: %v", err))
+		return nil
+	}
+	msg, err := translate.FromChatResponse(&chatResp, req.Model)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(msg)
+}
+
+// writeAPIError 以 Anthropic API 的错误格式返回错误
+func writeAPIError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"type": "error",
+		"error": map[string]string{
+			"type":    errType,
+			"message": message,
+		},
+	})
+}
@@ -0,0 +1,150 @@
+// Package geoip 查询出口 IP 及其所在国家
+package geoip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultEndpoint 默认的出口 IP 查询地址
+const DefaultEndpoint = "http://ip-api.com/json/?fields=status,message,country,countryCode,regionName,city,query"
+
+// Info 出口 IP 信息
+type Info struct {
+	IP          string
+	Country     string // 国家名称，部分服务不提供
+	CountryCode string // ISO 3166-1 两位国家代码，大写
+	Region      string
+	City        string
+}
+
+// Location 返回便于展示的位置描述
+func (i *Info) Location() string {
+	parts := []string{}
+	for _, s := range []string{i.Country, i.Region, i.City} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if len(parts) == 0 {
+		return i.CountryCode
+	}
+	if i.Country == "" && i.CountryCode != "" {
+		parts = append([]string{i.CountryCode}, parts...)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Lookup 通过 client 请求 endpoint 并解析结果
+func Lookup(ctx context.Context, client *http.Client, endpoint string) (*Info, error) {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("查询地址无效: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("查询出口 IP 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("读取查询结果失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("查询出口 IP 失败: HTTP %d", resp.StatusCode)
+	}
+	return Parse(body)
+}
+
+// response 兼容 ip-api.com、ipinfo.io 与 ipapi.co 的字段
+type response struct {
+	// ip-api.com
+	Status      string `json:"status"`
+	Message     string `json:"message"`
+	Query       string `json:"query"`
+	CountryCode string `json:"countryCode"`
+	RegionName  string `json:"regionName"`
+
+	// ipinfo.io 的 country 为国家代码，ip-api.com 的 country 为国家名称
+	IP      string `json:"ip"`
+	Country string `json:"country"`
+	Region  string `json:"region"`
+	City    string `json:"city"`
+
+	// ipapi.co
+	CountryCodeSnake string `json:"country_code"`
+	CountryName      string `json:"country_name"`
+	Error            any    `json:"error"`
+	Reason           string `json:"reason"`
+}
+
+// Parse 解析 ip-api.com、ipinfo.io 或 ipapi.co 格式的 JSON 响应
+func Parse(body []byte) (*Info, error) {
+	var r response
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("无法解析查询结果: %w", err)
+	}
+
+	if r.Status == "fail" {
+		return nil, fmt.Errorf("查询出口 IP 失败: %s", r.Message)
+	}
+	if failed, _ := r.Error.(bool); failed {
+		return nil, fmt.Errorf("查询出口 IP 失败: %s", r.Reason)
+	}
+
+	info := &Info{
+		IP:     firstNonEmpty(r.Query, r.IP),
+		Region: firstNonEmpty(r.RegionName, r.Region),
+		City:   r.City,
+	}
+	switch {
+	case r.CountryCode != "":
+		info.CountryCode = r.CountryCode
+		info.Country = r.Country
+	case r.CountryCodeSnake != "":
+		info.CountryCode = r.CountryCodeSnake
+		info.Country = firstNonEmpty(r.CountryName, r.Country)
+	case len(r.Country) == 2:
+		info.CountryCode = r.Country
+	default:
+		info.Country = r.Country
+	}
+	info.CountryCode = strings.ToUpper(info.CountryCode)
+
+	if info.IP == "" {
+		return nil, fmt.Errorf("查询结果中没有 IP 地址")
+	}
+	return info, nil
+}
+
+// Allowed 判断国家代码是否在允许列表中，列表为空时总是允许
+func Allowed(countryCode string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, c := range allowed {
+		if strings.EqualFold(c, countryCode) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
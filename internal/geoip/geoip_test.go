@@ -0,0 +1,88 @@
+package geoip
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want Info
+	}{
+		{
+			"ip-api",
+			`{"status":"success","country":"United States","countryCode":"US","regionName":"California","city":"Los Angeles","query":"1.2.3.4"}`,
+			Info{IP: "1.2.3.4", Country: "United States", CountryCode: "US", Region: "California", City: "Los Angeles"},
+		},
+		{
+			"ipinfo",
+			`{"ip":"5.6.7.8","city":"Tokyo","region":"Tokyo","country":"JP","loc":"35.6,139.6"}`,
+			Info{IP: "5.6.7.8", CountryCode: "JP", Region: "Tokyo", City: "Tokyo"},
+		},
+		{
+			"ipapi",
+			`{"ip":"9.9.9.9","city":"Singapore","region":"Singapore","country":"SG","country_code":"SG","country_name":"Singapore"}`,
+			Info{IP: "9.9.9.9", Country: "Singapore", CountryCode: "SG", Region: "Singapore", City: "Singapore"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, body := range []string{
+		`{"status":"fail","message":"private range"}`,
+		`{"error":true,"reason":"RateLimited"}`,
+		`{"country":"US"}`,
+		`not json`,
+	} {
+		if _, err := Parse([]byte(body)); err == nil {
+			t.Errorf("Parse(%s) expected error", body)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"ip":"5.6.7.8","country":"jp"}`)
+	}))
+	defer srv.Close()
+
+	info, err := Lookup(context.Background(), srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.IP != "5.6.7.8" || info.CountryCode != "JP" {
+		t.Errorf("Lookup() = %+v", info)
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	if !Allowed("CN", nil) {
+		t.Error("empty allowlist should allow every country")
+	}
+	if !Allowed("US", []string{"jp", "us"}) {
+		t.Error("US should be allowed")
+	}
+	if Allowed("CN", []string{"US", "JP"}) {
+		t.Error("CN should not be allowed")
+	}
+	if Allowed("", []string{"US"}) {
+		t.Error("unknown country should not be allowed")
+	}
+}
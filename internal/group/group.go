@@ -0,0 +1,114 @@
+// Package group 管理配置组
+// 配置组是按优先级排列的一组配置，启动时选择第一个可用的配置，
+// 通过网关使用时在上游出错后依次切换到下一个配置
+package group
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fiftyk/claude-switcher/internal/config"
+	"github.com/fiftyk/claude-switcher/internal/profile"
+)
+
+// Group 表示一个配置组
+type Group struct {
+	Name     string   // 显示名称
+	Fallback []string // 按优先级排列的配置名称
+}
+
+// groupPath 返回配置组文件的路径，名称不合法（如包含 / 或 ..）时返回错误，避免访问 groupsDir 以外的文件
+func groupPath(groupsDir, name string) (string, error) {
+	if valid, _ := config.ValidateConfigName(name); !valid {
+		return "", fmt.Errorf("配置组名称格式不正确: %s", name)
+	}
+	return filepath.Join(groupsDir, name+".conf"), nil
+}
+
+// LoadGroup 从 <groupsDir>/<name>.conf 加载配置组
+func LoadGroup(groupsDir, name string) (*Group, error) {
+	path, err := groupPath(groupsDir, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("配置组不存在: %s", name)
+	}
+
+	doc := profile.ParseDocument(data)
+	g := &Group{}
+	g.Name, _ = doc.Get("NAME")
+	if fallback, ok := doc.Get("FALLBACK"); ok {
+		g.Fallback = strings.Fields(strings.ReplaceAll(fallback, ",", " "))
+	}
+
+	if len(g.Fallback) == 0 {
+		return nil, fmt.Errorf("配置组 '%s' 没有成员（FALLBACK 为空）", name)
+	}
+	return g, nil
+}
+
+// SaveGroup 保存配置组
+func SaveGroup(groupsDir, name string, g *Group) error {
+	path, err := groupPath(groupsDir, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(groupsDir, 0700); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Claude Switcher 配置组\n")
+	sb.WriteString("NAME=" + profile.Quote(g.Name) + "\n")
+	sb.WriteString("FALLBACK=" + profile.Quote(strings.Join(g.Fallback, " ")) + "\n")
+
+	return os.WriteFile(path, []byte(sb.String()), 0600)
+}
+
+// Exists 判断配置组是否存在
+func Exists(groupsDir, name string) bool {
+	path, err := groupPath(groupsDir, name)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// ListGroups 列出所有配置组名称
+func ListGroups(groupsDir string) ([]string, error) {
+	entries, err := os.ReadDir(groupsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if name := entry.Name(); strings.HasSuffix(name, ".conf") {
+			names = append(names, strings.TrimSuffix(name, ".conf"))
+		}
+	}
+	return names, nil
+}
+
+// DeleteGroup 删除配置组
+func DeleteGroup(groupsDir, name string) error {
+	filePath, err := groupPath(groupsDir, name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fmt.Errorf("配置组不存在: %s", name)
+	}
+	return os.Remove(filePath)
+}
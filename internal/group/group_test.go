@@ -0,0 +1,111 @@
+package group
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadGroup(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `# Claude Switcher 配置组
+NAME="主备"
+FALLBACK="primary backup,openrouter"
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "ha.conf"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := LoadGroup(tmpDir, "ha")
+	if err != nil {
+		t.Fatalf("LoadGroup() error = %v", err)
+	}
+	if g.Name != "主备" {
+		t.Errorf("Name = %q", g.Name)
+	}
+	want := []string{"primary", "backup", "openrouter"}
+	if !reflect.DeepEqual(g.Fallback, want) {
+		t.Errorf("Fallback = %v, want %v", g.Fallback, want)
+	}
+}
+
+func TestLoadGroupErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	if _, err := LoadGroup(tmpDir, "missing"); err == nil {
+		t.Error("expected error for missing group")
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "empty.conf"), []byte(`NAME="empty"`+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGroup(tmpDir, "empty"); err == nil {
+		t.Error("expected error for group without members")
+	}
+}
+
+func TestSaveListDeleteGroup(t *testing.T) {
+	groupsDir := filepath.Join(t.TempDir(), "groups")
+
+	if names, err := ListGroups(groupsDir); err != nil || len(names) != 0 {
+		t.Errorf("ListGroups() on missing dir = %v, %v", names, err)
+	}
+
+	g := &Group{Name: "ha", Fallback: []string{"a", "b"}}
+	if err := SaveGroup(groupsDir, "ha", g); err != nil {
+		t.Fatal(err)
+	}
+	if !Exists(groupsDir, "ha") {
+		t.Error("group should exist after save")
+	}
+
+	loaded, err := LoadGroup(groupsDir, "ha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, g) {
+		t.Errorf("round trip = %+v, want %+v", loaded, g)
+	}
+
+	names, _ := ListGroups(groupsDir)
+	if !reflect.DeepEqual(names, []string{"ha"}) {
+		t.Errorf("ListGroups() = %v", names)
+	}
+
+	if err := DeleteGroup(groupsDir, "ha"); err != nil {
+		t.Fatal(err)
+	}
+	if Exists(groupsDir, "ha") {
+		t.Error("group should be deleted")
+	}
+}
+
+func TestGroupRejectsInvalidName(t *testing.T) {
+	root := t.TempDir()
+	groupsDir := filepath.Join(root, "groups")
+	profilesDir := filepath.Join(root, "profiles")
+	if err := os.MkdirAll(profilesDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(profilesDir, "work.conf")
+	if err := os.WriteFile(outside, []byte(`NAME="work"`+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "../profiles/work"
+	if err := DeleteGroup(groupsDir, name); err == nil {
+		t.Error("DeleteGroup should reject a name with path separators")
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("file outside groupsDir should survive: %v", err)
+	}
+	if _, err := LoadGroup(groupsDir, name); err == nil {
+		t.Error("LoadGroup should reject a name with path separators")
+	}
+	if err := SaveGroup(groupsDir, name, &Group{Name: "x", Fallback: []string{"a"}}); err == nil {
+		t.Error("SaveGroup should reject a name with path separators")
+	}
+	if Exists(groupsDir, name) {
+		t.Error("Exists should be false for an invalid name")
+	}
+}
@@ -0,0 +1,86 @@
+// Package home 管理每个配置独立的 Claude 配置目录（CLAUDE_CONFIG_DIR）
+package home
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// SharedEntries 从全局 ~/.claude 共享到配置目录的文件和目录
+var SharedEntries = []string{"plugins", "agents", "commands", "skills", "CLAUDE.md"}
+
+// Prepare 创建配置目录，并将 sharedFrom 中的共享资源链接到其中
+// 已存在的同名文件不会被覆盖；无法创建符号链接时改为复制
+func Prepare(dir, sharedFrom string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("创建配置目录失败: %w", err)
+	}
+
+	for _, name := range SharedEntries {
+		src := filepath.Join(sharedFrom, name)
+		dst := filepath.Join(dir, name)
+
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			continue
+		}
+
+		if err := os.Symlink(src, dst); err == nil {
+			continue
+		}
+		if err := copyPath(src, dst); err != nil {
+			return fmt.Errorf("复制 %s 失败: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// copyPath 递归复制文件或目录
+func copyPath(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return copyFile(src, dst, info.Mode().Perm())
+	}
+
+	if err := os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := copyPath(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile 复制单个文件
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,69 @@
+package home
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrepare(t *testing.T) {
+	shared := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(shared, "agents"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(shared, "agents", "reviewer.md"), []byte("agent"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(shared, "CLAUDE.md"), []byte("global"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "homes", "work")
+	// 用户在配置目录中自定义的文件不应被覆盖
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "CLAUDE.md"), []byte("custom"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Prepare(dir, shared); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	// 再次调用应保持幂等
+	if err := Prepare(dir, shared); err != nil {
+		t.Fatalf("Prepare() second call error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "agents", "reviewer.md"))
+	if err != nil || string(data) != "agent" {
+		t.Errorf("agents should be shared, got %q, %v", data, err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "CLAUDE.md"))
+	if string(data) != "custom" {
+		t.Errorf("CLAUDE.md = %q, existing file should be kept", data)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "plugins")); !os.IsNotExist(err) {
+		t.Errorf("plugins does not exist in shared dir and should not be created")
+	}
+}
+
+func TestCopyPath(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "a", "b", "c.txt"), []byte("c"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	if err := copyPath(src, dst); err != nil {
+		t.Fatalf("copyPath() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "a", "b", "c.txt"))
+	if err != nil || string(data) != "c" {
+		t.Errorf("copied file = %q, %v", data, err)
+	}
+}
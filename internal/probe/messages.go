@@ -0,0 +1,334 @@
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultModel 配置未指定模型时探测使用的模型
+const DefaultModel = "claude-sonnet-4-5"
+
+// anthropicVersion 请求头 anthropic-version 的值
+const anthropicVersion = "2023-06-01"
+
+// Status 探测结果分类
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusAuth    Status = "auth"    // 密钥无效或无权限
+	StatusModel   Status = "model"   // 模型不存在或不可用
+	StatusQuota   Status = "quota"   // 额度耗尽或被限流
+	StatusRegion  Status = "region"  // 所在地区不受支持
+	StatusError   Status = "error"   // 其他上游错误
+	StatusNetwork Status = "network" // 无法建立连接
+)
+
+// Label 返回分类的中文说明
+func (s Status) Label() string {
+	switch s {
+	case StatusOK:
+		return "正常"
+	case StatusAuth:
+		return "认证失败"
+	case StatusModel:
+		return "模型不可用"
+	case StatusQuota:
+		return "额度耗尽或被限流"
+	case StatusRegion:
+		return "地区受限"
+	case StatusNetwork:
+		return "网络错误"
+	default:
+		return "上游错误"
+	}
+}
+
+// MessagesOptions 描述一次 Messages API 探测
+type MessagesOptions struct {
+	BaseURL   string // 为空时使用 https://api.anthropic.com
+	AuthToken string // 以 Authorization: Bearer 发送
+	APIKey    string // 以 x-api-key 发送，AuthToken 为空时使用
+	Model     string // 为空时使用 DefaultModel
+	Stream    bool
+	Transport http.RoundTripper // 为空时使用 http.DefaultTransport
+	Timeout   time.Duration     // 为 0 时为 30 秒
+}
+
+// MessagesResult 探测结果
+type MessagesResult struct {
+	Status     Status
+	HTTPStatus int
+	ErrorType  string // 上游返回的 error.type
+	Message    string
+	Model      string
+	Timing     Timing
+	TTFT       time.Duration // 收到第一个输出 token 的耗时
+	Error      error
+}
+
+// Messages 发送一个最小的 POST /v1/messages 请求，按响应判断密钥、模型与额度是否可用
+func Messages(ctx context.Context, opts MessagesOptions) *MessagesResult {
+	model := opts.Model
+	if model == "" {
+		model = DefaultModel
+	}
+	result := &MessagesResult{Model: model}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := newMessagesRequest(ctx, opts, model)
+	if err != nil {
+		result.Status = StatusError
+		result.Error = err
+		result.Message = err.Error()
+		return result
+	}
+
+	transport := opts.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	req, tracer := Trace(req)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		result.Timing = tracer.Done()
+		result.Status = StatusNetwork
+		result.Error = err
+		result.Message = fmt.Sprintf("请求失败: %v", err)
+		return result
+	}
+	defer resp.Body.Close()
+	result.HTTPStatus = resp.StatusCode
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		result.Timing = tracer.Done()
+		errType, msg := parseError(body)
+		result.ErrorType = errType
+		result.Status = Classify(resp.StatusCode, errType, msg)
+		result.Message = describe(resp.StatusCode, errType, msg)
+		return result
+	}
+
+	if opts.Stream && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		readStream(resp.Body, tracer, result)
+	} else {
+		body, err := io.ReadAll(resp.Body)
+		result.Timing = tracer.Done()
+		result.TTFT = result.Timing.TTFB
+		if err != nil {
+			result.Status = StatusNetwork
+			result.Error = err
+			result.Message = fmt.Sprintf("读取响应失败: %v", err)
+			return result
+		}
+		var msg struct {
+			Type  string `json:"type"`
+			Model string `json:"model"`
+		}
+		if err := json.Unmarshal(body, &msg); err != nil || msg.Type != "message" {
+			result.Status = StatusError
+			result.Message = "响应不是有效的 Messages API 格式"
+			return result
+		}
+		if msg.Model != "" {
+			result.Model = msg.Model
+		}
+		result.Status = StatusOK
+	}
+
+	if result.Status == StatusOK {
+		result.Message = fmt.Sprintf("模型 %s 可用 (首 token %v)", result.Model, result.TTFT.Round(time.Millisecond))
+	}
+	return result
+}
+
+// newMessagesRequest 构建探测请求
+func newMessagesRequest(ctx context.Context, opts MessagesOptions, model string) (*http.Request, error) {
+	base := opts.BaseURL
+	if base == "" {
+		base = "https://api.anthropic.com"
+	}
+	body, err := json.Marshal(map[string]any{
+		"model":      model,
+		"max_tokens": 1,
+		"stream":     opts.Stream,
+		"messages": []map[string]string{
+			{"role": "user", "content": "ping"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(base, "/")+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("Base URL 无效: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", anthropicVersion)
+	if opts.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.AuthToken)
+	} else if opts.APIKey != "" {
+		req.Header.Set("x-api-key", opts.APIKey)
+	}
+	return req, nil
+}
+
+// readStream 读取 SSE 响应，记录第一个内容事件的时间
+// 流中出现 error 事件时按错误内容分类
+func readStream(r io.Reader, tracer *Tracer, result *MessagesResult) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			switch event {
+			case "message_start":
+				var start struct {
+					Message struct {
+						Model string `json:"model"`
+					} `json:"message"`
+				}
+				if json.Unmarshal([]byte(data), &start) == nil && start.Message.Model != "" {
+					result.Model = start.Message.Model
+				}
+			case "content_block_delta":
+				if result.TTFT == 0 {
+					result.TTFT = time.Since(tracer.start)
+				}
+			case "error":
+				result.Timing = tracer.Done()
+				errType, msg := parseError([]byte(data))
+				result.ErrorType = errType
+				result.Status = Classify(0, errType, msg)
+				result.Message = describe(0, errType, msg)
+				return
+			case "message_stop":
+				result.Timing = tracer.Done()
+				if result.TTFT == 0 {
+					result.TTFT = result.Timing.TTFB
+				}
+				result.Status = StatusOK
+				return
+			}
+		}
+	}
+
+	result.Timing = tracer.Done()
+	if err := scanner.Err(); err != nil {
+		result.Status = StatusNetwork
+		result.Error = err
+		result.Message = fmt.Sprintf("读取流式响应失败: %v", err)
+		return
+	}
+	result.Status = StatusError
+	result.Message = "流式响应在 message_stop 之前结束"
+}
+
+// parseError 解析 Anthropic 错误响应，也兼容 OpenAI 风格与纯文本
+func parseError(body []byte) (errType, message string) {
+	var e struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if json.Unmarshal(body, &e) == nil {
+		var detail struct {
+			Type    string `json:"type"`
+			Code    any    `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(e.Error, &detail) == nil {
+			if detail.Type == "" && detail.Code != nil {
+				detail.Type = fmt.Sprint(detail.Code)
+			}
+			return detail.Type, detail.Message
+		}
+		var s string
+		if json.Unmarshal(e.Error, &s) == nil {
+			return "", s
+		}
+		if e.Message != "" {
+			return "", e.Message
+		}
+	}
+	return "", strings.TrimSpace(string(body))
+}
+
+// regionHints 上游拒绝所在地区时错误信息中常见的关键词
+var regionHints = []string{"region", "country", "territor", "location", "not available in your"}
+
+// quotaHints 额度耗尽时错误信息中常见的关键词
+var quotaHints = []string{"credit", "quota", "balance", "billing", "insufficient", "余额", "额度"}
+
+// Classify 根据 HTTP 状态码与错误内容判断失败原因
+// 流式响应中的 error 事件没有对应的状态码，status 传 0
+func Classify(status int, errType, message string) Status {
+	msg := strings.ToLower(message)
+	hasAny := func(hints []string) bool {
+		for _, h := range hints {
+			if strings.Contains(msg, h) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case errType == "" && status >= 200 && status < 300:
+		return StatusOK
+	case hasAny(regionHints) && (status == http.StatusForbidden || errType == "permission_error" || errType == "forbidden"):
+		return StatusRegion
+	case errType == "authentication_error" || status == http.StatusUnauthorized:
+		return StatusAuth
+	case errType == "rate_limit_error" || errType == "insufficient_quota" || status == http.StatusTooManyRequests || status == http.StatusPaymentRequired:
+		return StatusQuota
+	case hasAny(quotaHints):
+		return StatusQuota
+	case errType == "not_found_error" || status == http.StatusNotFound:
+		if strings.Contains(msg, "model") {
+			return StatusModel
+		}
+		return StatusError
+	case (errType == "invalid_request_error" || status == http.StatusBadRequest) && strings.Contains(msg, "model"):
+		return StatusModel
+	case errType == "permission_error" || status == http.StatusForbidden:
+		return StatusAuth
+	default:
+		return StatusError
+	}
+}
+
+// describe 生成错误描述
+func describe(status int, errType, message string) string {
+	parts := []string{}
+	if status != 0 {
+		parts = append(parts, fmt.Sprintf("HTTP %d", status))
+	}
+	if errType != "" {
+		parts = append(parts, errType)
+	}
+	if message != "" {
+		parts = append(parts, message)
+	}
+	return strings.Join(parts, ": ")
+}
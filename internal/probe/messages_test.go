@@ -0,0 +1,160 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeAnthropic 模拟 Anthropic Messages API
+// 密钥 sk-good 可用，模型 claude-ok 存在
+func fakeAnthropic(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("anthropic-version") == "" {
+			t.Error("missing anthropic-version header")
+		}
+
+		var req struct {
+			Model     string `json:"model"`
+			MaxTokens int    `json:"max_tokens"`
+			Stream    bool   `json:"stream"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if req.MaxTokens != 1 {
+			t.Errorf("max_tokens = %d, want 1", req.MaxTokens)
+		}
+
+		writeErr := func(status int, typ, msg string) {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"type":"error","error":{"type":%q,"message":%q}}`, typ, msg)
+		}
+
+		switch r.Header.Get("Authorization") {
+		case "Bearer sk-good":
+		case "Bearer sk-broke":
+			writeErr(http.StatusBadRequest, "invalid_request_error", "Your credit balance is too low to access the Anthropic API.")
+			return
+		case "Bearer sk-limited":
+			writeErr(http.StatusTooManyRequests, "rate_limit_error", "Number of requests has exceeded your rate limit")
+			return
+		case "Bearer sk-region":
+			writeErr(http.StatusForbidden, "permission_error", "Request not allowed: unsupported country, region, or territory")
+			return
+		default:
+			if r.Header.Get("x-api-key") != "sk-good" {
+				writeErr(http.StatusUnauthorized, "authentication_error", "invalid x-api-key")
+				return
+			}
+		}
+
+		if req.Model != "claude-ok" {
+			writeErr(http.StatusNotFound, "not_found_error", "model: "+req.Model)
+			return
+		}
+
+		if !req.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-ok","content":[{"type":"text","text":"p"}],"stop_reason":"max_tokens"}`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-ok\"}}\n\n")
+		io.WriteString(w, "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0}\n\n")
+		io.WriteString(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"p\"}}\n\n")
+		io.WriteString(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+}
+
+func TestMessagesClassification(t *testing.T) {
+	srv := fakeAnthropic(t)
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		opts MessagesOptions
+		want Status
+	}{
+		{"ok", MessagesOptions{AuthToken: "sk-good", Model: "claude-ok"}, StatusOK},
+		{"api key", MessagesOptions{APIKey: "sk-good", Model: "claude-ok"}, StatusOK},
+		{"stream", MessagesOptions{AuthToken: "sk-good", Model: "claude-ok", Stream: true}, StatusOK},
+		{"bad token", MessagesOptions{AuthToken: "sk-bad", Model: "claude-ok"}, StatusAuth},
+		{"unknown model", MessagesOptions{AuthToken: "sk-good", Model: "claude-missing"}, StatusModel},
+		{"no credit", MessagesOptions{AuthToken: "sk-broke", Model: "claude-ok"}, StatusQuota},
+		{"rate limited", MessagesOptions{AuthToken: "sk-limited", Model: "claude-ok"}, StatusQuota},
+		{"region", MessagesOptions{AuthToken: "sk-region", Model: "claude-ok"}, StatusRegion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.BaseURL = srv.URL
+			result := Messages(context.Background(), tt.opts)
+			if result.Status != tt.want {
+				t.Fatalf("Status = %s, want %s (%s)", result.Status, tt.want, result.Message)
+			}
+			if tt.want == StatusOK && result.TTFT <= 0 {
+				t.Error("expected time to first token")
+			}
+		})
+	}
+}
+
+func TestMessagesStreamError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{}}\n\n")
+		io.WriteString(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	}))
+	defer srv.Close()
+
+	result := Messages(context.Background(), MessagesOptions{BaseURL: srv.URL, AuthToken: "sk", Stream: true})
+	if result.Status != StatusError || result.ErrorType != "overloaded_error" {
+		t.Errorf("result = %+v, want overloaded error", result)
+	}
+}
+
+func TestMessagesNetworkError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	result := Messages(context.Background(), MessagesOptions{BaseURL: url})
+	if result.Status != StatusNetwork {
+		t.Errorf("Status = %s, want network", result.Status)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		status  int
+		errType string
+		message string
+		want    Status
+	}{
+		{200, "", "", StatusOK},
+		{401, "", "Unauthorized", StatusAuth},
+		{403, "permission_error", "key lacks access", StatusAuth},
+		{403, "", "This service is not available in your region", StatusRegion},
+		{400, "invalid_request_error", "model: claude-x is not supported", StatusModel},
+		{402, "", "Payment required", StatusQuota},
+		{403, "insufficient_quota", "You exceeded your current quota", StatusQuota},
+		{500, "api_error", "internal", StatusError},
+		{0, "overloaded_error", "Overloaded", StatusError},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.status, tt.errType, tt.message); got != tt.want {
+			t.Errorf("Classify(%d, %q, %q) = %s, want %s", tt.status, tt.errType, tt.message, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package probe
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Percentile 使用最近秩法计算百分位数，p 取值 0 到 100
+// samples 为空时返回 0，不会修改 samples 的顺序
+func Percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,30 @@
+package probe
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	samples := []time.Duration{50, 10, 40, 20, 30}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, 10},
+		{50, 30},
+		{95, 50},
+		{100, 50},
+	}
+	for _, tt := range tests {
+		if got := Percentile(samples, tt.p); got != tt.want {
+			t.Errorf("Percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if samples[0] != 50 {
+		t.Error("Percentile should not reorder samples")
+	}
+	if Percentile(nil, 50) != 0 {
+		t.Error("Percentile of no samples should be 0")
+	}
+}
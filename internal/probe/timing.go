@@ -0,0 +1,97 @@
+// Package probe 对上游 API 发起探测请求并记录各阶段耗时
+package probe
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timing 记录一次请求各阶段的耗时，未发生的阶段为 0
+// 经过代理时 DNS 与 Connect 是到代理服务器的耗时
+type Timing struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration // 从开始请求到收到响应首字节
+	Total   time.Duration
+}
+
+// String 以单行文本展示各阶段耗时
+func (t Timing) String() string {
+	parts := []string{}
+	add := func(label string, d time.Duration) {
+		if d > 0 {
+			parts = append(parts, fmt.Sprintf("%s %v", label, d.Round(time.Millisecond)))
+		}
+	}
+	add("DNS", t.DNS)
+	add("TCP", t.Connect)
+	add("TLS", t.TLS)
+	add("首字节", t.TTFB)
+	add("总计", t.Total)
+	return strings.Join(parts, ", ")
+}
+
+// Tracer 通过 httptrace 收集请求的阶段耗时
+type Tracer struct {
+	mu     sync.Mutex
+	start  time.Time
+	dns    time.Time
+	conn   time.Time
+	tls    time.Time
+	timing Timing
+}
+
+// Trace 为请求挂载跟踪，返回新请求与对应的 Tracer
+// 应在发送请求前调用，读取完响应后调用 Tracer.Done
+func Trace(req *http.Request) (*http.Request, *Tracer) {
+	tr := &Tracer{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { tr.mark(&tr.dns) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			tr.since(&tr.dns, &tr.timing.DNS)
+		},
+		ConnectStart: func(string, string) { tr.mark(&tr.conn) },
+		ConnectDone: func(string, string, error) {
+			tr.since(&tr.conn, &tr.timing.Connect)
+		},
+		TLSHandshakeStart: func() { tr.mark(&tr.tls) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tr.since(&tr.tls, &tr.timing.TLS)
+		},
+		GotFirstResponseByte: func() {
+			tr.since(&tr.start, &tr.timing.TTFB)
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), tr
+}
+
+// Done 结束计时并返回结果
+func (tr *Tracer) Done() Timing {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.timing.Total = time.Since(tr.start)
+	return tr.timing
+}
+
+func (tr *Tracer) mark(t *time.Time) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	// 多个地址并发拨号时只记录第一次开始
+	if t.IsZero() {
+		*t = time.Now()
+	}
+}
+
+func (tr *Tracer) since(start *time.Time, d *time.Duration) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if *d == 0 {
+		*d = time.Since(*start)
+	}
+}
@@ -0,0 +1,49 @@
+package probe
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req, tracer := Trace(req)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	timing := tracer.Done()
+
+	if timing.Connect <= 0 {
+		t.Error("expected TCP connect time")
+	}
+	if timing.TLS <= 0 {
+		t.Error("expected TLS handshake time")
+	}
+	if timing.TTFB <= 0 || timing.TTFB > timing.Total {
+		t.Errorf("TTFB = %v, Total = %v", timing.TTFB, timing.Total)
+	}
+}
+
+func TestTimingString(t *testing.T) {
+	s := Timing{Connect: 12 * time.Millisecond, TTFB: 80 * time.Millisecond, Total: 90 * time.Millisecond}.String()
+	for _, want := range []string{"TCP 12ms", "首字节 80ms", "总计 90ms"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("String() = %q, missing %q", s, want)
+		}
+	}
+	if strings.Contains(s, "DNS") || strings.Contains(s, "TLS") {
+		t.Errorf("String() = %q should omit phases that did not happen", s)
+	}
+}
@@ -0,0 +1,407 @@
+package profile
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Document 是按 dotenv 语法解析的 .conf 文件
+// 未修改的行（包括注释、空行和无法识别的行）在 Bytes 中按原样输出
+//
+// 支持的语法:
+//
+//	KEY=value            # 未加引号的值，空白后的 # 开始行内注释
+//	export KEY=value     # 可选的 export 前缀
+//	KEY = "a \"b\" \$c"  # 双引号支持 \n \r \t \\ \" \$ \` 转义，可以跨行
+//	KEY='literal'        # 单引号内不做转义，可以跨行
+type Document struct {
+	lines []*docLine
+}
+
+// docLine 是文档中的一行（跨行的值视为一行）
+// 赋值行由 prefix、valueRaw、suffix 三部分组成，修改值时只替换 valueRaw
+type docLine struct {
+	raw string // 非赋值行的原文，包括换行符
+
+	key      string
+	value    string
+	prefix   string // 行首到值之前，如 "export KEY="
+	valueRaw string // 值的原文，包括引号
+	suffix   string // 值之后的空白、注释与换行符
+}
+
+// Entry 是文档中的一个赋值
+type Entry struct {
+	Key   string
+	Value string
+}
+
+// ParseDocument 解析 dotenv 格式的内容，不会失败
+// 无法解析的行保留原文，不产生赋值
+func ParseDocument(data []byte) *Document {
+	s := string(data)
+	d := &Document{}
+	for pos := 0; pos < len(s); {
+		if l, end, ok := parseAssignment(s, pos); ok {
+			d.lines = append(d.lines, l)
+			pos = end
+			continue
+		}
+		end := lineEnd(s, pos)
+		d.lines = append(d.lines, &docLine{raw: s[pos:end]})
+		pos = end
+	}
+	return d
+}
+
+// InvalidLine 是文档中无法解析为赋值的行
+type InvalidLine struct {
+	Line int    // 行号，从 1 开始
+	Text string // 行的原文，不包括换行符
+}
+
+// Invalid 返回既不是空行、注释，也无法解析为赋值的行（如引号未闭合），这些行中的键不会生效
+func (d *Document) Invalid() []InvalidLine {
+	var invalid []InvalidLine
+	line := 1
+	for _, l := range d.lines {
+		text := l.text()
+		if l.key == "" {
+			trimmed := strings.TrimSpace(text)
+			if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				invalid = append(invalid, InvalidLine{Line: line, Text: strings.TrimRight(text, "\r\n")})
+			}
+		}
+		line += strings.Count(text, "\n")
+	}
+	return invalid
+}
+
+// Bytes 返回文档内容
+func (d *Document) Bytes() []byte {
+	var sb strings.Builder
+	for _, l := range d.lines {
+		sb.WriteString(l.text())
+	}
+	return []byte(sb.String())
+}
+
+// Get 返回键的值，键出现多次时以最后一次为准
+func (d *Document) Get(key string) (string, bool) {
+	if l := d.last(key); l != nil {
+		return l.value, true
+	}
+	return "", false
+}
+
+// Entries 按文件顺序返回所有赋值
+func (d *Document) Entries() []Entry {
+	var entries []Entry
+	for _, l := range d.lines {
+		if l.key != "" {
+			entries = append(entries, Entry{Key: l.key, Value: l.value})
+		}
+	}
+	return entries
+}
+
+// Set 设置键的值
+// 已有的键只替换最后一次出现处的值，保留其 export 前缀与行内注释；值未变化时不做修改
+// 新的键追加到文件末尾
+//
+// 文件中有未闭合引号的行时，修改后的内容可能被解析成不同的赋值，此时不做修改并返回错误
+func (d *Document) Set(key, value string) error {
+	if !validKey(key) {
+		return fmt.Errorf("变量名无效: %s", key)
+	}
+
+	before := d.Entries()
+	saved := make([]docLine, len(d.lines))
+	for i, l := range d.lines {
+		saved[i] = *l
+	}
+	n := len(d.lines)
+
+	d.set(key, value)
+	if d.verify(key, value, before) {
+		return nil
+	}
+
+	d.lines = d.lines[:n]
+	for i := range saved {
+		*d.lines[i] = saved[i]
+	}
+	return fmt.Errorf("无法写入 %s: 文件中有格式错误的行（可能是引号未闭合）", key)
+}
+
+// verify 检查重新解析后 key 的值为 value，且其他赋值与 before 相同
+func (d *Document) verify(key, value string, before []Entry) bool {
+	reparsed := ParseDocument(d.Bytes())
+	if got, ok := reparsed.Get(key); !ok || got != value {
+		return false
+	}
+	return reflect.DeepEqual(withoutKey(before, key), withoutKey(reparsed.Entries(), key))
+}
+
+func withoutKey(entries []Entry, key string) []Entry {
+	var out []Entry
+	for _, e := range entries {
+		if e.Key != key {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// set 设置键的值，不检查修改后的内容
+func (d *Document) set(key, value string) {
+	if l := d.last(key); l != nil {
+		if l.value != value {
+			l.value = value
+			l.valueRaw = Quote(value)
+		}
+		return
+	}
+
+	if n := len(d.lines); n > 0 {
+		last := d.lines[n-1]
+		if !strings.HasSuffix(last.text(), "\n") {
+			if last.key != "" {
+				last.suffix += "\n"
+			} else {
+				last.raw += "\n"
+			}
+		}
+	}
+	d.lines = append(d.lines, &docLine{
+		key:      key,
+		value:    value,
+		prefix:   key + "=",
+		valueRaw: Quote(value),
+		suffix:   "\n",
+	})
+}
+
+// SetSorted 设置多个键的值，新的键按字母顺序追加
+func (d *Document) SetSorted(values map[string]string) error {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := d.Set(k, values[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete 删除键的所有赋值行
+func (d *Document) Delete(key string) {
+	lines := d.lines[:0]
+	for _, l := range d.lines {
+		if l.key != key {
+			lines = append(lines, l)
+		}
+	}
+	d.lines = lines
+}
+
+// AddComment 在文件末尾追加一行注释
+func (d *Document) AddComment(text string) {
+	d.lines = append(d.lines, &docLine{raw: "# " + text + "\n"})
+}
+
+func (d *Document) last(key string) *docLine {
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		if d.lines[i].key == key {
+			return d.lines[i]
+		}
+	}
+	return nil
+}
+
+func (l *docLine) text() string {
+	if l.key == "" {
+		return l.raw
+	}
+	return l.prefix + l.valueRaw + l.suffix
+}
+
+// Quote 将值格式化为单行的双引号字符串
+// 转义 \、"、$ 和 `，换行符写为 \n、\r
+func Quote(value string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\', '"', '$', '`':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// lineEnd 返回从 pos 开始的行结束位置（包括换行符）
+func lineEnd(s string, pos int) int {
+	if i := strings.IndexByte(s[pos:], '\n'); i >= 0 {
+		return pos + i + 1
+	}
+	return len(s)
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r'
+}
+
+func validKey(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if !isKeyByte(key[i], i == 0) {
+			return false
+		}
+	}
+	return key != ""
+}
+
+func isKeyByte(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
+
+// parseAssignment 尝试从 pos 开始解析一个赋值，成功时返回该行与结束位置
+func parseAssignment(s string, pos int) (*docLine, int, bool) {
+	i := pos
+	for i < len(s) && isBlank(s[i]) {
+		i++
+	}
+	if strings.HasPrefix(s[i:], "export") && i+6 < len(s) && (s[i+6] == ' ' || s[i+6] == '\t') {
+		i += 6
+		for i < len(s) && isBlank(s[i]) {
+			i++
+		}
+	}
+
+	keyStart := i
+	for i < len(s) && isKeyByte(s[i], i == keyStart) {
+		i++
+	}
+	if i == keyStart {
+		return nil, 0, false
+	}
+	key := s[keyStart:i]
+
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	if i >= len(s) || s[i] != '=' {
+		return nil, 0, false
+	}
+	i++
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+
+	valueStart := i
+	var value string
+	switch {
+	case i < len(s) && s[i] == '"':
+		v, end, ok := scanDoubleQuoted(s, i)
+		if !ok {
+			return nil, 0, false
+		}
+		value, i = v, end
+	case i < len(s) && s[i] == '\'':
+		end := strings.IndexByte(s[i+1:], '\'')
+		if end < 0 {
+			return nil, 0, false
+		}
+		value, i = s[i+1:i+1+end], i+end+2
+	default:
+		// 未加引号的值到行尾或行内注释为止，去掉末尾空白
+		stop := lineEnd(s, i)
+		if stop > i && s[stop-1] == '\n' {
+			stop--
+		}
+		end := i
+		for j := i; j < stop; j++ {
+			if s[j] == '#' && j > valueStart && isBlank(s[j-1]) {
+				break
+			}
+			if !isBlank(s[j]) {
+				end = j + 1
+			}
+		}
+		value, i = s[valueStart:end], end
+	}
+	valueEnd := i
+
+	// 值之后只允许空白和注释
+	stop := lineEnd(s, i)
+	rest := strings.TrimRight(s[i:stop], "\n")
+	trimmed := strings.TrimLeft(rest, " \t\r")
+	if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+		return nil, 0, false
+	}
+	if trimmed != "" && len(trimmed) == len(rest) && valueEnd > valueStart {
+		// 注释必须与值之间有空白
+		return nil, 0, false
+	}
+
+	return &docLine{
+		key:      key,
+		value:    value,
+		prefix:   s[pos:valueStart],
+		valueRaw: s[valueStart:valueEnd],
+		suffix:   s[valueEnd:stop],
+	}, stop, true
+}
+
+// scanDoubleQuoted 解析从 pos 开始的双引号字符串，返回值与结束引号之后的位置
+func scanDoubleQuoted(s string, pos int) (string, int, bool) {
+	var sb strings.Builder
+	for i := pos + 1; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"':
+			return sb.String(), i + 1, true
+		case '\\':
+			if i+1 >= len(s) {
+				return "", 0, false
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '\\', '"', '$', '`':
+				sb.WriteByte(s[i])
+			default:
+				// 未知转义保留反斜杠
+				sb.WriteByte('\\')
+				sb.WriteByte(s[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", 0, false
+}
@@ -1,12 +1,12 @@
 package profile
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
+	"time"
 )
 
 // Profile 表示一个 Claude 配置
@@ -16,38 +16,94 @@ type Profile struct {
 	BaseURL   string
 	HTTPProxy string
 	HTTPSProxy string
+	NoProxy   string // 不经过代理的地址列表，对应 no_proxy
 	Model     string
+	Protocol  string // 上游 API 协议，为空时视为 anthropic
+	AllowedCountries []string // 允许启动的出口国家代码，为空时不检查
+	ExpiresAt time.Time // token 过期时间，对应 _EXPIRES_AT，零值表示未设置
+	Note      string    // 备注，对应 _NOTE
 	EnvVars   map[string]string
 }
 
-// LoadProfile 从文件加载配置
-func LoadProfile(profilesDir, name string) (*Profile, error) {
-	filePath := filepath.Join(profilesDir, name+".conf")
-	file, err := os.Open(filePath)
+// 上游 API 协议
+const (
+	// ProtocolAnthropic 上游提供 Anthropic Messages API（默认）
+	ProtocolAnthropic = "anthropic"
+	// ProtocolOpenAI 上游只提供 OpenAI Chat Completions API，需要本地转换
+	ProtocolOpenAI = "openai"
+)
+
+// ParseCountryList 解析以逗号或空格分隔的国家代码列表，统一转为大写
+func ParseCountryList(value string) []string {
+	var codes []string
+	for _, c := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		codes = append(codes, strings.ToUpper(c))
+	}
+	return codes
+}
+
+// expiryDateLayout _EXPIRES_AT 的日期格式
+const expiryDateLayout = "2006-01-02"
+
+// ParseExpiry 解析过期时间，支持 2006-01-02（本地时间）和 RFC 3339 格式
+func ParseExpiry(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.ParseInLocation(expiryDateLayout, value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
 	if err != nil {
-		return nil, fmt.Errorf("配置文件不存在: %s", name)
+		return time.Time{}, fmt.Errorf("过期时间格式无效: %s（应为 YYYY-MM-DD）", value)
 	}
-	defer file.Close()
+	return t, nil
+}
 
-	p := &Profile{
-		EnvVars: make(map[string]string),
+// FormatExpiry 将过期时间格式化为 YYYY-MM-DD，零值返回空字符串
+func FormatExpiry(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+	return t.Local().Format(expiryDateLayout)
+}
 
-		// 跳过空行和注释行
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
+// DaysUntilExpiry 返回距离过期还有几天（按本地日期计算），0 表示今天过期，负数表示已过期
+// 未设置过期时间时 ok 为 false
+func (p *Profile) DaysUntilExpiry(now time.Time) (days int, ok bool) {
+	if p.ExpiresAt.IsZero() {
+		return 0, false
+	}
+	date := func(t time.Time) time.Time {
+		y, m, d := t.Local().Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	return int(date(p.ExpiresAt).Sub(date(now)).Hours() / 24), true
+}
 
-		// 解析 VAR=value 格式
-		kv := parseLine(line)
-		if kv == nil {
-			continue
-		}
+// LoadProfile 从文件加载配置，加密的配置自动解密
+func LoadProfile(profilesDir, name string) (*Profile, error) {
+	data, err := ReadFile(profilesDir, name)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("配置文件不存在: %s", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取配置 %s 失败: %w", name, err)
+	}
+	return Parse(data), nil
+}
 
-		key, value := kv[0], kv[1]
+// Parse 解析 .conf 文件内容
+func Parse(data []byte) *Profile {
+	return FromDocument(ParseDocument(data))
+}
+
+// FromDocument 从已解析的文档构建配置
+func FromDocument(doc *Document) *Profile {
+	p := &Profile{
+		EnvVars: make(map[string]string),
+	}
+	httpsProxySet := false
+	for _, e := range doc.Entries() {
+		key, value := e.Key, e.Value
 
 		// 根据键名设置对应字段
 		switch key {
@@ -60,13 +116,27 @@ func LoadProfile(profilesDir, name string) (*Profile, error) {
 		case "http_proxy":
 			p.HTTPProxy = value
 			// 如果没有设置 https_proxy，使用相同的值
-			if p.HTTPSProxy == "" {
+			if !httpsProxySet {
 				p.HTTPSProxy = value
 			}
 		case "https_proxy":
 			p.HTTPSProxy = value
+			httpsProxySet = true
+		case "no_proxy", "NO_PROXY":
+			p.NoProxy = value
 		case "ANTHROPIC_MODEL":
 			p.Model = value
+		case "PROTOCOL":
+			p.Protocol = strings.ToLower(value)
+		case "ALLOWED_COUNTRIES":
+			p.AllowedCountries = ParseCountryList(value)
+		case "_EXPIRES_AT":
+			// 格式无效时视为未设置，由 validate 提示
+			if t, err := ParseExpiry(value); err == nil {
+				p.ExpiresAt = t
+			}
+		case "_NOTE":
+			p.Note = value
 		default:
 			// 其他变量放入 EnvVars
 			if !strings.HasPrefix(key, "_") {
@@ -74,26 +144,166 @@ func LoadProfile(profilesDir, name string) (*Profile, error) {
 			}
 		}
 	}
+	return p
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+// IsFieldKey 判断键是否对应配置的字段或以 _ 开头的内部元数据
+// 解析时这些键不会放入 EnvVars，因此不能作为自定义环境变量
+func IsFieldKey(key string) bool {
+	switch key {
+	case "NAME", "ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_BASE_URL", "http_proxy", "https_proxy",
+		"no_proxy", "NO_PROXY", "ANTHROPIC_MODEL", "PROTOCOL", "ALLOWED_COUNTRIES":
+		return true
+	}
+	return strings.HasPrefix(key, "_")
+}
+
+// Format 将配置格式化为 .conf 文件内容
+// 字段按固定顺序输出，自定义环境变量按名称排序
+func Format(p *Profile) []byte {
+	doc := &Document{}
+	doc.AddComment("Claude Switcher 配置文件")
+	doc.set("NAME", p.Name)
+
+	fields := []struct{ key, value string }{
+		{"ANTHROPIC_AUTH_TOKEN", p.AuthToken},
+		{"ANTHROPIC_BASE_URL", p.BaseURL},
+		{"http_proxy", p.HTTPProxy},
+		{"https_proxy", p.HTTPSProxy},
+		{"no_proxy", p.NoProxy},
+		{"ANTHROPIC_MODEL", p.Model},
+		{"PROTOCOL", p.Protocol},
+		{"ALLOWED_COUNTRIES", strings.Join(p.AllowedCountries, ",")},
+		{"_EXPIRES_AT", FormatExpiry(p.ExpiresAt)},
+		{"_NOTE", p.Note},
+	}
+	for _, f := range fields {
+		if f.value != "" {
+			doc.set(f.key, f.value)
+		}
+	}
+	// 新文档中每一行都能独立解析，无需逐个校验
+	keys := make([]string, 0, len(p.EnvVars))
+	for k := range p.EnvVars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		doc.set(k, p.EnvVars[k])
 	}
 
-	return p, nil
+	return doc.Bytes()
 }
 
-func parseLine(line string) []string {
-	// 匹配 VAR=value 格式
-	re := regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
-	matches := re.FindStringSubmatch(line)
-	if matches == nil {
+// SaveProfile 保存配置到 <profilesDir>/<name>.conf（加密存储锁定时为 <name>.conf.enc）
+// 文件已存在时只改写有变化的键，注释、空行和原有顺序保持不变，新的键按名称顺序追加到末尾
+func SaveProfile(profilesDir, name string, p *Profile) error {
+	data, err := ReadFile(profilesDir, name)
+	if os.IsNotExist(err) {
+		return WriteFile(profilesDir, name, Format(p))
+	}
+	if err != nil {
+		return err
+	}
+
+	doc := ParseDocument(data)
+	if err := Update(doc, p); err != nil {
+		return err
+	}
+	updated := doc.Bytes()
+	if string(updated) == string(data) {
 		return nil
 	}
+	return WriteFile(profilesDir, name, updated)
+}
+
+// SaveRaw 将文件内容原样保存为配置，通过重命名原子替换
+func SaveRaw(profilesDir, name string, data []byte) error {
+	return WriteFile(profilesDir, name, data)
+}
+
+// writeFileAtomic 先写入同目录下的临时文件再重命名，避免写入中断时留下不完整的配置
+func writeFileAtomic(filePath string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
 
-	key := matches[1]
-	value := strings.Trim(matches[2], `"'`)
+// Update 修改文档使其表示配置 p，只改写值有变化的键
+func Update(doc *Document, p *Profile) error {
+	noProxyKey := "no_proxy"
+	if _, ok := doc.Get("no_proxy"); !ok {
+		if _, ok := doc.Get("NO_PROXY"); ok {
+			noProxyKey = "NO_PROXY"
+		}
+	}
 
-	return []string{key, value}
+	// https_proxy 缺省时沿用 http_proxy，因此需按顺序逐个比较解析后的结果
+	fields := []struct {
+		keys    []string
+		value   string
+		current func(*Profile) string
+	}{
+		{[]string{"NAME"}, p.Name, func(c *Profile) string { return c.Name }},
+		{[]string{"ANTHROPIC_AUTH_TOKEN"}, p.AuthToken, func(c *Profile) string { return c.AuthToken }},
+		{[]string{"ANTHROPIC_BASE_URL"}, p.BaseURL, func(c *Profile) string { return c.BaseURL }},
+		{[]string{"http_proxy"}, p.HTTPProxy, func(c *Profile) string { return c.HTTPProxy }},
+		{[]string{"https_proxy"}, p.HTTPSProxy, func(c *Profile) string { return c.HTTPSProxy }},
+		{[]string{noProxyKey, "no_proxy", "NO_PROXY"}, p.NoProxy, func(c *Profile) string { return c.NoProxy }},
+		{[]string{"ANTHROPIC_MODEL"}, p.Model, func(c *Profile) string { return c.Model }},
+		{[]string{"PROTOCOL"}, p.Protocol, func(c *Profile) string { return c.Protocol }},
+		{[]string{"ALLOWED_COUNTRIES"}, strings.Join(p.AllowedCountries, ","), func(c *Profile) string {
+			return strings.Join(c.AllowedCountries, ",")
+		}},
+		{[]string{"_EXPIRES_AT"}, FormatExpiry(p.ExpiresAt), func(c *Profile) string { return FormatExpiry(c.ExpiresAt) }},
+		{[]string{"_NOTE"}, p.Note, func(c *Profile) string { return c.Note }},
+	}
+	for _, f := range fields {
+		if f.current(FromDocument(doc)) == f.value {
+			continue
+		}
+		if f.value == "" {
+			for _, k := range f.keys {
+				doc.Delete(k)
+			}
+			if f.current(FromDocument(doc)) == "" {
+				continue
+			}
+		}
+		if err := doc.Set(f.keys[0], f.value); err != nil {
+			return err
+		}
+	}
+
+	current := FromDocument(doc).EnvVars
+	for k := range current {
+		if _, ok := p.EnvVars[k]; !ok {
+			doc.Delete(k)
+		}
+	}
+	changed := make(map[string]string)
+	for k, v := range p.EnvVars {
+		if old, ok := current[k]; !ok || old != v {
+			changed[k] = v
+		}
+	}
+	return doc.SetSorted(changed)
 }
 
 // ListProfiles 列出所有配置名称
@@ -104,56 +314,52 @@ func ListProfiles(profilesDir string) ([]string, error) {
 		return nil, err
 	}
 
+	// 同一配置可能同时存在明文和加密文件，只列出一次
+	seen := make(map[string]bool)
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
-		name := entry.Name()
-		if strings.HasSuffix(name, ".conf") {
-			names = append(names, strings.TrimSuffix(name, ".conf"))
+		if name, ok := profileName(entry.Name()); ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
 		}
 	}
+	sort.Strings(names)
 
 	return names, nil
 }
 
 // DeleteProfile 删除配置
 func DeleteProfile(profilesDir, name string) error {
-	filePath := filepath.Join(profilesDir, name+".conf")
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	if !Exists(profilesDir, name) {
 		return fmt.Errorf("配置文件不存在: %s", name)
 	}
-	return os.Remove(filePath)
+	return removeFiles(profilesDir, name)
 }
 
 // CopyProfile 复制配置
 func CopyProfile(profilesDir, srcName, dstName string) error {
-	srcFile := filepath.Join(profilesDir, srcName+".conf")
-	dstFile := filepath.Join(profilesDir, dstName+".conf")
-
-	if _, err := os.Stat(srcFile); os.IsNotExist(err) {
+	content, err := ReadFile(profilesDir, srcName)
+	if os.IsNotExist(err) {
 		return fmt.Errorf("源配置不存在: %s", srcName)
 	}
-
-	content, err := os.ReadFile(srcFile)
 	if err != nil {
 		return err
 	}
 
-	// 更新 NAME 字段
-	re := regexp.MustCompile(`(?m)^NAME=.*$`)
-	content = re.ReplaceAllFunc(content, func(match []byte) []byte {
-		return []byte(fmt.Sprintf(`NAME="%s"`, dstName))
-	})
+	// 更新 NAME 字段，其余内容保持不变
+	doc := ParseDocument(content)
+	if err := doc.Set("NAME", dstName); err != nil {
+		return err
+	}
 
-	return os.WriteFile(dstFile, content, 0600)
+	return WriteFile(profilesDir, dstName, doc.Bytes())
 }
 
 // RenameProfile 重命名配置
 func RenameProfile(profilesDir, oldName, newName string) error {
-	srcFile := filepath.Join(profilesDir, oldName+".conf")
-
-	if _, err := os.Stat(srcFile); os.IsNotExist(err) {
+	if !Exists(profilesDir, oldName) {
 		return fmt.Errorf("配置不存在: %s", oldName)
 	}
 
@@ -163,5 +369,5 @@ func RenameProfile(profilesDir, oldName, newName string) error {
 	}
 
 	// 删除原文件
-	return os.Remove(srcFile)
+	return removeFiles(profilesDir, oldName)
 }
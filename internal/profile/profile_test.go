@@ -3,7 +3,10 @@ package profile
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadProfile(t *testing.T) {
@@ -86,6 +89,115 @@ ANOTHER_VAR="another"
 	}
 }
 
+func TestLoadProfileProtocol(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `NAME="openrouter"
+ANTHROPIC_BASE_URL="https://openrouter.ai/api/v1"
+PROTOCOL="OpenAI"
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "or.conf"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadProfile(tmpDir, "or")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Protocol != ProtocolOpenAI {
+		t.Errorf("Protocol = %q, want %q", p.Protocol, ProtocolOpenAI)
+	}
+	if _, ok := p.EnvVars["PROTOCOL"]; ok {
+		t.Error("PROTOCOL should not be exported as an environment variable")
+	}
+}
+
+func TestLoadProfileAllowedCountries(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `NAME="relay"
+ALLOWED_COUNTRIES="us, jp SG"
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "relay.conf"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadProfile(tmpDir, "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(p.AllowedCountries, ","); got != "US,JP,SG" {
+		t.Errorf("AllowedCountries = %q, want US,JP,SG", got)
+	}
+	if _, ok := p.EnvVars["ALLOWED_COUNTRIES"]; ok {
+		t.Error("ALLOWED_COUNTRIES should not be exported as an environment variable")
+	}
+}
+
+func TestLoadProfileExpiryMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `NAME="relay"
+_EXPIRES_AT="2026-03-01"
+_NOTE="月付，续费后更新"
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "relay.conf"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadProfile(tmpDir, "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local); !p.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", p.ExpiresAt, want)
+	}
+	if p.Note != "月付，续费后更新" {
+		t.Errorf("Note = %q", p.Note)
+	}
+	if len(p.EnvVars) != 0 {
+		t.Errorf("metadata should not be exported as environment variables: %v", p.EnvVars)
+	}
+
+	// 格式无效时视为未设置
+	if p := Parse([]byte("_EXPIRES_AT=soon\n")); !p.ExpiresAt.IsZero() {
+		t.Errorf("invalid _EXPIRES_AT should be ignored, got %v", p.ExpiresAt)
+	}
+	if p := Parse([]byte("_EXPIRES_AT=2026-03-01T10:00:00Z\n")); p.ExpiresAt.IsZero() {
+		t.Error("RFC 3339 _EXPIRES_AT should be accepted")
+	}
+}
+
+func TestIsFieldKey(t *testing.T) {
+	// 与解析保持一致：IsFieldKey 为 true 的键不会出现在 EnvVars 中
+	for _, key := range []string{"NAME", "ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_BASE_URL", "http_proxy", "https_proxy",
+		"no_proxy", "NO_PROXY", "ANTHROPIC_MODEL", "PROTOCOL", "ALLOWED_COUNTRIES", "_EXPIRES_AT", "_NOTE", "_OTHER",
+		"ANTHROPIC_API_KEY", "HTTP_PROXY", "name"} {
+		_, inEnv := Parse([]byte(key + "=x\n")).EnvVars[key]
+		if IsFieldKey(key) == inEnv {
+			t.Errorf("IsFieldKey(%q) = %v, but parsed into EnvVars = %v", key, IsFieldKey(key), inEnv)
+		}
+	}
+}
+
+func TestDaysUntilExpiry(t *testing.T) {
+	p := &Profile{ExpiresAt: time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)}
+	tests := []struct {
+		now  time.Time
+		want int
+	}{
+		{time.Date(2026, 3, 1, 23, 59, 0, 0, time.Local), 9},
+		{time.Date(2026, 3, 10, 8, 0, 0, 0, time.Local), 0},
+		{time.Date(2026, 3, 12, 0, 0, 0, 0, time.Local), -2},
+	}
+	for _, tt := range tests {
+		if got, ok := p.DaysUntilExpiry(tt.now); !ok || got != tt.want {
+			t.Errorf("DaysUntilExpiry(%v) = %d, %v, want %d", tt.now, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := (&Profile{}).DaysUntilExpiry(time.Now()); ok {
+		t.Error("profile without _EXPIRES_AT should report ok = false")
+	}
+}
+
 func TestLoadProfileSkipsCommentsAndEmpty(t *testing.T) {
 	tmpDir := t.TempDir()
 	profileName := "skip-test"
@@ -245,3 +357,182 @@ ANTHROPIC_AUTH_TOKEN="sk-token"
 		t.Error("New profile file was not created")
 	}
 }
+
+func TestFormat(t *testing.T) {
+	p := &Profile{
+		Name:       "Test",
+		AuthToken:  "sk-test",
+		BaseURL:    "https://api.example.com",
+		HTTPProxy:  "http://127.0.0.1:7890",
+		HTTPSProxy: "http://127.0.0.1:7890",
+		Model:      "claude-3-5-sonnet",
+		EnvVars:    map[string]string{"CUSTOM": "value"},
+	}
+
+	result := string(Format(p))
+
+	// 验证输出包含必要内容
+	if !strings.Contains(result, "NAME=\"Test\"") {
+		t.Error("expected NAME in output")
+	}
+	if !strings.Contains(result, "ANTHROPIC_AUTH_TOKEN=\"sk-test\"") {
+		t.Error("expected AuthToken in output")
+	}
+	if !strings.Contains(result, "ANTHROPIC_BASE_URL=\"https://api.example.com\"") {
+		t.Error("expected BaseURL in output")
+	}
+	if !strings.Contains(result, "CUSTOM=\"value\"") {
+		t.Error("expected custom env var in output")
+	}
+}
+
+func TestFormat_AllFields(t *testing.T) {
+	p := &Profile{
+		Name:       "Test Profile",
+		AuthToken:  "sk-test-token",
+		BaseURL:    "https://api.example.com",
+		HTTPProxy:  "http://127.0.0.1:7890",
+		HTTPSProxy: "http://127.0.0.1:7890",
+		Model:      "claude-3-5-sonnet",
+		EnvVars:    map[string]string{"CUSTOM_VAR": "custom_value"},
+	}
+
+	result := string(Format(p))
+
+	// 验证所有字段都被格式化
+	if !strings.Contains(result, "NAME=\"Test Profile\"") {
+		t.Error("expected NAME in output")
+	}
+	if !strings.Contains(result, "ANTHROPIC_AUTH_TOKEN=\"sk-test-token\"") {
+		t.Error("expected AuthToken in output")
+	}
+	if !strings.Contains(result, "ANTHROPIC_BASE_URL=\"https://api.example.com\"") {
+		t.Error("expected BaseURL in output")
+	}
+	if !strings.Contains(result, "http_proxy=\"http://127.0.0.1:7890\"") {
+		t.Error("expected http_proxy in output")
+	}
+	if !strings.Contains(result, "ANTHROPIC_MODEL=\"claude-3-5-sonnet\"") {
+		t.Error("expected Model in output")
+	}
+	if !strings.Contains(result, "CUSTOM_VAR=\"custom_value\"") {
+		t.Error("expected custom env var in output")
+	}
+}
+
+func TestFormat_EmptyFields(t *testing.T) {
+	p := &Profile{
+		Name: "Minimal",
+	}
+
+	result := string(Format(p))
+
+	// 验证包含 NAME
+	if !strings.Contains(result, "NAME=\"Minimal\"") {
+		t.Error("expected NAME in output")
+	}
+	// 验证只有2行（注释 + NAME）
+	lines := strings.Split(strings.TrimSpace(result), "\n")
+	if len(lines) != 2 {
+		t.Errorf("expected 2 lines (comment + NAME), got %d", len(lines))
+	}
+}
+
+func TestSaveProfileNew(t *testing.T) {
+	tmpDir := t.TempDir()
+	p := &Profile{Name: "New", AuthToken: "sk-new", EnvVars: map[string]string{"B": "2", "A": "1"}}
+
+	if err := SaveProfile(tmpDir, "new", p); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(tmpDir, "new.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(Format(p)) {
+		t.Errorf("new profile should be written with Format, got:\n%s", data)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	content := "# 注释\nNAME=\"Work\"\nhttp_proxy=http://127.0.0.1:7890\nNO_PROXY=localhost\nOLD_VAR=x\nKEEP=1  # 保留\n"
+	doc := ParseDocument([]byte(content))
+
+	p := Parse([]byte(content))
+	if err := Update(doc, p); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(doc.Bytes()); got != content {
+		t.Errorf("unchanged profile should not modify document, got:\n%s", got)
+	}
+
+	p.HTTPProxy = "http://127.0.0.1:8080"
+	p.HTTPSProxy = "http://127.0.0.1:8080"
+	p.NoProxy = "localhost,.corp"
+	delete(p.EnvVars, "OLD_VAR")
+	p.EnvVars["Z_NEW"] = "z"
+	p.EnvVars["A_NEW"] = "a"
+	if err := Update(doc, p); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "# 注释\nNAME=\"Work\"\nhttp_proxy=\"http://127.0.0.1:8080\"\nNO_PROXY=\"localhost,.corp\"\nKEEP=1  # 保留\nA_NEW=\"a\"\nZ_NEW=\"z\"\n"
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("Bytes() =\n%s\nwant\n%s", got, want)
+	}
+	if got := FromDocument(doc); !reflect.DeepEqual(got, p) {
+		t.Errorf("FromDocument() = %+v, want %+v", got, p)
+	}
+
+	// https_proxy 与 http_proxy 不同时单独写入，清空字段时删除对应的键
+	p.HTTPSProxy = "http://127.0.0.1:9090"
+	p.NoProxy = ""
+	if err := Update(doc, p); err != nil {
+		t.Fatal(err)
+	}
+	if got := FromDocument(doc); !reflect.DeepEqual(got, p) {
+		t.Errorf("FromDocument() = %+v, want %+v", got, p)
+	}
+	if _, ok := doc.Get("NO_PROXY"); ok {
+		t.Error("NO_PROXY should be deleted when cleared")
+	}
+
+	// 元数据以 _ 开头的键保存
+	p.ExpiresAt = time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	p.Note = "备用"
+	if err := Update(doc, p); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := doc.Get("_EXPIRES_AT"); got != "2026-03-01" {
+		t.Errorf("_EXPIRES_AT = %q, want 2026-03-01", got)
+	}
+	if got := FromDocument(doc); !reflect.DeepEqual(got, p) {
+		t.Errorf("FromDocument() = %+v, want %+v", got, p)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("TEST_WORK_KEY", "sk-resolved")
+	p := &Profile{
+		Name:      "work",
+		AuthToken: "${env:TEST_WORK_KEY}",
+		BaseURL:   "https://api.example.com",
+		EnvVars:   map[string]string{"ANTHROPIC_API_KEY": "${env:TEST_WORK_KEY}", "PLAIN": "x"},
+	}
+
+	r, err := Resolve(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.AuthToken != "sk-resolved" || r.EnvVars["ANTHROPIC_API_KEY"] != "sk-resolved" || r.EnvVars["PLAIN"] != "x" {
+		t.Errorf("Resolve() = %+v", r)
+	}
+	if p.AuthToken != "${env:TEST_WORK_KEY}" || p.EnvVars["ANTHROPIC_API_KEY"] != "${env:TEST_WORK_KEY}" {
+		t.Error("Resolve should not modify the original profile")
+	}
+
+	p.AuthToken = "${env:TEST_MISSING_KEY}"
+	if _, err := Resolve(p); err == nil || !strings.Contains(err.Error(), "ANTHROPIC_AUTH_TOKEN") {
+		t.Errorf("expected error naming the key, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package profile
+
+import (
+	"fmt"
+
+	"github.com/fiftyk/claude-switcher/internal/secret"
+)
+
+// Resolve 返回解析了密钥引用（${env:NAME}、file:、cmd:）的配置副本，p 本身不变
+// 启动、同步和 shell 导出时使用解析后的配置；显示、比较和 JSON/YAML 导出时使用原配置，只显示引用
+func Resolve(p *Profile) (*Profile, error) {
+	r := *p
+	fields := []struct {
+		key   string
+		value *string
+	}{
+		{"ANTHROPIC_AUTH_TOKEN", &r.AuthToken},
+		{"ANTHROPIC_BASE_URL", &r.BaseURL},
+		{"http_proxy", &r.HTTPProxy},
+		{"https_proxy", &r.HTTPSProxy},
+		{"no_proxy", &r.NoProxy},
+		{"ANTHROPIC_MODEL", &r.Model},
+	}
+	for _, f := range fields {
+		v, err := secret.Resolve(*f.value)
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", f.key, err)
+		}
+		*f.value = v
+	}
+
+	r.EnvVars = make(map[string]string, len(p.EnvVars))
+	for k, v := range p.EnvVars {
+		resolved, err := secret.Resolve(v)
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", k, err)
+		}
+		r.EnvVars[k] = resolved
+	}
+	return &r, nil
+}
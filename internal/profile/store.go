@@ -0,0 +1,195 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fiftyk/claude-switcher/internal/vault"
+)
+
+// EncryptedExt 加密配置文件的扩展名
+const EncryptedExt = ".conf.enc"
+
+// Cipher 加密和解密配置文件内容
+type Cipher interface {
+	Seal(plaintext []byte) ([]byte, error)
+	Open(data []byte) ([]byte, error)
+}
+
+// CipherSource 返回读写加密配置使用的 Cipher，由命令行层设置（提示输入口令或交给 agent 处理）
+var CipherSource func(profilesDir string) (Cipher, error)
+
+// ErrNoKey 表示配置已加密但无法获取密钥
+var ErrNoKey = errors.New("配置已加密，需要口令解锁")
+
+func plainPath(profilesDir, name string) string {
+	return filepath.Join(profilesDir, name+".conf")
+}
+
+func encryptedPath(profilesDir, name string) string {
+	return filepath.Join(profilesDir, name+EncryptedExt)
+}
+
+// cipherFor 通过 CipherSource 获取 Cipher
+func cipherFor(profilesDir string) (Cipher, error) {
+	if CipherSource == nil {
+		return nil, ErrNoKey
+	}
+	return CipherSource(profilesDir)
+}
+
+// Exists 判断配置是否存在（明文或加密）
+func Exists(profilesDir, name string) bool {
+	if _, err := os.Stat(plainPath(profilesDir, name)); err == nil {
+		return true
+	}
+	_, err := os.Stat(encryptedPath(profilesDir, name))
+	return err == nil
+}
+
+// IsEncrypted 判断配置是否以加密形式保存
+func IsEncrypted(profilesDir, name string) bool {
+	if _, err := os.Stat(plainPath(profilesDir, name)); err == nil {
+		return false
+	}
+	_, err := os.Stat(encryptedPath(profilesDir, name))
+	return err == nil
+}
+
+// ReadFile 读取配置文件内容，加密的配置自动解密
+// 同时存在两种形式时以明文文件为准；都不存在时返回的错误满足 os.IsNotExist
+func ReadFile(profilesDir, name string) ([]byte, error) {
+	data, err := os.ReadFile(plainPath(profilesDir, name))
+	if !os.IsNotExist(err) {
+		return data, err
+	}
+
+	data, err = os.ReadFile(encryptedPath(profilesDir, name))
+	if err != nil {
+		return nil, err
+	}
+	c, err := cipherFor(profilesDir)
+	if err != nil {
+		return nil, err
+	}
+	return c.Open(data)
+}
+
+// WriteFile 原子写入配置文件内容
+// 加密存储处于锁定状态时加密保存，否则保存为明文，并删除另一种形式的旧文件
+func WriteFile(profilesDir, name string, data []byte) error {
+	encrypt := false
+	if v, err := vault.Load(profilesDir); err == nil {
+		encrypt = v.Locked
+	} else if err != vault.ErrNotInitialized {
+		return err
+	}
+
+	target, other := plainPath(profilesDir, name), encryptedPath(profilesDir, name)
+	if encrypt {
+		c, err := cipherFor(profilesDir)
+		if err != nil {
+			return err
+		}
+		if data, err = c.Seal(data); err != nil {
+			return err
+		}
+		target, other = other, target
+	}
+
+	if err := writeFileAtomic(target, data); err != nil {
+		return err
+	}
+	if err := os.Remove(other); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// removeFiles 删除配置的明文和加密文件
+func removeFiles(profilesDir, name string) error {
+	for _, path := range []string{plainPath(profilesDir, name), encryptedPath(profilesDir, name)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncryptAll 用 c 加密所有明文配置并删除明文文件，返回加密的配置数
+func EncryptAll(profilesDir string, c Cipher) (int, error) {
+	names, err := ListProfiles(profilesDir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, name := range names {
+		data, err := os.ReadFile(plainPath(profilesDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		sealed, err := c.Seal(data)
+		if err != nil {
+			return count, err
+		}
+		if err := writeFileAtomic(encryptedPath(profilesDir, name), sealed); err != nil {
+			return count, err
+		}
+		if err := os.Remove(plainPath(profilesDir, name)); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// DecryptAll 用 c 解密所有加密配置并保存为明文，返回解密的配置数
+// 同名明文文件已存在时保留明文文件，只删除加密文件
+func DecryptAll(profilesDir string, c Cipher) (int, error) {
+	names, err := ListProfiles(profilesDir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, name := range names {
+		sealed, err := os.ReadFile(encryptedPath(profilesDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		if _, err := os.Stat(plainPath(profilesDir, name)); os.IsNotExist(err) {
+			data, err := c.Open(sealed)
+			if err != nil {
+				return count, fmt.Errorf("%s: %w", name, err)
+			}
+			if err := writeFileAtomic(plainPath(profilesDir, name), data); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if err := os.Remove(encryptedPath(profilesDir, name)); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// profileName 从文件名中取出配置名称，不是配置文件时返回 false
+func profileName(fileName string) (string, bool) {
+	for _, ext := range []string{EncryptedExt, ".conf"} {
+		if strings.HasSuffix(fileName, ext) {
+			return strings.TrimSuffix(fileName, ext), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,106 @@
+package profile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fiftyk/claude-switcher/internal/vault"
+)
+
+// setupVault 在 dir 中创建锁定的加密存储，并让 CipherSource 使用其密钥
+func setupVault(t *testing.T, dir string) vault.Key {
+	t.Helper()
+	orig := vault.DefaultParams
+	vault.DefaultParams = vault.Params{N: 1 << 10, R: 8, P: 1}
+	t.Cleanup(func() { vault.DefaultParams = orig })
+
+	v, key, err := vault.Create("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Locked = true
+	if err := v.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	origSource := CipherSource
+	CipherSource = func(string) (Cipher, error) { return vault.Key(key), nil }
+	t.Cleanup(func() { CipherSource = origSource })
+	return key
+}
+
+func TestEncryptedStore(t *testing.T) {
+	dir := t.TempDir()
+	plain := &Profile{Name: "work", AuthToken: "sk-work", EnvVars: map[string]string{}}
+	if err := SaveProfile(dir, "work", plain); err != nil {
+		t.Fatal(err)
+	}
+
+	key := setupVault(t, dir)
+	n, err := EncryptAll(dir, key)
+	if err != nil || n != 1 {
+		t.Fatalf("EncryptAll() = %d, %v; want 1", n, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "work"+EncryptedExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("sk-work")) {
+		t.Error("token should not appear in encrypted file")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "work.conf")); !os.IsNotExist(err) {
+		t.Error("plaintext file should be removed after encryption")
+	}
+
+	// 读取、修改、复制、重命名都透明处理加密文件
+	p, err := LoadProfile(dir, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, plain) {
+		t.Errorf("LoadProfile() = %+v, want %+v", p, plain)
+	}
+	p.Model = "claude-opus-4-1"
+	if err := SaveProfile(dir, "work", p); err != nil {
+		t.Fatal(err)
+	}
+	if err := CopyProfile(dir, "work", "copy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RenameProfile(dir, "copy", "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(dir, "renamed") || Exists(dir, "copy") {
+		t.Error("renamed profile should be encrypted and the old name removed")
+	}
+	if got, _ := LoadProfile(dir, "renamed"); got == nil || got.Model != "claude-opus-4-1" || got.Name != "renamed" {
+		t.Errorf("LoadProfile(renamed) = %+v", got)
+	}
+	if names, _ := ListProfiles(dir); !reflect.DeepEqual(names, []string{"renamed", "work"}) {
+		t.Errorf("ListProfiles() = %v", names)
+	}
+
+	// 没有密钥时无法读取
+	CipherSource = nil
+	if _, err := LoadProfile(dir, "work"); err == nil {
+		t.Error("expected error without a key")
+	}
+	CipherSource = func(string) (Cipher, error) { return key, nil }
+
+	n, err = DecryptAll(dir, key)
+	if err != nil || n != 2 {
+		t.Fatalf("DecryptAll() = %d, %v; want 2", n, err)
+	}
+	if IsEncrypted(dir, "work") {
+		t.Error("work should be plaintext after DecryptAll")
+	}
+	if err := DeleteProfile(dir, "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("expected work.conf and %s only, got %d entries", vault.FileName, len(entries))
+	}
+}
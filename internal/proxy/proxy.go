@@ -0,0 +1,197 @@
+// Package proxy 根据配置中的代理设置构建 HTTP Transport
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Config 表示一组代理设置，与 http_proxy、https_proxy、no_proxy 环境变量对应
+type Config struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// ProxyFunc 返回用于 http.Transport.Proxy 的函数
+// https 请求使用 HTTPSProxy（未设置时使用 HTTPProxy），命中 NoProxy 的主机直接连接
+func (c Config) ProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	httpURL, err := parseProxy(c.HTTPProxy)
+	if err != nil {
+		return nil, err
+	}
+	httpsURL, err := parseProxy(c.HTTPSProxy)
+	if err != nil {
+		return nil, err
+	}
+	if httpsURL == nil {
+		httpsURL = httpURL
+	}
+	if httpURL == nil && httpsURL == nil {
+		return nil, nil
+	}
+
+	noProxy := ParseNoProxy(c.NoProxy)
+	return func(req *http.Request) (*url.URL, error) {
+		if noProxy.Match(req.URL.Host) {
+			return nil, nil
+		}
+		if req.URL.Scheme == "https" {
+			return httpsURL, nil
+		}
+		return httpURL, nil
+	}, nil
+}
+
+// NewTransport 创建使用该代理设置的 Transport
+// 未设置代理时直接连接，不读取当前进程的代理环境变量
+func NewTransport(c Config) (*http.Transport, error) {
+	proxyFunc, err := c.ProxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = proxyFunc
+	return t, nil
+}
+
+// parseProxy 解析代理地址，省略协议时视为 http
+func parseProxy(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("代理地址无效: %s", Mask(raw))
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("不支持的代理协议: %s", u.Scheme)
+	}
+	return u, nil
+}
+
+// IsSOCKS 判断代理地址是否为 SOCKS5 代理
+func IsSOCKS(raw string) bool {
+	return strings.HasPrefix(raw, "socks5://") || strings.HasPrefix(raw, "socks5h://")
+}
+
+// Mask 遮蔽代理地址中的密码，用于显示和日志
+// 无法解析的地址原样返回其 @ 之后的部分，避免泄露认证信息
+func Mask(raw string) string {
+	if !strings.Contains(raw, "@") {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return "****@" + raw[strings.LastIndex(raw, "@")+1:]
+	}
+	return u.Redacted()
+}
+
+// NoProxy 是解析后的 no_proxy 列表
+type NoProxy struct {
+	all     bool
+	domains []noProxyEntry
+	nets    []*net.IPNet
+}
+
+type noProxyEntry struct {
+	host  string // 小写；以点开头时只匹配子域名
+	port  string // 为空时匹配任意端口
+	exact bool   // IP 地址只做精确匹配
+}
+
+// ParseNoProxy 解析逗号或空格分隔的 no_proxy 列表
+// 支持 *、主机名、.域名（只匹配子域名）、域名（匹配自身及子域名）、IP、CIDR 和可选端口
+func ParseNoProxy(value string) NoProxy {
+	var np NoProxy
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		if item == "*" {
+			np.all = true
+			continue
+		}
+		if _, ipnet, err := net.ParseCIDR(item); err == nil {
+			np.nets = append(np.nets, ipnet)
+			continue
+		}
+
+		host, port := item, ""
+		if h, p, err := net.SplitHostPort(item); err == nil {
+			host, port = h, p
+		}
+		host = strings.Trim(host, "[]")
+
+		if ip := net.ParseIP(host); ip != nil {
+			np.domains = append(np.domains, noProxyEntry{host: ip.String(), port: port, exact: true})
+			continue
+		}
+
+		// *.example.com 与 .example.com 等价，只匹配子域名
+		host = strings.TrimPrefix(host, "*")
+		np.domains = append(np.domains, noProxyEntry{host: host, port: port})
+	}
+	return np
+}
+
+// Match 判断 host（可带端口）是否应绕过代理
+// 本机回环地址总是直接连接
+func (np NoProxy) Match(hostport string) bool {
+	host, port := hostport, ""
+	if h, p, err := net.SplitHostPort(hostport); err == nil {
+		host, port = h, p
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+	if np.all {
+		return true
+	}
+
+	if ip != nil {
+		for _, n := range np.nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		host = ip.String()
+	}
+
+	for _, e := range np.domains {
+		if e.port != "" && e.port != port {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(e.host, "."):
+			if strings.HasSuffix(host, e.host) {
+				return true
+			}
+		case e.exact:
+			if host == e.host {
+				return true
+			}
+		default:
+			if host == e.host || strings.HasSuffix(host, "."+e.host) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+// Package secret 解析配置中的密钥引用
+//
+// 配置的值可以是以下形式的引用，在启动或同步时才读取真实的值:
+//
+//	${env:NAME}     环境变量 NAME
+//	file:<路径>      文件内容（去掉首尾空白），路径支持 ~/
+//	cmd:<命令>       命令的标准输出（去掉首尾空白），通过 shell 执行
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandTimeout 执行 cmd: 引用的超时时间
+var CommandTimeout = 10 * time.Second
+
+// CacheTTL cmd: 引用结果在本进程中的缓存时间
+var CacheTTL = time.Minute
+
+// envRef 匹配 ${env:NAME}
+var envRef = regexp.MustCompile(`^\$\{env:([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// IsRef 判断值是否为密钥引用
+func IsRef(value string) bool {
+	return envRef.MatchString(value) || strings.HasPrefix(value, "file:") || strings.HasPrefix(value, "cmd:")
+}
+
+type cacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]cacheEntry)
+)
+
+// ClearCache 清除 cmd: 引用的缓存
+func ClearCache() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cache = make(map[string]cacheEntry)
+}
+
+// Resolve 解析引用，不是引用的值原样返回
+func Resolve(value string) (string, error) {
+	if m := envRef.FindStringSubmatch(value); m != nil {
+		v := os.Getenv(m[1])
+		if v == "" {
+			return "", fmt.Errorf("环境变量 %s 未设置", m[1])
+		}
+		return v, nil
+	}
+	if path, ok := strings.CutPrefix(value, "file:"); ok {
+		return readFile(path)
+	}
+	if command, ok := strings.CutPrefix(value, "cmd:"); ok {
+		return runCached(strings.TrimSpace(command))
+	}
+	return value, nil
+}
+
+// readFile 读取文件内容作为密钥
+func readFile(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("无法读取密钥文件: %w", err)
+	}
+	v := strings.TrimSpace(string(data))
+	if v == "" {
+		return "", fmt.Errorf("密钥文件为空: %s", path)
+	}
+	return v, nil
+}
+
+// runCached 执行命令，CacheTTL 内相同的命令直接返回上次的结果
+func runCached(command string) (string, error) {
+	cacheMu.Lock()
+	e, ok := cache[command]
+	cacheMu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.value, nil
+	}
+
+	v, err := run(command)
+	if err != nil {
+		return "", err
+	}
+	cacheMu.Lock()
+	cache[command] = cacheEntry{value: v, expires: time.Now().Add(CacheTTL)}
+	cacheMu.Unlock()
+	return v, nil
+}
+
+// run 通过 shell 执行命令并返回标准输出
+// 标准输入和标准错误连接到当前终端，便于 pass、gpg 等工具提示输入口令
+func run(command string) (string, error) {
+	if command == "" {
+		return "", fmt.Errorf("cmd: 引用缺少命令")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), CommandTimeout)
+	defer cancel()
+
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		c = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout bytes.Buffer
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, &stdout, os.Stderr
+	// 命令启动的子进程仍持有输出管道时，超时后不再等待
+	c.WaitDelay = time.Second
+
+	err := c.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("命令执行超时（%s）: %s", CommandTimeout, command)
+	}
+	if err != nil {
+		return "", fmt.Errorf("命令执行失败: %s: %w", command, err)
+	}
+
+	v := strings.TrimSpace(stdout.String())
+	if v == "" {
+		return "", fmt.Errorf("命令没有输出: %s", command)
+	}
+	return v, nil
+}
@@ -0,0 +1,97 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsRef(t *testing.T) {
+	tests := map[string]bool{
+		"${env:WORK_KEY}":        true,
+		"file:~/.secrets/key":    true,
+		"cmd:pass show x":        true,
+		"sk-plain":               false,
+		"${env:1BAD}":            false,
+		"prefix ${env:WORK_KEY}": false,
+		"":                       false,
+	}
+	for value, want := range tests {
+		if got := IsRef(value); got != want {
+			t.Errorf("IsRef(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestResolveEnvAndFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	if err := os.WriteFile(filepath.Join(home, "key"), []byte("sk-from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("WORK_KEY", "sk-from-env")
+
+	tests := map[string]string{
+		"sk-plain":                           "sk-plain",
+		"${env:WORK_KEY}":                    "sk-from-env",
+		"file:~/key":                         "sk-from-file",
+		"file:" + filepath.Join(home, "key"): "sk-from-file",
+	}
+	for value, want := range tests {
+		got, err := Resolve(value)
+		if err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"${env:MISSING_KEY_FOR_TEST}", "file:~/missing"} {
+		if _, err := Resolve(value); err == nil {
+			t.Errorf("Resolve(%q) should fail", value)
+		}
+	}
+}
+
+func TestResolveCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	ClearCache()
+	t.Cleanup(ClearCache)
+
+	// 每次执行在文件中追加一行，用于统计执行次数
+	counter := filepath.Join(t.TempDir(), "count")
+	ref := "cmd:echo x >> " + counter + "; echo '  sk-from-cmd  '"
+	for i := 0; i < 2; i++ {
+		got, err := Resolve(ref)
+		if err != nil || got != "sk-from-cmd" {
+			t.Fatalf("Resolve() = %q, %v", got, err)
+		}
+	}
+	data, _ := os.ReadFile(counter)
+	if n := strings.Count(string(data), "x"); n != 1 {
+		t.Errorf("command ran %d times, want 1 (cached)", n)
+	}
+
+	if _, err := Resolve("cmd:exit 3"); err == nil {
+		t.Error("expected error for failing command")
+	}
+	if _, err := Resolve("cmd:true"); err == nil {
+		t.Error("expected error for empty output")
+	}
+
+	orig := CommandTimeout
+	CommandTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { CommandTimeout = orig })
+	start := time.Now()
+	_, err := Resolve("cmd:exec sleep 5")
+	if err == nil || !strings.Contains(err.Error(), "超时") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Error("timeout did not stop the command")
+	}
+}
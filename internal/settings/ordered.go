@@ -0,0 +1,174 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// orderedField 表示 JSON 对象中的一个键值对，值保持原始字节
+type orderedField struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// orderedObject 是保留键顺序和原始值的 JSON 对象
+// 用于在不理解全部字段的情况下无损地读写 settings.json
+type orderedObject struct {
+	fields []orderedField
+}
+
+// UnmarshalJSON 按出现顺序解析对象的每个键
+func (o *orderedObject) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("期望 JSON 对象")
+	}
+
+	o.fields = nil
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("无效的键: %v", tok)
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		// 重复键以最后一次出现为准，与 encoding/json 行为一致
+		o.set(key, value)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalJSON 按原顺序输出所有键
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := marshalNoEscape(f.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(f.Value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// get 返回指定键的原始值
+func (o *orderedObject) get(key string) (json.RawMessage, bool) {
+	for _, f := range o.fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// set 原位更新已有键，不存在时追加到末尾
+func (o *orderedObject) set(key string, value json.RawMessage) {
+	for i := range o.fields {
+		if o.fields[i].Key == key {
+			o.fields[i].Value = value
+			return
+		}
+	}
+	o.fields = append(o.fields, orderedField{Key: key, Value: value})
+}
+
+// delete 删除指定键
+func (o *orderedObject) delete(key string) {
+	for i := range o.fields {
+		if o.fields[i].Key == key {
+			o.fields = append(o.fields[:i], o.fields[i+1:]...)
+			return
+		}
+	}
+}
+
+// clone 返回对象的浅拷贝
+func (o *orderedObject) clone() *orderedObject {
+	if o == nil {
+		return &orderedObject{}
+	}
+	c := &orderedObject{fields: make([]orderedField, len(o.fields))}
+	copy(c.fields, o.fields)
+	return c
+}
+
+// marshalOrderedMap 将 map 序列化为对象
+// 原对象中已有的键保持原顺序，值未变化时复用原始字节；新增的键按字母序追加
+func marshalOrderedMap[V comparable](m map[string]V, prev *orderedObject, decode func(json.RawMessage) (V, bool)) (json.RawMessage, error) {
+	out := &orderedObject{}
+
+	if prev != nil {
+		for _, f := range prev.fields {
+			v, ok := m[f.Key]
+			if !ok {
+				continue
+			}
+			if old, ok := decode(f.Value); ok && old == v {
+				out.fields = append(out.fields, f)
+				continue
+			}
+			raw, err := marshalNoEscape(v)
+			if err != nil {
+				return nil, err
+			}
+			out.fields = append(out.fields, orderedField{Key: f.Key, Value: raw})
+		}
+	}
+
+	var added []string
+	for k := range m {
+		if prev != nil {
+			if _, ok := prev.get(k); ok {
+				continue
+			}
+		}
+		added = append(added, k)
+	}
+	sort.Strings(added)
+	for _, k := range added {
+		raw, err := marshalNoEscape(m[k])
+		if err != nil {
+			return nil, err
+		}
+		out.fields = append(out.fields, orderedField{Key: k, Value: raw})
+	}
+
+	return out.MarshalJSON()
+}
+
+// marshalNoEscape 序列化值但不转义 HTML 字符，避免 hooks 中的 && 等被改写
+func marshalNoEscape(v interface{}) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
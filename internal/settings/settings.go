@@ -1,17 +1,223 @@
 package settings
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
 // Settings 表示 ~/.claude/settings.json 的结构
+// 只建模 claude-switcher 需要读写的字段，其余字段（permissions、hooks、model 等）
+// 连同键顺序一起保存在 raw 中，写回时原样输出
 type Settings struct {
-	Env                   map[string]string `json:"env,omitempty"`
-	EnabledPlugins        map[string]bool   `json:"enabledPlugins,omitempty"`
-	ClaudeSwitcherProfile string           `json:"_claudeSwitcherProfile,omitempty"`
+	Env                   map[string]string
+	EnabledPlugins        map[string]bool
+	APIKeyHelper          string
+	ClaudeSwitcherProfile string
+	Managed               Manifest
+
+	raw         *orderedObject
+	hasManifest bool // 文件中存在 _claudeSwitcherManaged（即使其中没有任何键）
+}
+
+// Manifest 记录 claude-switcher 写入 settings.json 的内容
+// 切换配置时只清理清单中的键，用户手动添加的键不受影响
+// 配置覆盖了用户原有的键时，原值记录在 Restore 中，切换时恢复而不是删除
+type Manifest struct {
+	Env          []string          `json:"env,omitempty"`
+	Restore      map[string]string `json:"restore,omitempty"`
+	APIKeyHelper bool              `json:"apiKeyHelper,omitempty"`
+}
+
+const (
+	keyEnv            = "env"
+	keyEnabledPlugins = "enabledPlugins"
+	keyAPIKeyHelper   = "apiKeyHelper"
+	keyProfile        = "_claudeSwitcherProfile"
+	keyManaged        = "_claudeSwitcherManaged"
+)
+
+// legacyManagedEnv 是旧版本（无清单）写入的环境变量
+var legacyManagedEnv = []string{
+	"ANTHROPIC_AUTH_TOKEN",
+	"ANTHROPIC_BASE_URL",
+	"http_proxy",
+	"https_proxy",
+	"ANTHROPIC_MODEL",
+}
+
+// UnmarshalJSON 解析 settings.json 并保留完整文档
+func (s *Settings) UnmarshalJSON(data []byte) error {
+	raw := &orderedObject{}
+	if err := raw.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	s.raw = raw
+	s.Env = make(map[string]string)
+	s.EnabledPlugins = make(map[string]bool)
+	s.APIKeyHelper = ""
+	s.ClaudeSwitcherProfile = ""
+	s.Managed = Manifest{}
+	s.hasManifest = false
+
+	if v, ok := raw.get(keyEnv); ok && !isNull(v) {
+		var env orderedObject
+		if err := env.UnmarshalJSON(v); err != nil {
+			return fmt.Errorf("env: %w", err)
+		}
+		for _, f := range env.fields {
+			s.Env[f.Key], _ = decodeEnvValue(f.Value)
+		}
+	}
+
+	if v, ok := raw.get(keyEnabledPlugins); ok && !isNull(v) {
+		if err := json.Unmarshal(v, &s.EnabledPlugins); err != nil {
+			return fmt.Errorf("enabledPlugins: %w", err)
+		}
+	}
+
+	if v, ok := raw.get(keyAPIKeyHelper); ok {
+		// 非字符串的值无法理解，保留原样
+		_ = json.Unmarshal(v, &s.APIKeyHelper)
+	}
+
+	if v, ok := raw.get(keyProfile); ok && !isNull(v) {
+		if err := json.Unmarshal(v, &s.ClaudeSwitcherProfile); err != nil {
+			return fmt.Errorf("%s: %w", keyProfile, err)
+		}
+	}
+
+	if v, ok := raw.get(keyManaged); ok && !isNull(v) {
+		if err := json.Unmarshal(v, &s.Managed); err != nil {
+			return fmt.Errorf("%s: %w", keyManaged, err)
+		}
+		s.hasManifest = true
+	}
+
+	return nil
+}
+
+// MarshalJSON 输出完整文档，仅改写 claude-switcher 管理的键
+func (s *Settings) MarshalJSON() ([]byte, error) {
+	out := s.raw.clone()
+
+	if err := s.marshalEnv(out); err != nil {
+		return nil, err
+	}
+	if err := s.marshalEnabledPlugins(out); err != nil {
+		return nil, err
+	}
+
+	if s.APIKeyHelper != "" {
+		v, err := marshalNoEscape(s.APIKeyHelper)
+		if err != nil {
+			return nil, err
+		}
+		out.set(keyAPIKeyHelper, v)
+	} else if v, ok := out.get(keyAPIKeyHelper); ok {
+		var str string
+		if json.Unmarshal(v, &str) == nil {
+			out.delete(keyAPIKeyHelper)
+		}
+	}
+
+	if s.ClaudeSwitcherProfile != "" {
+		v, err := marshalNoEscape(s.ClaudeSwitcherProfile)
+		if err != nil {
+			return nil, err
+		}
+		out.set(keyProfile, v)
+	} else {
+		out.delete(keyProfile)
+	}
+
+	// 有 profile 标记时总是写入清单，即使为空，避免下次读取时被当作旧版本写入的文件
+	if s.ClaudeSwitcherProfile != "" || len(s.Managed.Env) > 0 || len(s.Managed.Restore) > 0 || s.Managed.APIKeyHelper {
+		v, err := marshalNoEscape(s.Managed)
+		if err != nil {
+			return nil, err
+		}
+		out.set(keyManaged, v)
+	} else {
+		out.delete(keyManaged)
+	}
+
+	return out.MarshalJSON()
+}
+
+func (s *Settings) marshalEnv(out *orderedObject) error {
+	prev, had := s.rawObject(keyEnv)
+	if len(s.Env) == 0 && !had {
+		return nil
+	}
+	v, err := marshalOrderedMap(s.Env, prev, decodeEnvValue)
+	if err != nil {
+		return err
+	}
+	out.set(keyEnv, v)
+	return nil
+}
+
+func (s *Settings) marshalEnabledPlugins(out *orderedObject) error {
+	prev, had := s.rawObject(keyEnabledPlugins)
+	if len(s.EnabledPlugins) == 0 && !had {
+		return nil
+	}
+	v, err := marshalOrderedMap(s.EnabledPlugins, prev, decodeBool)
+	if err != nil {
+		return err
+	}
+	out.set(keyEnabledPlugins, v)
+	return nil
+}
+
+// rawObject 返回原始文档中指定键对应的对象
+func (s *Settings) rawObject(key string) (*orderedObject, bool) {
+	if s.raw == nil {
+		return nil, false
+	}
+	v, ok := s.raw.get(key)
+	if !ok {
+		return nil, false
+	}
+	var obj orderedObject
+	if err := obj.UnmarshalJSON(v); err != nil {
+		return nil, true
+	}
+	return &obj, true
+}
+
+// decodeEnvValue 将 env 中的值转换为字符串，非字符串值使用其 JSON 文本
+func decodeEnvValue(v json.RawMessage) (string, bool) {
+	var str string
+	if err := json.Unmarshal(v, &str); err == nil {
+		return str, true
+	}
+	return string(bytes.TrimSpace(v)), true
+}
+
+func decodeBool(v json.RawMessage) (bool, bool) {
+	var b bool
+	if err := json.Unmarshal(v, &b); err != nil {
+		return false, false
+	}
+	return b, true
+}
+
+func isNull(v json.RawMessage) bool {
+	return string(bytes.TrimSpace(v)) == "null"
+}
+
+// newSettings 创建空的 Settings
+func newSettings() *Settings {
+	return &Settings{
+		Env:            make(map[string]string),
+		EnabledPlugins: make(map[string]bool),
+	}
 }
 
 // LoadSettings 从文件加载 settings.json
@@ -26,63 +232,149 @@ func LoadSettings(filePath string) (*Settings, error) {
 		return nil, fmt.Errorf("解析 settings.json 失败: %w", err)
 	}
 
-	// 初始化 map 以防 nil
-	if s.Env == nil {
-		s.Env = make(map[string]string)
-	}
-	if s.EnabledPlugins == nil {
-		s.EnabledPlugins = make(map[string]bool)
-	}
-
 	return &s, nil
 }
 
 // SaveSettings 保存 settings.json
+// 先写入同目录的临时文件再重命名，避免写入中途失败损坏原文件
+// settings.json 是符号链接时（如由 dotfiles 管理）写入链接指向的文件，保留链接本身
 func SaveSettings(filePath string, s *Settings) error {
-	data, err := json.MarshalIndent(s, "", "  ")
-	if err != nil {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
 		return fmt.Errorf("序列化 settings.json 失败: %w", err)
 	}
 
+	if resolved, err := filepath.EvalSymlinks(filePath); err == nil {
+		filePath = resolved
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("写入 settings.json 失败: %w", err)
+	}
+
 	// 确保目录存在
 	dir := filepath.Dir(filePath)
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return fmt.Errorf("创建目录失败: %w", err)
 	}
 
-	if err := os.WriteFile(filePath, data, 0600); err != nil {
+	tmp, err := os.CreateTemp(dir, ".settings-*.json")
+	if err != nil {
+		return fmt.Errorf("写入 settings.json 失败: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入 settings.json 失败: %w", err)
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入 settings.json 失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入 settings.json 失败: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
 		return fmt.Errorf("写入 settings.json 失败: %w", err)
 	}
 
 	return nil
 }
 
+// loadOrNewSettings 加载 settings.json，文件不存在或为空时返回空配置
+// 文件存在但无法解析时返回错误，不能用空配置覆盖用户的文件
+func loadOrNewSettings(filePath string) (*Settings, error) {
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return newSettings(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 settings.json 失败: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return newSettings(), nil
+	}
+
+	var s Settings
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("解析 settings.json 失败: %w", err)
+	}
+	return &s, nil
+}
+
+// managedEnvKeys 返回上一次同步写入的环境变量
+// 完全没有清单但存在 profile 标记时，说明由旧版本写入，使用旧版本的固定列表
+func (s *Settings) managedEnvKeys() []string {
+	if s.hasManifest || len(s.Managed.Env) > 0 {
+		return s.Managed.Env
+	}
+	if s.ClaudeSwitcherProfile != "" {
+		return legacyManagedEnv
+	}
+	return nil
+}
+
+// removeManaged 移除上一次同步写入的内容，恢复被覆盖的用户原值，并清空清单
+func (s *Settings) removeManaged() {
+	for _, key := range s.managedEnvKeys() {
+		delete(s.Env, key)
+	}
+	for key, value := range s.Managed.Restore {
+		s.Env[key] = value
+	}
+	if s.Managed.APIKeyHelper {
+		s.APIKeyHelper = ""
+	}
+	s.Managed = Manifest{}
+}
+
+// SyncOptions 同步到 settings.json 的选项
+type SyncOptions struct {
+	// APIKeyHelper 不为空时写入 apiKeyHelper，由该命令输出 API 密钥
+	APIKeyHelper string
+}
+
 // SyncProfileToSettings 将 profile 的环境变量同步到 settings.json
+// 上一个配置写入的环境变量会先被移除，再写入新配置并记录清单
 func SyncProfileToSettings(filePath, profileName string, envVars map[string]string) error {
-	var s *Settings
+	return SyncProfileWithOptions(filePath, profileName, envVars, SyncOptions{})
+}
 
-	// 如果文件存在，先加载以保留现有配置
-	if _, err := os.Stat(filePath); err == nil {
-		var err error
-		s, err = LoadSettings(filePath)
-		if err != nil {
-			// 如果加载失败，创建新的
-			s = &Settings{
-				Env:          make(map[string]string),
-				EnabledPlugins: make(map[string]bool),
-			}
-		}
-	} else {
-		s = &Settings{
-			Env:          make(map[string]string),
-			EnabledPlugins: make(map[string]bool),
+// SyncProfileWithOptions 与 SyncProfileToSettings 相同，可额外写入 apiKeyHelper
+// 用户自行配置的 apiKeyHelper 不会被覆盖
+func SyncProfileWithOptions(filePath, profileName string, envVars map[string]string, opts SyncOptions) error {
+	s, err := loadOrNewSettings(filePath)
+	if err != nil {
+		return err
+	}
+
+	s.removeManaged()
+
+	if opts.APIKeyHelper != "" {
+		if s.APIKeyHelper != "" {
+			return fmt.Errorf("settings.json 中已有未由 claude-switcher 管理的 apiKeyHelper: %s", s.APIKeyHelper)
 		}
+		s.APIKeyHelper = opts.APIKeyHelper
+		s.Managed.APIKeyHelper = true
 	}
 
-	// 更新 env
+	// 更新 env，用户原有的键不记为由 claude-switcher 写入，只记录原值以便恢复
 	for k, v := range envVars {
+		if old, ok := s.Env[k]; ok {
+			if s.Managed.Restore == nil {
+				s.Managed.Restore = make(map[string]string)
+			}
+			s.Managed.Restore[k] = old
+		} else {
+			s.Managed.Env = append(s.Managed.Env, k)
+		}
 		s.Env[k] = v
 	}
+	sort.Strings(s.Managed.Env)
 
 	// 更新 profile 标记
 	s.ClaudeSwitcherProfile = profileName
@@ -104,22 +396,31 @@ func ClearProfileEnvVars(filePath string, profileName string) error {
 		return err
 	}
 
-	// 需要清除的环境变量列表
-	keysToClear := []string{
-		"ANTHROPIC_AUTH_TOKEN",
-		"ANTHROPIC_BASE_URL",
-		"http_proxy",
-		"https_proxy",
-		"ANTHROPIC_MODEL",
+	// 清除清单中记录的键
+	s.removeManaged()
+
+	// 清除 profile 标记
+	s.ClaudeSwitcherProfile = ""
+
+	return SaveSettings(filePath, s)
+}
+
+// SeedSettings 以 src 为模板创建 dst，dst 已存在时不做任何修改
+// 模板中由 claude-switcher 同步的环境变量和标记不会被复制
+func SeedSettings(dst, src string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("读取 settings.json 失败: %w", err)
 	}
 
-	// 清除这些键
-	for _, key := range keysToClear {
-		delete(s.Env, key)
+	s, err := loadOrNewSettings(src)
+	if err != nil {
+		return err
 	}
 
-	// 清除 profile 标记
+	s.removeManaged()
 	s.ClaudeSwitcherProfile = ""
 
-	return SaveSettings(filePath, s)
-}
\ No newline at end of file
+	return SaveSettings(dst, s)
+}
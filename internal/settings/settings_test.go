@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -97,6 +98,37 @@ func TestSaveSettings(t *testing.T) {
 	}
 }
 
+func TestSaveSettingsFollowsSymlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	dotfiles := filepath.Join(tmpDir, "dotfiles")
+	if err := os.MkdirAll(dotfiles, 0700); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dotfiles, "settings.json")
+	if err := os.WriteFile(target, []byte("{}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(tmpDir, "settings.json")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	if err := SaveSettings(link, &Settings{ClaudeSwitcherProfile: "work"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("settings.json should still be a symlink: %v", err)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"work"`) {
+		t.Errorf("link target should be updated, got %s", data)
+	}
+}
+
 func TestSyncProfileToSettings(t *testing.T) {
 	tmpDir := t.TempDir()
 	settingsFile := filepath.Join(tmpDir, "settings.json")
@@ -261,3 +293,378 @@ func TestClearProfileEnvVarsFileNotExist(t *testing.T) {
 		t.Errorf("ClearProfileEnvVars() error = %v, want nil", err)
 	}
 }
+
+func TestSyncProfileToSettingsPreservesUnknownKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsFile := filepath.Join(tmpDir, "settings.json")
+
+	initialContent := `{
+  "model": "opus",
+  "permissions": {
+    "allow": ["Bash(npm run test:*)", "Read(~/.zshrc)"],
+    "deny": []
+  },
+  "env": {
+    "USER_VAR": "keep",
+    "MAX_THINKING_TOKENS": 1024
+  },
+  "hooks": {
+    "PostToolUse": [{"matcher": "Edit", "hooks": [{"type": "command", "command": "make fmt && make lint"}]}]
+  },
+  "statusLine": {"type": "command", "command": "~/.claude/statusline.sh"},
+  "enabledPlugins": {"b-plugin": true, "a-plugin": false}
+}`
+	if err := os.WriteFile(settingsFile, []byte(initialContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	envVars := map[string]string{
+		"ANTHROPIC_BASE_URL": "https://api.example.com",
+	}
+	if err := SyncProfileToSettings(settingsFile, "work", envVars); err != nil {
+		t.Fatalf("SyncProfileToSettings() error = %v", err)
+	}
+	if err := ClearProfileEnvVars(settingsFile, "work"); err != nil {
+		t.Fatalf("ClearProfileEnvVars() error = %v", err)
+	}
+
+	data, err := os.ReadFile(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+
+	// 未建模的键及其顺序保持不变
+	order := []string{`"model"`, `"permissions"`, `"env"`, `"hooks"`, `"statusLine"`, `"enabledPlugins"`}
+	last := -1
+	for _, key := range order {
+		idx := strings.Index(out, key)
+		if idx < 0 {
+			t.Fatalf("key %s missing from output:\n%s", key, out)
+		}
+		if idx < last {
+			t.Errorf("key %s out of order:\n%s", key, out)
+		}
+		last = idx
+	}
+
+	// 值原样保留，不做 HTML 转义
+	for _, want := range []string{
+		`"Bash(npm run test:*)"`,
+		`"make fmt && make lint"`,
+		`"MAX_THINKING_TOKENS": 1024`,
+		`"USER_VAR": "keep"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %s:\n%s", want, out)
+		}
+	}
+
+	// enabledPlugins 的键顺序保持不变
+	if strings.Index(out, `"b-plugin"`) > strings.Index(out, `"a-plugin"`) {
+		t.Errorf("enabledPlugins order changed:\n%s", out)
+	}
+
+	if strings.Contains(out, keyProfile) {
+		t.Errorf("profile marker should be removed:\n%s", out)
+	}
+}
+
+func TestSyncProfileToSettingsInvalidJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsFile := filepath.Join(tmpDir, "settings.json")
+
+	initialContent := `{"permissions": {"allow": [}`
+	if err := os.WriteFile(settingsFile, []byte(initialContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SyncProfileToSettings(settingsFile, "work", map[string]string{"A": "b"}); err == nil {
+		t.Error("SyncProfileToSettings() expected error for invalid settings.json")
+	}
+
+	// 无法解析的文件不能被覆盖
+	data, err := os.ReadFile(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != initialContent {
+		t.Errorf("settings.json was modified: %s", data)
+	}
+}
+
+func TestSyncProfileToSettingsReplacesPreviousProfileKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsFile := filepath.Join(tmpDir, "settings.json")
+
+	initialContent := `{
+  "env": {
+    "USER_VAR": "hand-written"
+  }
+}`
+	if err := os.WriteFile(settingsFile, []byte(initialContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	first := map[string]string{
+		"ANTHROPIC_AUTH_TOKEN":         "sk-first",
+		"ANTHROPIC_DEFAULT_OPUS_MODEL": "opus-custom",
+	}
+	if err := SyncProfileToSettings(settingsFile, "first", first); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadSettings(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_DEFAULT_OPUS_MODEL"}
+	if strings.Join(s.Managed.Env, ",") != strings.Join(want, ",") {
+		t.Errorf("Managed.Env = %v, want %v", s.Managed.Env, want)
+	}
+
+	second := map[string]string{
+		"ANTHROPIC_AUTH_TOKEN": "sk-second",
+	}
+	if err := SyncProfileToSettings(settingsFile, "second", second); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = LoadSettings(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Env["ANTHROPIC_DEFAULT_OPUS_MODEL"]; ok {
+		t.Error("ANTHROPIC_DEFAULT_OPUS_MODEL from previous profile should be removed")
+	}
+	if s.Env["ANTHROPIC_AUTH_TOKEN"] != "sk-second" {
+		t.Errorf("ANTHROPIC_AUTH_TOKEN = %v, want sk-second", s.Env["ANTHROPIC_AUTH_TOKEN"])
+	}
+	if s.Env["USER_VAR"] != "hand-written" {
+		t.Errorf("USER_VAR should be preserved, got %v", s.Env["USER_VAR"])
+	}
+	if len(s.Managed.Env) != 1 || s.Managed.Env[0] != "ANTHROPIC_AUTH_TOKEN" {
+		t.Errorf("Managed.Env = %v, want [ANTHROPIC_AUTH_TOKEN]", s.Managed.Env)
+	}
+
+	if err := ClearProfileEnvVars(settingsFile, "second"); err != nil {
+		t.Fatal(err)
+	}
+	s, err = LoadSettings(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Env) != 1 || s.Env["USER_VAR"] != "hand-written" {
+		t.Errorf("Env = %v, want only USER_VAR", s.Env)
+	}
+	if len(s.Managed.Env) != 0 {
+		t.Errorf("Managed.Env should be cleared, got %v", s.Managed.Env)
+	}
+}
+
+func TestSyncProfileToSettingsRestoresUserKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsFile := filepath.Join(tmpDir, "settings.json")
+
+	initialContent := `{
+  "env": {
+    "ANTHROPIC_MODEL": "user-model"
+  }
+}`
+	if err := os.WriteFile(settingsFile, []byte(initialContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SyncProfileToSettings(settingsFile, "first", map[string]string{"ANTHROPIC_MODEL": "profile-model"}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSettings(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Env["ANTHROPIC_MODEL"] != "profile-model" {
+		t.Errorf("ANTHROPIC_MODEL = %v, want profile-model", s.Env["ANTHROPIC_MODEL"])
+	}
+	if len(s.Managed.Env) != 0 {
+		t.Errorf("user key should not be recorded as managed, got %v", s.Managed.Env)
+	}
+
+	// 新配置不设置该键时恢复用户原值，而不是删除
+	if err := SyncProfileToSettings(settingsFile, "second", map[string]string{"ANTHROPIC_AUTH_TOKEN": "sk-second"}); err != nil {
+		t.Fatal(err)
+	}
+	s, err = LoadSettings(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Env["ANTHROPIC_MODEL"] != "user-model" {
+		t.Errorf("ANTHROPIC_MODEL = %v, want user-model restored", s.Env["ANTHROPIC_MODEL"])
+	}
+
+	if err := ClearProfileEnvVars(settingsFile, "second"); err != nil {
+		t.Fatal(err)
+	}
+	s, err = LoadSettings(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Env) != 1 || s.Env["ANTHROPIC_MODEL"] != "user-model" {
+		t.Errorf("Env = %v, want only the user's ANTHROPIC_MODEL", s.Env)
+	}
+}
+
+func TestSyncProfileToSettingsEmptyManifestIsNotLegacy(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsFile := filepath.Join(tmpDir, "settings.json")
+
+	// 上一个配置没有写入任何环境变量，之后用户手动添加了 ANTHROPIC_MODEL
+	if err := SyncProfileToSettings(settingsFile, "empty", nil); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSettings(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Env["ANTHROPIC_MODEL"] = "user-model"
+	if err := SaveSettings(settingsFile, s); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SyncProfileToSettings(settingsFile, "work", map[string]string{"ANTHROPIC_AUTH_TOKEN": "sk-work"}); err != nil {
+		t.Fatal(err)
+	}
+	s, err = LoadSettings(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Env["ANTHROPIC_MODEL"] != "user-model" {
+		t.Errorf("user's ANTHROPIC_MODEL should survive, Env = %v", s.Env)
+	}
+
+	// 完全没有清单的旧版本文件仍然使用固定列表
+	legacy := `{"env": {"ANTHROPIC_MODEL": "old"}, "_claudeSwitcherProfile": "old"}`
+	if err := os.WriteFile(settingsFile, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SyncProfileToSettings(settingsFile, "work", nil); err != nil {
+		t.Fatal(err)
+	}
+	s, err = LoadSettings(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Env["ANTHROPIC_MODEL"]; ok {
+		t.Error("legacy managed key should be removed")
+	}
+}
+
+func TestSeedSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "settings.json")
+	dst := filepath.Join(tmpDir, "homes", "work", "settings.json")
+
+	initialContent := `{
+  "env": {
+    "USER_VAR": "hand-written"
+  },
+  "hooks": {}
+}`
+	if err := os.WriteFile(src, []byte(initialContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SyncProfileToSettings(src, "global", map[string]string{"ANTHROPIC_AUTH_TOKEN": "sk-global"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SeedSettings(dst, src); err != nil {
+		t.Fatalf("SeedSettings() error = %v", err)
+	}
+
+	s, err := LoadSettings(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Env["ANTHROPIC_AUTH_TOKEN"]; ok {
+		t.Error("synced env from the template should not be copied")
+	}
+	if s.ClaudeSwitcherProfile != "" {
+		t.Errorf("profile marker = %q, want empty", s.ClaudeSwitcherProfile)
+	}
+	if s.Env["USER_VAR"] != "hand-written" {
+		t.Errorf("USER_VAR should be copied, got %v", s.Env["USER_VAR"])
+	}
+	data, _ := os.ReadFile(dst)
+	if !strings.Contains(string(data), `"hooks"`) {
+		t.Errorf("unknown keys should be copied: %s", data)
+	}
+
+	// 已存在时不覆盖
+	if err := os.WriteFile(dst, []byte(`{"env":{"MINE":"1"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SeedSettings(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(dst)
+	if string(data) != `{"env":{"MINE":"1"}}` {
+		t.Errorf("existing settings.json should be kept, got %s", data)
+	}
+}
+
+func TestSyncProfileWithOptionsAPIKeyHelper(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsFile := filepath.Join(tmpDir, "settings.json")
+
+	envVars := map[string]string{"ANTHROPIC_BASE_URL": "https://api.example.com"}
+	opts := SyncOptions{APIKeyHelper: "claude-switcher token work"}
+	if err := SyncProfileWithOptions(settingsFile, "work", envVars, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadSettings(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.APIKeyHelper != opts.APIKeyHelper {
+		t.Errorf("APIKeyHelper = %q, want %q", s.APIKeyHelper, opts.APIKeyHelper)
+	}
+	if !s.Managed.APIKeyHelper {
+		t.Error("apiKeyHelper should be recorded in the manifest")
+	}
+
+	// 切换回 env 模式时移除 apiKeyHelper
+	if err := SyncProfileToSettings(settingsFile, "work", map[string]string{"ANTHROPIC_AUTH_TOKEN": "sk"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(settingsFile)
+	if strings.Contains(string(data), "apiKeyHelper") {
+		t.Errorf("managed apiKeyHelper should be removed: %s", data)
+	}
+}
+
+func TestSyncProfileWithOptionsKeepsUserAPIKeyHelper(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsFile := filepath.Join(tmpDir, "settings.json")
+
+	if err := os.WriteFile(settingsFile, []byte(`{"apiKeyHelper": "my-helper"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// env 模式不会改动用户的 apiKeyHelper
+	if err := SyncProfileToSettings(settingsFile, "work", map[string]string{"ANTHROPIC_AUTH_TOKEN": "sk"}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSettings(settingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.APIKeyHelper != "my-helper" {
+		t.Errorf("APIKeyHelper = %q, want my-helper", s.APIKeyHelper)
+	}
+
+	// helper 模式拒绝覆盖
+	err = SyncProfileWithOptions(settingsFile, "work", nil, SyncOptions{APIKeyHelper: "claude-switcher token work"})
+	if err == nil {
+		t.Error("expected error when apiKeyHelper is not managed by claude-switcher")
+	}
+}
@@ -0,0 +1,231 @@
+// Package translate 在 Anthropic Messages API 与 OpenAI Chat Completions API 之间转换
+// 用于让 claude 使用只提供 OpenAI 兼容接口的服务
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ToChatRequest 将 Anthropic Messages 请求转换为 OpenAI Chat Completions 请求
+func ToChatRequest(req *MessagesRequest) (*ChatRequest, error) {
+	out := &ChatRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Stream:      req.Stream,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stop:        req.StopSequences,
+	}
+	if req.Stream {
+		out.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+
+	system, err := systemText(req.System)
+	if err != nil {
+		return nil, fmt.Errorf("system: %w", err)
+	}
+	if system != "" {
+		out.Messages = append(out.Messages, ChatMessage{Role: "system", Content: system})
+	}
+
+	for i, m := range req.Messages {
+		blocks, err := decodeContent(m.Content)
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+
+		var msgs []ChatMessage
+		switch m.Role {
+		case "user":
+			msgs, err = userMessages(blocks)
+		case "assistant":
+			msgs, err = assistantMessages(blocks)
+		default:
+			return nil, fmt.Errorf("messages[%d]: 不支持的角色 %q", i, m.Role)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		out.Messages = append(out.Messages, msgs...)
+	}
+
+	for _, t := range req.Tools {
+		out.Tools = append(out.Tools, ChatTool{
+			Type: "function",
+			Function: FunctionSpec{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.InputSchema,
+			},
+		})
+	}
+
+	if req.ToolChoice != nil && len(out.Tools) > 0 {
+		switch req.ToolChoice.Type {
+		case "auto":
+			out.ToolChoice = "auto"
+		case "any":
+			out.ToolChoice = "required"
+		case "none":
+			out.ToolChoice = "none"
+		case "tool":
+			out.ToolChoice = map[string]interface{}{
+				"type":     "function",
+				"function": map[string]string{"name": req.ToolChoice.Name},
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// decodeContent 解析字符串或内容块数组形式的 content
+func decodeContent(raw json.RawMessage) ([]ContentBlock, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] == '"' {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+		return []ContentBlock{{Type: "text", Text: text}}, nil
+	}
+	var blocks []ContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// systemText 将 system 字段合并为一段文本
+func systemText(raw json.RawMessage) (string, error) {
+	blocks, err := decodeContent(raw)
+	if err != nil {
+		return "", err
+	}
+	return joinText(blocks), nil
+}
+
+// joinText 合并所有文本块
+func joinText(blocks []ContentBlock) string {
+	var parts []string
+	for _, b := range blocks {
+		if b.Type == "text" && b.Text != "" {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// userMessages 转换用户消息
+// tool_result 块转换为独立的 tool 消息，并放在同一轮的其他内容之前
+func userMessages(blocks []ContentBlock) ([]ChatMessage, error) {
+	var toolMsgs []ChatMessage
+	var parts []ContentPart
+	hasImage := false
+
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			parts = append(parts, ContentPart{Type: "text", Text: b.Text})
+		case "image":
+			part, err := imagePart(b.Source)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+			hasImage = true
+		case "tool_result":
+			inner, err := decodeContent(b.Content)
+			if err != nil {
+				return nil, fmt.Errorf("tool_result: %w", err)
+			}
+			text := joinText(inner)
+			if b.IsError && text != "" {
+				text = "Error: " + text
+			}
+			toolMsgs = append(toolMsgs, ChatMessage{Role: "tool", ToolCallID: b.ToolUseID, Content: text})
+
+			// tool 消息只支持文本，结果中的图片放到随后的用户消息中
+			for _, ib := range inner {
+				if ib.Type != "image" {
+					continue
+				}
+				part, err := imagePart(ib.Source)
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, part)
+				hasImage = true
+			}
+		}
+	}
+
+	msgs := toolMsgs
+	if len(parts) == 0 {
+		return msgs, nil
+	}
+	if !hasImage {
+		// 纯文本使用字符串形式，兼容性最好
+		var texts []string
+		for _, p := range parts {
+			texts = append(texts, p.Text)
+		}
+		return append(msgs, ChatMessage{Role: "user", Content: strings.Join(texts, "\n")}), nil
+	}
+	return append(msgs, ChatMessage{Role: "user", Content: parts}), nil
+}
+
+// assistantMessages 转换助手消息，tool_use 块转换为 tool_calls
+// thinking 等 OpenAI 不支持的块会被忽略
+func assistantMessages(blocks []ContentBlock) ([]ChatMessage, error) {
+	msg := ChatMessage{Role: "assistant"}
+	var texts []string
+
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			if b.Text != "" {
+				texts = append(texts, b.Text)
+			}
+		case "tool_use":
+			args := "{}"
+			if len(bytes.TrimSpace(b.Input)) > 0 {
+				args = string(b.Input)
+			}
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+				ID:       b.ID,
+				Type:     "function",
+				Function: FunctionCall{Name: b.Name, Arguments: args},
+			})
+		}
+	}
+
+	if len(texts) > 0 {
+		msg.Content = strings.Join(texts, "\n")
+	}
+	if msg.Content == nil && len(msg.ToolCalls) == 0 {
+		return nil, nil
+	}
+	return []ChatMessage{msg}, nil
+}
+
+// imagePart 将图片来源转换为 image_url
+func imagePart(src *ImageSource) (ContentPart, error) {
+	if src == nil {
+		return ContentPart{}, fmt.Errorf("image 缺少 source")
+	}
+	switch src.Type {
+	case "base64":
+		return ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: "data:" + src.MediaType + ";base64," + src.Data}}, nil
+	case "url":
+		return ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: src.URL}}, nil
+	default:
+		return ContentPart{}, fmt.Errorf("不支持的图片来源: %s", src.Type)
+	}
+}
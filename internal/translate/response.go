@@ -0,0 +1,151 @@
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FromChatResponse 将 OpenAI Chat Completions 响应转换为 Anthropic Messages 响应
+// model 为客户端请求的模型名称，上游未返回模型时使用
+func FromChatResponse(resp *ChatResponse, model string) (*MessagesResponse, error) {
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("上游响应中没有 choices")
+	}
+	choice := resp.Choices[0]
+
+	out := &MessagesResponse{
+		ID:      messageID(resp.ID),
+		Type:    "message",
+		Role:    "assistant",
+		Model:   firstNonEmpty(resp.Model, model),
+		Content: []ContentBlock{},
+	}
+
+	finish := ""
+	if choice.FinishReason != nil {
+		finish = *choice.FinishReason
+	}
+	out.StopReason = StopReason(finish)
+
+	if msg := choice.Message; msg != nil {
+		if msg.Content != nil && *msg.Content != "" {
+			out.Content = append(out.Content, ContentBlock{Type: "text", Text: *msg.Content})
+		}
+		for i, tc := range msg.ToolCalls {
+			out.Content = append(out.Content, ContentBlock{
+				Type:  "tool_use",
+				ID:    toolUseID(tc.ID, i),
+				Name:  tc.Function.Name,
+				Input: toolInput(tc.Function.Arguments),
+			})
+		}
+		if len(msg.ToolCalls) > 0 {
+			out.StopReason = "tool_use"
+		}
+	}
+
+	if resp.Usage != nil {
+		out.Usage = Usage{InputTokens: resp.Usage.PromptTokens, OutputTokens: resp.Usage.CompletionTokens}
+	}
+
+	return out, nil
+}
+
+// StopReason 将 OpenAI 的 finish_reason 转换为 Anthropic 的 stop_reason
+func StopReason(finish string) string {
+	switch finish {
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	default:
+		return "end_turn"
+	}
+}
+
+// ErrorBody 将上游的错误响应转换为 Anthropic 的错误格式
+func ErrorBody(status int, body []byte) []byte {
+	message := strings.TrimSpace(string(body))
+
+	var oe struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &oe) == nil && oe.Error.Message != "" {
+		message = oe.Error.Message
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"type": "error",
+		"error": map[string]string{
+			"type":    errorType(status),
+			"message": message,
+		},
+	})
+	return data
+}
+
+// errorType 根据状态码返回 Anthropic 的错误类型
+func errorType(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case 529:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// toolInput 将 JSON 字符串形式的参数转换为对象，无效时返回空对象
+func toolInput(args string) json.RawMessage {
+	if strings.TrimSpace(args) == "" || !json.Valid([]byte(args)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(args)
+}
+
+// messageID 生成 Anthropic 风格的消息 ID
+func messageID(id string) string {
+	if strings.HasPrefix(id, "msg_") {
+		return id
+	}
+	if id == "" {
+		id = "translated"
+	}
+	return "msg_" + id
+}
+
+// toolUseID 返回工具调用 ID
+// 回传给上游时使用同一个 ID，因此只在缺失时按序号生成
+func toolUseID(id string, index int) string {
+	if id == "" {
+		return fmt.Sprintf("toolu_%d", index)
+	}
+	return id
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,257 @@
+package translate
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrUnexpectedEnd 上游的流在收到 [DONE] 或 finish_reason 之前结束
+var ErrUnexpectedEnd = errors.New("upstream stream ended unexpectedly")
+
+// StreamWriter 将 OpenAI 的流式 chunk 转换为 Anthropic 的 SSE 事件
+type StreamWriter struct {
+	w     io.Writer
+	flush func()
+	model string
+
+	started    bool
+	done       bool        // 已收到 [DONE] 或 finish_reason
+	blockIndex int         // 下一个内容块的序号
+	openBlock  string      // 当前打开的内容块类型：text、tool_use 或空
+	toolBlocks map[int]int // OpenAI 工具调用序号 -> 内容块序号
+
+	id           string
+	stopReason   string
+	inputTokens  int
+	outputTokens int
+}
+
+// NewStreamWriter 创建流式转换器，每个事件写入后调用 flush（可为 nil）
+func NewStreamWriter(w io.Writer, flush func(), model string) *StreamWriter {
+	if flush == nil {
+		flush = func() {}
+	}
+	return &StreamWriter{
+		w:          w,
+		flush:      flush,
+		model:      model,
+		toolBlocks: make(map[int]int),
+	}
+}
+
+// Translate 读取上游的 SSE 流直到结束，并输出完整的 Anthropic 事件序列
+func (s *StreamWriter) Translate(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			s.done = true
+			break
+		}
+
+		var chunk ChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("解析上游流式响应失败: %w", err)
+		}
+		if err := s.Chunk(&chunk); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		// 连接中断时同样告知客户端响应不完整
+		if ferr := s.fail(); ferr != nil {
+			return ferr
+		}
+		return err
+	}
+
+	return s.Finish()
+}
+
+// Chunk 处理一个 OpenAI chunk
+func (s *StreamWriter) Chunk(chunk *ChatResponse) error {
+	if s.id == "" {
+		s.id = chunk.ID
+	}
+	if chunk.Model != "" && s.model == "" {
+		s.model = chunk.Model
+	}
+	if err := s.start(); err != nil {
+		return err
+	}
+
+	if chunk.Usage != nil {
+		s.inputTokens = chunk.Usage.PromptTokens
+		s.outputTokens = chunk.Usage.CompletionTokens
+	}
+
+	for _, choice := range chunk.Choices {
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			s.stopReason = StopReason(*choice.FinishReason)
+			s.done = true
+		}
+
+		delta := choice.Delta
+		if delta == nil {
+			continue
+		}
+
+		if delta.Content != nil && *delta.Content != "" {
+			if s.openBlock != "text" {
+				if err := s.closeBlock(); err != nil {
+					return err
+				}
+				if err := s.openContentBlock("text", map[string]interface{}{"type": "text", "text": ""}); err != nil {
+					return err
+				}
+			}
+			if err := s.event("content_block_delta", map[string]interface{}{
+				"index": s.blockIndex - 1,
+				"delta": map[string]string{"type": "text_delta", "text": *delta.Content},
+			}); err != nil {
+				return err
+			}
+		}
+
+		for i, tc := range delta.ToolCalls {
+			toolIndex := i
+			if tc.Index != nil {
+				toolIndex = *tc.Index
+			}
+
+			index, ok := s.toolBlocks[toolIndex]
+			if !ok {
+				if err := s.closeBlock(); err != nil {
+					return err
+				}
+				if err := s.openContentBlock("tool_use", map[string]interface{}{
+					"type":  "tool_use",
+					"id":    toolUseID(tc.ID, toolIndex),
+					"name":  tc.Function.Name,
+					"input": map[string]interface{}{},
+				}); err != nil {
+					return err
+				}
+				index = s.blockIndex - 1
+				s.toolBlocks[toolIndex] = index
+			}
+
+			if tc.Function.Arguments != "" {
+				if err := s.event("content_block_delta", map[string]interface{}{
+					"index": index,
+					"delta": map[string]string{"type": "input_json_delta", "partial_json": tc.Function.Arguments},
+				}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Finish 关闭当前内容块并输出 message_delta 和 message_stop
+// 上游没有正常结束时改为输出 error 事件并返回 ErrUnexpectedEnd，避免截断的响应被当作完整的回复
+func (s *StreamWriter) Finish() error {
+	if !s.done {
+		if err := s.fail(); err != nil {
+			return err
+		}
+		return ErrUnexpectedEnd
+	}
+	if err := s.start(); err != nil {
+		return err
+	}
+	if err := s.closeBlock(); err != nil {
+		return err
+	}
+
+	stopReason := s.stopReason
+	if stopReason == "" {
+		stopReason = "end_turn"
+	}
+	if len(s.toolBlocks) > 0 && stopReason == "end_turn" {
+		stopReason = "tool_use"
+	}
+
+	if err := s.event("message_delta", map[string]interface{}{
+		"delta": map[string]interface{}{"stop_reason": stopReason, "stop_sequence": nil},
+		"usage": map[string]int{"input_tokens": s.inputTokens, "output_tokens": s.outputTokens},
+	}); err != nil {
+		return err
+	}
+	return s.event("message_stop", map[string]interface{}{})
+}
+
+// fail 输出 Anthropic 的 error 事件
+func (s *StreamWriter) fail() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	return s.event("error", map[string]interface{}{
+		"error": map[string]string{"type": "api_error", "message": ErrUnexpectedEnd.Error()},
+	})
+}
+
+// start 在第一个事件前输出 message_start
+func (s *StreamWriter) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	return s.event("message_start", map[string]interface{}{
+		"message": map[string]interface{}{
+			"id":            messageID(s.id),
+			"type":          "message",
+			"role":          "assistant",
+			"model":         s.model,
+			"content":       []interface{}{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         Usage{},
+		},
+	})
+}
+
+// openContentBlock 输出 content_block_start
+func (s *StreamWriter) openContentBlock(blockType string, block map[string]interface{}) error {
+	s.openBlock = blockType
+	s.blockIndex++
+	return s.event("content_block_start", map[string]interface{}{
+		"index":         s.blockIndex - 1,
+		"content_block": block,
+	})
+}
+
+// closeBlock 输出当前内容块的 content_block_stop
+func (s *StreamWriter) closeBlock() error {
+	if s.openBlock == "" {
+		return nil
+	}
+	s.openBlock = ""
+	return s.event("content_block_stop", map[string]interface{}{"index": s.blockIndex - 1})
+}
+
+// event 写入一个 SSE 事件
+func (s *StreamWriter) event(name string, payload map[string]interface{}) error {
+	payload["type"] = name
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	s.flush()
+	return nil
+}
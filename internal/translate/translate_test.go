@@ -0,0 +1,324 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToChatRequest(t *testing.T) {
+	body := `{
+  "model": "openai/gpt-4o",
+  "max_tokens": 1024,
+  "system": [{"type": "text", "text": "You are helpful."}, {"type": "text", "text": "Be brief."}],
+  "stream": true,
+  "tools": [{"name": "read_file", "description": "Read a file", "input_schema": {"type": "object"}}],
+  "tool_choice": {"type": "any"},
+  "messages": [
+    {"role": "user", "content": "hello"},
+    {"role": "assistant", "content": [
+      {"type": "thinking", "thinking": "..."},
+      {"type": "text", "text": "Let me read it."},
+      {"type": "tool_use", "id": "call_1", "name": "read_file", "input": {"path": "a.txt"}}
+    ]},
+    {"role": "user", "content": [
+      {"type": "tool_result", "tool_use_id": "call_1", "content": [{"type": "text", "text": "file body"}]},
+      {"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "AAAA"}},
+      {"type": "text", "text": "what is this?"}
+    ]}
+  ]
+}`
+	var req MessagesRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ToChatRequest(&req)
+	if err != nil {
+		t.Fatalf("ToChatRequest() error = %v", err)
+	}
+
+	if out.Model != "openai/gpt-4o" || out.MaxTokens != 1024 || !out.Stream {
+		t.Errorf("model/max_tokens/stream = %q/%d/%v", out.Model, out.MaxTokens, out.Stream)
+	}
+	if out.StreamOptions == nil || !out.StreamOptions.IncludeUsage {
+		t.Error("stream requests should ask for usage")
+	}
+	if out.ToolChoice != "required" {
+		t.Errorf("ToolChoice = %v, want required", out.ToolChoice)
+	}
+	if len(out.Tools) != 1 || out.Tools[0].Function.Name != "read_file" || string(out.Tools[0].Function.Parameters) != `{"type": "object"}` {
+		t.Errorf("Tools = %+v", out.Tools)
+	}
+
+	roles := []string{}
+	for _, m := range out.Messages {
+		roles = append(roles, m.Role)
+	}
+	wantRoles := []string{"system", "user", "assistant", "tool", "user"}
+	if !reflect.DeepEqual(roles, wantRoles) {
+		t.Fatalf("roles = %v, want %v", roles, wantRoles)
+	}
+
+	if out.Messages[0].Content != "You are helpful.\nBe brief." {
+		t.Errorf("system = %q", out.Messages[0].Content)
+	}
+	if out.Messages[1].Content != "hello" {
+		t.Errorf("user content = %v", out.Messages[1].Content)
+	}
+
+	assistant := out.Messages[2]
+	if assistant.Content != "Let me read it." {
+		t.Errorf("assistant content = %v", assistant.Content)
+	}
+	if len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].ID != "call_1" || assistant.ToolCalls[0].Function.Arguments != `{"path": "a.txt"}` {
+		t.Errorf("tool_calls = %+v", assistant.ToolCalls)
+	}
+
+	tool := out.Messages[3]
+	if tool.ToolCallID != "call_1" || tool.Content != "file body" {
+		t.Errorf("tool message = %+v", tool)
+	}
+
+	parts, ok := out.Messages[4].Content.([]ContentPart)
+	if !ok || len(parts) != 2 {
+		t.Fatalf("multimodal user content = %#v", out.Messages[4].Content)
+	}
+	if parts[0].ImageURL == nil || parts[0].ImageURL.URL != "data:image/png;base64,AAAA" {
+		t.Errorf("image part = %+v", parts[0])
+	}
+	if parts[1].Text != "what is this?" {
+		t.Errorf("text part = %+v", parts[1])
+	}
+}
+
+func TestToChatRequestToolChoice(t *testing.T) {
+	tests := []struct {
+		choice ToolChoice
+		want   interface{}
+	}{
+		{ToolChoice{Type: "auto"}, "auto"},
+		{ToolChoice{Type: "none"}, "none"},
+		{ToolChoice{Type: "tool", Name: "x"}, map[string]interface{}{"type": "function", "function": map[string]string{"name": "x"}}},
+	}
+	for _, tt := range tests {
+		req := &MessagesRequest{
+			Tools:      []Tool{{Name: "x", InputSchema: json.RawMessage(`{}`)}},
+			ToolChoice: &tt.choice,
+		}
+		out, err := ToChatRequest(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out.ToolChoice, tt.want) {
+			t.Errorf("tool_choice %s = %#v, want %#v", tt.choice.Type, out.ToolChoice, tt.want)
+		}
+	}
+}
+
+func TestToChatRequestErrors(t *testing.T) {
+	bad := []string{
+		`{"messages":[{"role":"system","content":"x"}]}`,
+		`{"messages":[{"role":"user","content":[{"type":"image","source":{"type":"file"}}]}]}`,
+		`{"messages":[{"role":"user","content":42}]}`,
+	}
+	for _, body := range bad {
+		var req MessagesRequest
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ToChatRequest(&req); err == nil {
+			t.Errorf("expected error for %s", body)
+		}
+	}
+}
+
+func TestFromChatResponse(t *testing.T) {
+	body := `{
+  "id": "chatcmpl-1",
+  "model": "gpt-4o",
+  "choices": [{
+    "message": {
+      "role": "assistant",
+      "content": "Reading.",
+      "tool_calls": [{"id": "call_9", "type": "function", "function": {"name": "read_file", "arguments": "{\"path\":\"b\"}"}}]
+    },
+    "finish_reason": "tool_calls"
+  }],
+  "usage": {"prompt_tokens": 12, "completion_tokens": 7}
+}`
+	var resp ChatResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := FromChatResponse(&resp, "requested")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != "msg_chatcmpl-1" || msg.Type != "message" || msg.Role != "assistant" || msg.Model != "gpt-4o" {
+		t.Errorf("header fields = %+v", msg)
+	}
+	if msg.StopReason != "tool_use" {
+		t.Errorf("StopReason = %q, want tool_use", msg.StopReason)
+	}
+	if msg.Usage.InputTokens != 12 || msg.Usage.OutputTokens != 7 {
+		t.Errorf("Usage = %+v", msg.Usage)
+	}
+	if len(msg.Content) != 2 || msg.Content[0].Text != "Reading." {
+		t.Fatalf("Content = %+v", msg.Content)
+	}
+	tool := msg.Content[1]
+	if tool.Type != "tool_use" || tool.ID != "call_9" || tool.Name != "read_file" || string(tool.Input) != `{"path":"b"}` {
+		t.Errorf("tool_use = %+v", tool)
+	}
+}
+
+func TestStopReason(t *testing.T) {
+	tests := map[string]string{
+		"stop":           "end_turn",
+		"length":         "max_tokens",
+		"tool_calls":     "tool_use",
+		"content_filter": "end_turn",
+		"":               "end_turn",
+	}
+	for in, want := range tests {
+		if got := StopReason(in); got != want {
+			t.Errorf("StopReason(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestErrorBody(t *testing.T) {
+	data := ErrorBody(401, []byte(`{"error":{"message":"bad key","type":"invalid_api_key"}}`))
+	var e struct {
+		Type  string `json:"type"`
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != "error" || e.Error.Type != "authentication_error" || e.Error.Message != "bad key" {
+		t.Errorf("ErrorBody() = %s", data)
+	}
+}
+
+// parseEvents 解析 SSE 输出为事件名和数据
+func parseEvents(t *testing.T, out string) ([]string, []map[string]interface{}) {
+	t.Helper()
+	var names []string
+	var payloads []map[string]interface{}
+	for _, ev := range strings.Split(strings.TrimSpace(out), "\n\n") {
+		lines := strings.Split(ev, "\n")
+		if len(lines) != 2 {
+			t.Fatalf("malformed event: %q", ev)
+		}
+		names = append(names, strings.TrimPrefix(lines[0], "event: "))
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &payload); err != nil {
+			t.Fatal(err)
+		}
+		payloads = append(payloads, payload)
+	}
+	return names, payloads
+}
+
+func TestStreamWriter(t *testing.T) {
+	upstream := strings.Join([]string{
+		`data: {"id":"chatcmpl-2","model":"gpt-4o","choices":[{"delta":{"role":"assistant","content":""}}]}`,
+		`data: {"id":"chatcmpl-2","choices":[{"delta":{"content":"Hel"}}]}`,
+		`data: {"id":"chatcmpl-2","choices":[{"delta":{"content":"lo"}}]}`,
+		`data: {"id":"chatcmpl-2","choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"ls","arguments":""}}]}}]}`,
+		`data: {"id":"chatcmpl-2","choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"dir\":"}}]}}]}`,
+		`data: {"id":"chatcmpl-2","choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\".\"}"}}]}}]}`,
+		`data: {"id":"chatcmpl-2","choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		`data: {"id":"chatcmpl-2","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":9}}`,
+		`data: [DONE]`,
+		``,
+	}, "\n\n")
+
+	var out bytes.Buffer
+	flushes := 0
+	sw := NewStreamWriter(&out, func() { flushes++ }, "gpt-4o")
+	if err := sw.Translate(strings.NewReader(upstream)); err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+
+	names, payloads := parseEvents(t, out.String())
+	want := []string{
+		"message_start",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("events = %v, want %v", names, want)
+	}
+	if flushes != len(names) {
+		t.Errorf("flushes = %d, want one per event (%d)", flushes, len(names))
+	}
+
+	msg := payloads[0]["message"].(map[string]interface{})
+	if msg["id"] != "msg_chatcmpl-2" || msg["model"] != "gpt-4o" {
+		t.Errorf("message_start = %v", msg)
+	}
+
+	if d := payloads[2]["delta"].(map[string]interface{}); d["text"] != "Hel" || payloads[2]["index"] != float64(0) {
+		t.Errorf("text delta = %v", payloads[2])
+	}
+
+	block := payloads[5]["content_block"].(map[string]interface{})
+	if block["type"] != "tool_use" || block["id"] != "call_1" || block["name"] != "ls" || payloads[5]["index"] != float64(1) {
+		t.Errorf("tool block = %v", payloads[5])
+	}
+	args := payloads[6]["delta"].(map[string]interface{})["partial_json"].(string) +
+		payloads[7]["delta"].(map[string]interface{})["partial_json"].(string)
+	if args != `{"dir":"."}` {
+		t.Errorf("tool arguments = %q", args)
+	}
+
+	delta := payloads[9]
+	if delta["delta"].(map[string]interface{})["stop_reason"] != "tool_use" {
+		t.Errorf("message_delta = %v", delta)
+	}
+	if delta["usage"].(map[string]interface{})["output_tokens"] != float64(9) {
+		t.Errorf("usage = %v", delta["usage"])
+	}
+}
+
+func TestStreamWriterEmptyStream(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewStreamWriter(&out, nil, "m").Translate(strings.NewReader("data: [DONE]\n\n")); err != nil {
+		t.Fatal(err)
+	}
+	names, _ := parseEvents(t, out.String())
+	if !reflect.DeepEqual(names, []string{"message_start", "message_delta", "message_stop"}) {
+		t.Errorf("events = %v", names)
+	}
+}
+
+func TestStreamWriterTruncatedStream(t *testing.T) {
+	upstream := `data: {"id":"chatcmpl-3","choices":[{"delta":{"content":"Hel"}}]}` + "\n\n"
+
+	var out bytes.Buffer
+	err := NewStreamWriter(&out, nil, "m").Translate(strings.NewReader(upstream))
+	if !errors.Is(err, ErrUnexpectedEnd) {
+		t.Errorf("Translate() error = %v, want ErrUnexpectedEnd", err)
+	}
+
+	// 不能输出 message_stop，否则截断的回复会被当作正常结束
+	names, payloads := parseEvents(t, out.String())
+	want := []string{"message_start", "content_block_start", "content_block_delta", "error"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("events = %v, want %v", names, want)
+	}
+	if e := payloads[3]["error"].(map[string]interface{}); e["type"] != "api_error" || e["message"] != "upstream stream ended unexpectedly" {
+		t.Errorf("error event = %v", payloads[3])
+	}
+}
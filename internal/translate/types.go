@@ -0,0 +1,182 @@
+package translate
+
+import "encoding/json"
+
+// Anthropic Messages API
+
+// MessagesRequest 是 POST /v1/messages 的请求体
+type MessagesRequest struct {
+	Model         string          `json:"model"`
+	MaxTokens     int             `json:"max_tokens,omitempty"`
+	System        json.RawMessage `json:"system,omitempty"` // 字符串或文本块数组
+	Messages      []Message       `json:"messages"`
+	Tools         []Tool          `json:"tools,omitempty"`
+	ToolChoice    *ToolChoice     `json:"tool_choice,omitempty"`
+	Stream        bool            `json:"stream,omitempty"`
+	Temperature   *float64        `json:"temperature,omitempty"`
+	TopP          *float64        `json:"top_p,omitempty"`
+	StopSequences []string        `json:"stop_sequences,omitempty"`
+}
+
+// Message 是一条对话消息
+type Message struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"` // 字符串或内容块数组
+}
+
+// ContentBlock 是消息中的内容块
+type ContentBlock struct {
+	Type string `json:"type"`
+
+	// text
+	Text string `json:"text,omitempty"`
+
+	// image
+	Source *ImageSource `json:"source,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"` // 字符串或内容块数组
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// ImageSource 是图片内容块的数据来源
+type ImageSource struct {
+	Type      string `json:"type"` // base64 或 url
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// Tool 是可供模型调用的工具
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// ToolChoice 控制模型如何使用工具
+type ToolChoice struct {
+	Type string `json:"type"` // auto、any、tool、none
+	Name string `json:"name,omitempty"`
+}
+
+// MessagesResponse 是非流式响应
+type MessagesResponse struct {
+	ID           string         `json:"id"`
+	Type         string         `json:"type"`
+	Role         string         `json:"role"`
+	Model        string         `json:"model"`
+	Content      []ContentBlock `json:"content"`
+	StopReason   string         `json:"stop_reason"`
+	StopSequence *string        `json:"stop_sequence"`
+	Usage        Usage          `json:"usage"`
+}
+
+// Usage 是 token 用量
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// OpenAI Chat Completions API
+
+// ChatRequest 是 POST /chat/completions 的请求体
+type ChatRequest struct {
+	Model         string         `json:"model"`
+	Messages      []ChatMessage  `json:"messages"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Tools         []ChatTool     `json:"tools,omitempty"`
+	ToolChoice    interface{}    `json:"tool_choice,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	Temperature   *float64       `json:"temperature,omitempty"`
+	TopP          *float64       `json:"top_p,omitempty"`
+	Stop          []string       `json:"stop,omitempty"`
+}
+
+// StreamOptions 流式请求选项
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ChatMessage 是一条对话消息
+// Content 为字符串或 ContentPart 数组
+type ChatMessage struct {
+	Role       string      `json:"role"`
+	Content    interface{} `json:"content"`
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+}
+
+// ContentPart 是多模态消息的一部分
+type ContentPart struct {
+	Type     string    `json:"type"` // text 或 image_url
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL 图片地址，可以是 data URL
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+// ToolCall 是模型发起的函数调用
+type ToolCall struct {
+	Index    *int         `json:"index,omitempty"` // 仅出现在流式增量中
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall 函数名称和 JSON 字符串形式的参数
+type FunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// ChatTool 是可供模型调用的函数
+type ChatTool struct {
+	Type     string       `json:"type"`
+	Function FunctionSpec `json:"function"`
+}
+
+// FunctionSpec 函数定义
+type FunctionSpec struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ChatResponse 是非流式响应，也用于解析流式响应的每个 chunk
+type ChatResponse struct {
+	ID      string       `json:"id"`
+	Model   string       `json:"model"`
+	Choices []ChatChoice `json:"choices"`
+	Usage   *ChatUsage   `json:"usage,omitempty"`
+}
+
+// ChatChoice 是一个候选结果
+type ChatChoice struct {
+	Message      *ChatResponseMessage `json:"message,omitempty"`
+	Delta        *ChatResponseMessage `json:"delta,omitempty"`
+	FinishReason *string              `json:"finish_reason"`
+}
+
+// ChatResponseMessage 是响应中的消息或流式增量
+type ChatResponseMessage struct {
+	Role      string     `json:"role,omitempty"`
+	Content   *string    `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// ChatUsage 是 token 用量
+type ChatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
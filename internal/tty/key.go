@@ -0,0 +1,120 @@
+// Package tty 提供交互式菜单使用的终端操作：原始模式、按键解析与显示宽度计算
+package tty
+
+import "unicode/utf8"
+
+// KeyCode 表示按键类型
+type KeyCode int
+
+const (
+	KeyUnknown KeyCode = iota
+	KeyRune            // 普通字符，见 Key.Rune
+	KeyEnter
+	KeyEsc
+	KeyBackspace
+	KeyTab
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyDelete
+	KeyCtrlC
+)
+
+// Key 表示一次按键
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// ParseKeys 将一次读取到的输入解析为按键序列
+// 终端会把一个转义序列一次性写入，因此末尾单独的 ESC 视为 Esc 键
+func ParseKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		k, n := parseKey(b)
+		keys = append(keys, k)
+		b = b[n:]
+	}
+	return keys
+}
+
+// parseKey 解析开头的一个按键，返回按键和消耗的字节数
+func parseKey(b []byte) (Key, int) {
+	switch c := b[0]; {
+	case c == 0x1b:
+		if len(b) > 1 && (b[1] == '[' || b[1] == 'O') {
+			return parseEscape(b)
+		}
+		return Key{Code: KeyEsc}, 1
+	case c == '\r' || c == '\n':
+		// 部分终端回车发送 \r\n，合并为一次
+		if c == '\r' && len(b) > 1 && b[1] == '\n' {
+			return Key{Code: KeyEnter}, 2
+		}
+		return Key{Code: KeyEnter}, 1
+	case c == 0x7f || c == 0x08:
+		return Key{Code: KeyBackspace}, 1
+	case c == '\t':
+		return Key{Code: KeyTab}, 1
+	case c == 0x03:
+		return Key{Code: KeyCtrlC}, 1
+	case c < 0x20:
+		return Key{Code: KeyUnknown}, 1
+	case c < utf8.RuneSelf:
+		return Key{Code: KeyRune, Rune: rune(c)}, 1
+	}
+
+	r, n := utf8.DecodeRune(b)
+	if r == utf8.RuneError {
+		return Key{Code: KeyUnknown}, n
+	}
+	return Key{Code: KeyRune, Rune: r}, n
+}
+
+// parseEscape 解析 ESC [ 或 ESC O 开头的转义序列
+func parseEscape(b []byte) (Key, int) {
+	// 参数字节为 0x30–0x3F，以 0x40–0x7E 之间的字节结束
+	i := 2
+	for i < len(b) && b[i] >= 0x30 && b[i] <= 0x3f {
+		i++
+	}
+	if i >= len(b) || b[i] < 0x40 || b[i] > 0x7e {
+		return Key{Code: KeyUnknown}, i
+	}
+
+	params, final := string(b[2:i]), b[i]
+	n := i + 1
+	switch final {
+	case 'A':
+		return Key{Code: KeyUp}, n
+	case 'B':
+		return Key{Code: KeyDown}, n
+	case 'C':
+		return Key{Code: KeyRight}, n
+	case 'D':
+		return Key{Code: KeyLeft}, n
+	case 'H':
+		return Key{Code: KeyHome}, n
+	case 'F':
+		return Key{Code: KeyEnd}, n
+	case '~':
+		switch params {
+		case "1", "7":
+			return Key{Code: KeyHome}, n
+		case "4", "8":
+			return Key{Code: KeyEnd}, n
+		case "3":
+			return Key{Code: KeyDelete}, n
+		case "5":
+			return Key{Code: KeyPageUp}, n
+		case "6":
+			return Key{Code: KeyPageDown}, n
+		}
+	}
+	return Key{Code: KeyUnknown}, n
+}
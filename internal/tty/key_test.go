@@ -0,0 +1,32 @@
+package tty
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []Key
+	}{
+		{"\x1b[A\x1b[B\x1bOC\x1b[D", []Key{{Code: KeyUp}, {Code: KeyDown}, {Code: KeyRight}, {Code: KeyLeft}}},
+		{"\x1b[5~\x1b[6~\x1b[1~\x1b[4~\x1b[H\x1b[F", []Key{
+			{Code: KeyPageUp}, {Code: KeyPageDown}, {Code: KeyHome}, {Code: KeyEnd}, {Code: KeyHome}, {Code: KeyEnd},
+		}},
+		{"\x1b", []Key{{Code: KeyEsc}}},
+		{"\x1bq", []Key{{Code: KeyEsc}, {Code: KeyRune, Rune: 'q'}}},
+		{"\r\n\r", []Key{{Code: KeyEnter}, {Code: KeyEnter}}},
+		{"a工\x7f\x03\t", []Key{
+			{Code: KeyRune, Rune: 'a'}, {Code: KeyRune, Rune: '工'}, {Code: KeyBackspace}, {Code: KeyCtrlC}, {Code: KeyTab},
+		}},
+		{"\x1b[1;5A", []Key{{Code: KeyUp}}},
+		{"\x1b[", []Key{{Code: KeyUnknown}}},
+		{"\xff", []Key{{Code: KeyUnknown}}},
+	}
+	for _, tt := range tests {
+		if got := ParseKeys([]byte(tt.in)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseKeys(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package tty
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// IsTerminal 判断文件是否连接到终端
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// ReadPassword 从终端读取一行输入，不回显
+func ReadPassword(f *os.File) (string, error) {
+	b, err := term.ReadPassword(int(f.Fd()))
+	return string(b), err
+}
+
+// Terminal 表示处于原始模式、使用备用屏幕的终端
+type Terminal struct {
+	in    *os.File
+	out   *os.File
+	state *term.State
+	buf   []byte
+}
+
+// Open 将 in 切换为原始模式，并在 out 上切换到备用屏幕、隐藏光标
+// 使用完毕后必须调用 Close 恢复终端
+func Open(in, out *os.File) (*Terminal, error) {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	t := &Terminal{in: in, out: out, state: state, buf: make([]byte, 256)}
+	t.out.WriteString("\x1b[?1049h\x1b[?25l")
+	return t, nil
+}
+
+// Close 显示光标、离开备用屏幕并恢复终端模式
+func (t *Terminal) Close() error {
+	t.out.WriteString("\x1b[?25h\x1b[?1049l")
+	return term.Restore(int(t.in.Fd()), t.state)
+}
+
+// Size 返回终端的列数和行数，无法获取时返回 80x24
+func (t *Terminal) Size() (int, int) {
+	w, h, err := term.GetSize(int(t.out.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// ReadKeys 等待输入并返回读取到的按键，timeout 内没有输入时返回空
+// 不支持等待的平台上会一直阻塞到有输入为止
+func (t *Terminal) ReadKeys(timeout time.Duration) ([]Key, error) {
+	ready, err := waitInput(t.in, timeout)
+	if err != nil || !ready {
+		return nil, err
+	}
+	n, err := t.in.Read(t.buf)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeys(t.buf[:n]), nil
+}
+
+// Draw 从左上角开始重绘整个屏幕
+// 原始模式下换行不会回到行首，因此每行以 \r\n 分隔
+func (t *Terminal) Draw(lines []string) {
+	var sb strings.Builder
+	sb.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteString("\r\n")
+		}
+		sb.WriteString(line)
+		sb.WriteString("\x1b[K")
+	}
+	sb.WriteString("\x1b[J")
+	t.out.WriteString(sb.String())
+}
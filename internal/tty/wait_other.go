@@ -0,0 +1,14 @@
+//go:build !unix
+
+package tty
+
+import (
+	"os"
+	"time"
+)
+
+// waitInput 不支持等待输入的平台上直接返回，由 Read 阻塞
+// 窗口大小变化会在下一次按键后重绘
+func waitInput(f *os.File, timeout time.Duration) (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package tty
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitInput 等待 f 可读，超时返回 false
+func waitInput(f *os.File, timeout time.Duration) (bool, error) {
+	fds := []unix.PollFd{{Fd: int32(f.Fd()), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, int(timeout/time.Millisecond))
+	if errors.Is(err, unix.EINTR) {
+		// 收到信号（如窗口大小变化）时提前返回，由调用方重绘
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,70 @@
+package tty
+
+import "strings"
+
+// RuneWidth 返回字符在终端中占用的列数
+// 中日韩文字、全角符号和常见 emoji 占两列，组合字符与零宽字符不占列
+func RuneWidth(r rune) int {
+	switch {
+	case r == 0:
+		return 0
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		return 0
+	case r >= 0x300 && r <= 0x36f, r >= 0x200b && r <= 0x200f, r >= 0xfe00 && r <= 0xfe0f:
+		return 0
+	case r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0x303e,
+		r >= 0x3041 && r <= 0xa4cf,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f,
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
+
+// Width 返回字符串在终端中占用的列数
+func Width(s string) int {
+	w := 0
+	for _, r := range s {
+		w += RuneWidth(r)
+	}
+	return w
+}
+
+// Truncate 将字符串截断到不超过 width 列，超出时以 … 结尾
+func Truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if Width(s) <= width {
+		return s
+	}
+
+	var sb strings.Builder
+	w := 0
+	for _, r := range s {
+		rw := RuneWidth(r)
+		if w+rw > width-1 {
+			break
+		}
+		sb.WriteRune(r)
+		w += rw
+	}
+	sb.WriteString("…")
+	return sb.String()
+}
+
+// Pad 在字符串末尾补空格到 width 列，超出时截断
+func Pad(s string, width int) string {
+	s = Truncate(s, width)
+	if w := Width(s); w < width {
+		s += strings.Repeat(" ", width-w)
+	}
+	return s
+}
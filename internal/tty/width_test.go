@@ -0,0 +1,38 @@
+package tty
+
+import "testing"
+
+func TestWidth(t *testing.T) {
+	tests := map[string]int{
+		"abc":  3,
+		"配置管理": 8,
+		"a中b":  4,
+		"é":   1,
+		"ＡＢ":   4,
+		"":     0,
+	}
+	for s, want := range tests {
+		if got := Width(s); got != want {
+			t.Errorf("Width(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestTruncateAndPad(t *testing.T) {
+	if got := Truncate("hello", 5); got != "hello" {
+		t.Errorf("Truncate = %q", got)
+	}
+	if got := Truncate("hello world", 6); got != "hello…" {
+		t.Errorf("Truncate = %q", got)
+	}
+	// 宽字符不能被截成半个
+	if got := Truncate("配置管理", 6); got != "配置…" {
+		t.Errorf("Truncate = %q", got)
+	}
+	if got := Truncate("abc", 0); got != "" {
+		t.Errorf("Truncate = %q", got)
+	}
+	if got := Pad("配置", 6); got != "配置  " {
+		t.Errorf("Pad = %q", got)
+	}
+}
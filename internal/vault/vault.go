@@ -0,0 +1,236 @@
+// Package vault 实现配置文件的加密存储
+//
+// 口令经 scrypt 派生出密钥加密密钥，用 AES-GCM 封装随机生成的数据密钥；
+// 配置文件使用数据密钥以 AES-GCM 加密。修改口令只需重新封装数据密钥，配置文件无需重新加密
+package vault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// FileName 保存在 profiles 目录中的加密存储元数据文件
+const FileName = ".vault.json"
+
+// KeySize 数据密钥长度（AES-256）
+const KeySize = 32
+
+// magic 加密文件的文件头，同时作为 AES-GCM 的附加数据
+var magic = []byte("CSENC1")
+
+// ErrNotInitialized 表示加密存储尚未初始化
+var ErrNotInitialized = errors.New("加密存储未初始化，请先运行 'claude-switcher vault init'")
+
+// ErrWrongPassphrase 表示口令错误
+var ErrWrongPassphrase = errors.New("口令错误")
+
+// Params scrypt 参数
+type Params struct {
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+}
+
+// DefaultParams 新建或修改口令时使用的 scrypt 参数（约 32MB 内存）
+var DefaultParams = Params{N: 1 << 15, R: 8, P: 1}
+
+// Vault 加密存储的元数据
+type Vault struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Params     Params `json:"params"`
+	Salt       []byte `json:"salt"`
+	WrappedKey []byte `json:"wrappedKey"` // 口令派生密钥加密后的数据密钥
+	Locked     bool   `json:"locked"`     // 为 true 时配置以加密形式保存
+}
+
+// Path 返回 dir 中的元数据文件路径
+func Path(dir string) string {
+	return filepath.Join(dir, FileName)
+}
+
+// Exists 判断 dir 中是否已初始化加密存储
+func Exists(dir string) bool {
+	_, err := os.Stat(Path(dir))
+	return err == nil
+}
+
+// Load 读取 dir 中的元数据，未初始化时返回 ErrNotInitialized
+func Load(dir string) (*Vault, error) {
+	data, err := os.ReadFile(Path(dir))
+	if os.IsNotExist(err) {
+		return nil, ErrNotInitialized
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var v Vault
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("无法解析 %s: %w", FileName, err)
+	}
+	if v.KDF != "scrypt" || len(v.Salt) == 0 || len(v.WrappedKey) == 0 {
+		return nil, fmt.Errorf("%s 格式不正确", FileName)
+	}
+	return &v, nil
+}
+
+// Create 生成新的数据密钥并用口令封装，返回元数据和数据密钥
+func Create(passphrase string) (*Vault, []byte, error) {
+	if passphrase == "" {
+		return nil, nil, fmt.Errorf("口令不能为空")
+	}
+
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+
+	v := &Vault{Version: 1, KDF: "scrypt"}
+	if err := v.wrap(key, passphrase); err != nil {
+		return nil, nil, err
+	}
+	return v, key, nil
+}
+
+// Save 将元数据写入 dir，通过重命名原子替换
+func (v *Vault) Save(dir string) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".vault-*.json")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, Path(dir))
+}
+
+// Unlock 用口令解出数据密钥
+func (v *Vault) Unlock(passphrase string) ([]byte, error) {
+	kek, err := deriveKey(passphrase, v.Salt, v.Params)
+	if err != nil {
+		return nil, err
+	}
+	key, err := Open(kek, v.WrappedKey)
+	if err != nil || len(key) != KeySize {
+		return nil, ErrWrongPassphrase
+	}
+	return key, nil
+}
+
+// ChangePassphrase 验证旧口令后用新口令重新封装数据密钥
+func (v *Vault) ChangePassphrase(oldPassphrase, newPassphrase string) error {
+	if newPassphrase == "" {
+		return fmt.Errorf("口令不能为空")
+	}
+	key, err := v.Unlock(oldPassphrase)
+	if err != nil {
+		return err
+	}
+	return v.wrap(key, newPassphrase)
+}
+
+// wrap 使用新的盐和当前默认参数封装数据密钥
+func (v *Vault) wrap(key []byte, passphrase string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	kek, err := deriveKey(passphrase, salt, DefaultParams)
+	if err != nil {
+		return err
+	}
+	wrapped, err := Seal(kek, key)
+	if err != nil {
+		return err
+	}
+
+	v.Params, v.Salt, v.WrappedKey = DefaultParams, salt, wrapped
+	return nil
+}
+
+// deriveKey 使用 scrypt 从口令派生密钥
+func deriveKey(passphrase string, salt []byte, p Params) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, p.N, p.R, p.P, KeySize)
+}
+
+// Key 数据密钥，用于加密和解密配置文件
+type Key []byte
+
+// Seal 使用密钥加密
+func (k Key) Seal(plaintext []byte) ([]byte, error) {
+	return Seal(k, plaintext)
+}
+
+// Open 使用密钥解密
+func (k Key) Open(data []byte) ([]byte, error) {
+	return Open(k, data)
+}
+
+// IsEncrypted 判断数据是否为 Seal 生成的密文
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Seal 使用 AES-GCM 加密，输出为文件头 + 随机 nonce + 密文
+func Seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, len(magic)+gcm.NonceSize(), len(magic)+gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	copy(out, magic)
+	nonce := out[len(magic):]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(out, nonce, plaintext, magic), nil
+}
+
+// Open 解密 Seal 生成的数据，密钥错误或数据被篡改时返回错误
+func Open(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if !IsEncrypted(data) || len(data) < len(magic)+gcm.NonceSize() {
+		return nil, fmt.Errorf("不是有效的加密文件")
+	}
+
+	nonce := data[len(magic) : len(magic)+gcm.NonceSize()]
+	plaintext, err := gcm.Open(nil, nonce, data[len(magic)+gcm.NonceSize():], magic)
+	if err != nil {
+		return nil, fmt.Errorf("解密失败: 密钥错误或文件已损坏")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
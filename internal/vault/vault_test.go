@@ -0,0 +1,109 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func init() {
+	// 测试中使用较小的参数加快密钥派生
+	DefaultParams = Params{N: 1 << 10, R: 8, P: 1}
+}
+
+func TestCreateSaveLoadUnlock(t *testing.T) {
+	dir := t.TempDir()
+	if Exists(dir) {
+		t.Fatal("vault should not exist yet")
+	}
+	if _, err := Load(dir); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("Load() error = %v, want ErrNotInitialized", err)
+	}
+
+	v, key, err := Create("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Locked = true
+	if err := v.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(Path(dir)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("vault file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Locked {
+		t.Error("Locked should be persisted")
+	}
+	got, err := loaded.Unlock("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Error("Unlock returned a different key")
+	}
+	if _, err := loaded.Unlock("wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Unlock(wrong) error = %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestChangePassphraseKeepsKey(t *testing.T) {
+	v, key, err := Create("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.ChangePassphrase("bad", "new"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("ChangePassphrase with wrong passphrase: %v", err)
+	}
+	if err := v.ChangePassphrase("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.Unlock("old"); err == nil {
+		t.Error("old passphrase should no longer work")
+	}
+	got, err := v.Unlock("new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Error("data key should not change with the passphrase")
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, KeySize)
+	plaintext := []byte("ANTHROPIC_AUTH_TOKEN=\"sk-secret\"\n")
+
+	sealed, err := Seal(key, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(sealed) || bytes.Contains(sealed, []byte("sk-secret")) {
+		t.Fatal("sealed data should be encrypted")
+	}
+	got, err := Open(key, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("Open() = %q, want %q", got, plaintext)
+	}
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := Open(key, tampered); err == nil {
+		t.Error("expected error for tampered data")
+	}
+	if _, err := Open(bytes.Repeat([]byte{2}, KeySize), sealed); err == nil {
+		t.Error("expected error for wrong key")
+	}
+	if _, err := Open(key, plaintext); err == nil {
+		t.Error("expected error for plaintext input")
+	}
+}